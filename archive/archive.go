@@ -0,0 +1,144 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// Format is an archive format.
+type Format string
+
+const (
+	FormatTar   Format = "tar"
+	FormatTarGz Format = "tar.gz"
+	FormatZip   Format = "zip"
+)
+
+var (
+	magicGzip = []byte{0x1f, 0x8b}
+	magicZip  = []byte("PK\x03\x04")
+)
+
+// WalkFunc is called for each regular file member of an archive.
+// name is the cleaned, slash-separated path of the member.
+// r reads the contents of the member, and is only valid until WalkFunc returns.
+type WalkFunc func(name string, r io.Reader) error
+
+// Walk calls fn for each regular file member of the archive at archivePath, in archive order.
+// Members are streamed, and are never extracted.
+// If fn returns an error, Walk stops and returns that error.
+func Walk(archivePath string, fn WalkFunc) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	format, err := detect(f)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case FormatZip:
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		return walkZip(f, info.Size(), fn)
+
+	case FormatTarGz:
+		gz, err := gzip.NewReader(bufio.NewReader(f))
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		return walkTar(gz, fn)
+
+	default:
+		return walkTar(bufio.NewReader(f), fn)
+	}
+}
+
+// detect detects the Format of f from its magic bytes.
+// The offset of f is reset to the beginning.
+func detect(f *os.File) (Format, error) {
+	magic := make([]byte, 4)
+	n, err := io.ReadFull(f, magic)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	magic = magic[:n]
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, magicZip):
+		return FormatZip, nil
+	case bytes.HasPrefix(magic, magicGzip):
+		return FormatTarGz, nil
+	default:
+		return FormatTar, nil
+	}
+}
+
+func walkTar(r io.Reader, fn WalkFunc) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if !hdr.FileInfo().Mode().IsRegular() {
+			continue
+		}
+
+		if err := fn(CleanName(hdr.Name), tr); err != nil {
+			return err
+		}
+	}
+}
+
+func walkZip(r io.ReaderAt, size int64, fn WalkFunc) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range zr.File {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.Name, err)
+		}
+		err = fn(CleanName(file.Name), rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CleanName returns the cleaned, slash-separated, relative form of the member name.
+func CleanName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
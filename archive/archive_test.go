@@ -0,0 +1,55 @@
+package archive_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/archive"
+	"github.com/ghifari160/medhash-tools/testcommon"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalk(t *testing.T) {
+	t.Parallel()
+
+	for _, format := range []string{"tar", "tar.gz", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			testWalk(t, format)
+		})
+	}
+}
+
+func testWalk(t *testing.T, format string) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	require.NoError(os.Mkdir(sub, 0755))
+	require.NoError(os.WriteFile(filepath.Join(dir, "a"), []byte("a"), 0644))
+	require.NoError(os.WriteFile(filepath.Join(sub, "b"), []byte("b"), 0644))
+
+	archivePath := filepath.Join(t.TempDir(), "archive."+format)
+	testcommon.CreateArchive(t, archivePath, format, dir)
+
+	members := make(map[string]string)
+	err := archive.Walk(archivePath, func(name string, r io.Reader) error {
+		data, err := io.ReadAll(r)
+		members[name] = string(data)
+		return err
+	})
+	require.NoError(err)
+	require.Equal(map[string]string{"a": "a", "sub/b": "b"}, members)
+}
+
+func TestCleanName(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	require.Equal("a/b", archive.CleanName("./a/b"))
+	require.Equal("a/b", archive.CleanName("/a/b"))
+	require.Equal("b", archive.CleanName("../b"))
+	require.Equal("a/b", archive.CleanName("a\\b"))
+}
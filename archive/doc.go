@@ -0,0 +1,5 @@
+// Package archive provides streaming access to the members of tar and zip archives.
+//
+// Supported formats are tar, gzip-compressed tar, and zip.
+// The format of an archive is detected from its contents rather than its file name.
+package archive
@@ -0,0 +1,95 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+// Writer writes members to an archive.
+type Writer struct {
+	tw *tar.Writer
+	gz *gzip.Writer
+	zw *zip.Writer
+}
+
+// NewWriter returns a new Writer writing an archive of format to w.
+func NewWriter(w io.Writer, format Format) (*Writer, error) {
+	switch format {
+	case FormatTar:
+		return &Writer{tw: tar.NewWriter(w)}, nil
+	case FormatTarGz:
+		gz := gzip.NewWriter(w)
+		return &Writer{tw: tar.NewWriter(gz), gz: gz}, nil
+	case FormatZip:
+		return &Writer{zw: zip.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown archive format: %s", format)
+	}
+}
+
+// Create adds a regular file member name to the archive.
+// The contents of the member must be written to the returned io.Writer before the next call to
+// Create or Close.
+// For tar archives, exactly size bytes must be written.
+func (w *Writer) Create(name string, size int64, mode fs.FileMode, modTime time.Time) (io.Writer, error) {
+	name = CleanName(name)
+
+	if w.zw != nil {
+		hdr := &zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: modTime,
+		}
+		hdr.SetMode(mode)
+		return w.zw.CreateHeader(hdr)
+	}
+
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     int64(mode.Perm()),
+		ModTime:  modTime,
+	}
+	if err := w.tw.WriteHeader(hdr); err != nil {
+		return nil, err
+	}
+	return w.tw, nil
+}
+
+// Close finishes writing the archive.
+// Close does not close the underlying io.Writer.
+func (w *Writer) Close() error {
+	if w.zw != nil {
+		return w.zw.Close()
+	}
+
+	if err := w.tw.Close(); err != nil {
+		return err
+	}
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	return nil
+}
+
+// FormatFromName returns the Format for the archive file name, based on its extension.
+// Names ending in .zip are zip archives, names ending in .tar.gz or .tgz are gzip-compressed tar
+// archives, and any other name is a tar archive.
+func FormatFromName(name string) Format {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return FormatZip
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return FormatTarGz
+	default:
+		return FormatTar
+	}
+}
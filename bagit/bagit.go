@@ -0,0 +1,213 @@
+package bagit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ghifari160/medhash-tools/medhash"
+)
+
+const (
+	// Version is the BagIt version of the bags written by this package.
+	Version = "1.0"
+	// PayloadDir is the name of the payload directory of a bag.
+	PayloadDir = "data"
+	// DeclarationFile is the name of the bag declaration tag file.
+	DeclarationFile = "bagit.txt"
+	// InfoFile is the name of the bag metadata tag file.
+	InfoFile = "bag-info.txt"
+)
+
+// Tag labels used by this package.
+const (
+	TagVersion       = "BagIt-Version"
+	TagEncoding      = "Tag-File-Character-Encoding"
+	TagBaggingDate   = "Bagging-Date"
+	TagSoftwareAgent = "Bag-Software-Agent"
+	TagPayloadOxum   = "Payload-Oxum"
+)
+
+// algs maps the Hash field names to the BagIt algorithm names.
+var algs = map[string]string{
+	"sha512": "sha512",
+	"sha256": "sha256",
+	"sha1":   "sha1",
+	"md5":    "md5",
+}
+
+// Algorithm returns the BagIt name of alg, the Hash field name of a medhash algorithm.
+// Algorithm returns an empty string for algorithms without a BagIt name.
+func Algorithm(alg string) string {
+	return algs[alg]
+}
+
+// Algorithms returns the BagIt names of the algorithms toggled in config, in the order of
+// config.Algorithms.
+func Algorithms(config medhash.Config) []string {
+	names := make([]string, 0)
+	for _, alg := range config.Algorithms() {
+		if name := Algorithm(alg); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// FromAlgorithm returns the Hash field name of name, a BagIt algorithm name.
+// FromAlgorithm returns an empty string for unsupported algorithms.
+func FromAlgorithm(name string) string {
+	for alg, n := range algs {
+		if n == name {
+			return alg
+		}
+	}
+	return ""
+}
+
+// ManifestName returns the name of the payload manifest of the BagIt algorithm name.
+func ManifestName(name string) string {
+	return "manifest-" + name + ".txt"
+}
+
+// TagManifestName returns the name of the tag manifest of the BagIt algorithm name.
+func TagManifestName(name string) string {
+	return "tagmanifest-" + name + ".txt"
+}
+
+// WriteManifest writes the alg hash of each media to w as a BagIt manifest.
+// alg is the Hash field name, and media paths must be relative to the bag.
+// It is an error for a media to lack the alg hash.
+func WriteManifest(w io.Writer, alg string, medias []medhash.Media) error {
+	bw := bufio.NewWriter(w)
+
+	for _, med := range medias {
+		digest := med.Hash.Get(alg)
+		if digest == "" {
+			return fmt.Errorf("%s: no %s hash", med.Path, alg)
+		}
+		fmt.Fprintf(bw, "%s  %s\n", digest, encoder.Replace(med.Path))
+	}
+
+	return bw.Flush()
+}
+
+// ReadManifest reads a BagIt manifest of alg, the Hash field name, from r.
+// Medias are returned in the order they appear in r.
+func ReadManifest(r io.Reader, alg string) ([]medhash.Media, error) {
+	medias := make([]medhash.Media, 0)
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		digest, path, ok := strings.Cut(line, " ")
+		path = strings.TrimLeft(path, " \t")
+		if !ok || path == "" {
+			return nil, fmt.Errorf("line %d: malformed line: %q", n, line)
+		}
+
+		med := medhash.Media{Path: decoder.Replace(path)}
+		med.Hash.Set(alg, strings.ToLower(digest))
+		medias = append(medias, med)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return medias, nil
+}
+
+// Tag is a label and value pair of a tag file.
+type Tag struct {
+	Label string
+	Value string
+}
+
+// WriteTags writes tags to w as a tag file.
+func WriteTags(w io.Writer, tags []Tag) error {
+	bw := bufio.NewWriter(w)
+	for _, tag := range tags {
+		fmt.Fprintf(bw, "%s: %s\n", tag.Label, tag.Value)
+	}
+	return bw.Flush()
+}
+
+// ReadTags reads a tag file from r.
+// Values continued on lines starting with whitespace are joined with a single space.
+// Tags are returned in the order they appear in r.
+func ReadTags(r io.Reader) ([]Tag, error) {
+	tags := make([]Tag, 0)
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if line[0] == ' ' || line[0] == '\t' {
+			if len(tags) < 1 {
+				return nil, fmt.Errorf("line %d: continuation without tag", n)
+			}
+			tags[len(tags)-1].Value += " " + strings.TrimSpace(line)
+			continue
+		}
+
+		label, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: malformed tag: %q", n, line)
+		}
+		tags = append(tags, Tag{Label: strings.TrimSpace(label), Value: strings.TrimSpace(value)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// Lookup returns the value of the first tag labelled label.
+// Labels are matched case-insensitively.
+func Lookup(tags []Tag, label string) (string, bool) {
+	for _, tag := range tags {
+		if strings.EqualFold(tag.Label, label) {
+			return tag.Value, true
+		}
+	}
+	return "", false
+}
+
+// Oxum formats the Payload-Oxum of a payload of size octets in count files.
+func Oxum(size int64, count int) string {
+	return strconv.FormatInt(size, 10) + "." + strconv.Itoa(count)
+}
+
+// ParseOxum parses a Payload-Oxum.
+func ParseOxum(oxum string) (size int64, count int, err error) {
+	sizeStr, countStr, ok := strings.Cut(oxum, ".")
+	if !ok {
+		err = fmt.Errorf("malformed Payload-Oxum: %q", oxum)
+		return
+	}
+
+	if size, err = strconv.ParseInt(sizeStr, 10, 64); err != nil {
+		err = fmt.Errorf("malformed Payload-Oxum: %q", oxum)
+		return
+	}
+	if count, err = strconv.Atoi(countStr); err != nil {
+		err = fmt.Errorf("malformed Payload-Oxum: %q", oxum)
+	}
+	return
+}
+
+// Manifest paths percent-encode the characters that would break the line-oriented format.
+var (
+	encoder = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	decoder = strings.NewReplacer("%25", "%", "%0D", "\r", "%0A", "\n", "%0d", "\r", "%0a", "\n")
+)
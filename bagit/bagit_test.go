@@ -0,0 +1,90 @@
+package bagit_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/bagit"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/stretchr/testify/require"
+)
+
+const sha256A = "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"
+
+func TestManifest(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	medias := []medhash.Media{
+		{Path: "data/a", Hash: medhash.Hash{SHA256: sha256A}},
+		{Path: "data/50%\nb", Hash: medhash.Hash{SHA256: sha256A}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(bagit.WriteManifest(&buf, "sha256", medias))
+	require.Equal(sha256A+"  data/a\n"+sha256A+"  data/50%25%0Ab\n", buf.String())
+
+	read, err := bagit.ReadManifest(&buf, "sha256")
+	require.NoError(err)
+	require.Equal(medias, read)
+
+	require.Error(bagit.WriteManifest(&buf, "md5", medias))
+}
+
+func TestReadManifestWhitespace(t *testing.T) {
+	t.Parallel()
+
+	medias, err := bagit.ReadManifest(strings.NewReader(strings.ToUpper(sha256A)+" \tdata/a b\r\n"), "sha256")
+	require.NoError(t, err)
+	require.Equal(t, []medhash.Media{{Path: "data/a b", Hash: medhash.Hash{SHA256: sha256A}}}, medias)
+}
+
+func TestTags(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	input := "BagIt-Version: 1.0\nExternal-Description: first\n  second\n"
+
+	tags, err := bagit.ReadTags(strings.NewReader(input))
+	require.NoError(err)
+	require.Equal([]bagit.Tag{
+		{Label: "BagIt-Version", Value: "1.0"},
+		{Label: "External-Description", Value: "first second"},
+	}, tags)
+
+	value, ok := bagit.Lookup(tags, "bagit-version")
+	require.True(ok)
+	require.Equal("1.0", value)
+
+	var buf bytes.Buffer
+	require.NoError(bagit.WriteTags(&buf, tags[:1]))
+	require.Equal("BagIt-Version: 1.0\n", buf.String())
+}
+
+func TestOxum(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	require.Equal("1024.3", bagit.Oxum(1024, 3))
+
+	size, count, err := bagit.ParseOxum("1024.3")
+	require.NoError(err)
+	require.Equal(int64(1024), size)
+	require.Equal(3, count)
+
+	_, _, err = bagit.ParseOxum("1024")
+	require.Error(err)
+	_, _, err = bagit.ParseOxum("a.3")
+	require.Error(err)
+}
+
+func TestAlgorithms(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	require.Equal([]string{"sha512", "sha256", "sha1", "md5"}, bagit.Algorithms(medhash.AllConfig))
+	require.Empty(bagit.Algorithms(medhash.DefaultConfig))
+	require.Equal("sha256", bagit.FromAlgorithm("sha256"))
+	require.Empty(bagit.FromAlgorithm("xxh3"))
+}
@@ -0,0 +1,8 @@
+// Package bagit reads and writes the files of BagIt bags, as specified by RFC 8493.
+//
+// Payload manifests, tag manifests, and tag files (e.g. bagit.txt and bag-info.txt) are supported.
+// Manifests are converted to and from medhash.Media, so that bags can be created and validated
+// with the MedHash hashing engine.
+//
+// Only the SHA512, SHA256, SHA1, and MD5 hashes have registered BagIt algorithm names.
+package bagit
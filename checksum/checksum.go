@@ -0,0 +1,334 @@
+package checksum
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/ghifari160/medhash-tools/medhash"
+)
+
+// Format is a checksum file format.
+type Format string
+
+const (
+	FormatSHA512Sum Format = "sha512sum"
+	FormatSHA256Sum Format = "sha256sum"
+	FormatSHA1Sum   Format = "sha1sum"
+	FormatMD5Sum    Format = "md5sum"
+	// FormatBSD is the BSD tag format (e.g. "SHA256 (file) = digest").
+	// Unlike the GNU coreutils formats, it may contain hashes of multiple algorithms.
+	FormatBSD Format = "bsd"
+	// FormatSFV is the Simple File Verification format (e.g. "file 1A2B3C4D") of CRC32 checksums.
+	// Lines starting with a semicolon are comments.
+	FormatSFV Format = "sfv"
+)
+
+// Formats lists all supported Formats.
+var Formats = []Format{FormatSHA512Sum, FormatSHA256Sum, FormatSHA1Sum, FormatMD5Sum, FormatBSD,
+	FormatSFV}
+
+// FormatNames returns the names of all supported Formats.
+func FormatNames() []string {
+	names := make([]string, len(Formats))
+	for i, format := range Formats {
+		names[i] = string(format)
+	}
+	return names
+}
+
+// tags maps the Hash field names to the BSD tag names.
+var tags = map[string]string{
+	"xxh3":   "XXH3",
+	"sha512": "SHA512",
+	"sha3":   "SHA3-256",
+	"sha256": "SHA256",
+	"sha1":   "SHA1",
+	"md5":    "MD5",
+	"crc32":  "CRC32",
+}
+
+// digestLens maps the Hash field names to the length of their hex digests.
+var digestLens = map[string]int{
+	"xxh3":   16,
+	"sha512": 128,
+	"sha3":   64,
+	"sha256": 64,
+	"sha1":   40,
+	"md5":    32,
+	"crc32":  8,
+}
+
+// Algorithm returns the Hash field name of the algorithm used by format.
+// Algorithm returns an empty string for FormatBSD and unknown formats.
+func (format Format) Algorithm() string {
+	switch format {
+	case FormatSHA512Sum:
+		return "sha512"
+	case FormatSHA256Sum:
+		return "sha256"
+	case FormatSHA1Sum:
+		return "sha1"
+	case FormatMD5Sum:
+		return "md5"
+	case FormatSFV:
+		return "crc32"
+	default:
+		return ""
+	}
+}
+
+// FormatGNU formats the digest of path as a line in the GNU coreutils format, without the trailing
+// newline.
+// Paths containing a backslash, a carriage return, or a line feed are escaped, and the line is
+// prefixed with a backslash.
+func FormatGNU(path, digest string) string {
+	prefix, path := escape(path)
+	return prefix + digest + "  " + path
+}
+
+// FormatBSDTag formats the alg digest of path as a line in the BSD tag format, without the
+// trailing newline.
+// Paths are escaped as in FormatGNU.
+func FormatBSDTag(alg, path, digest string) string {
+	prefix, path := escape(path)
+	return prefix + tags[alg] + " (" + path + ") = " + digest
+}
+
+// Write writes medias to w in format.
+// For GNU coreutils formats, it is an error for a media to lack the hash of the format.
+// For FormatBSD, a line is written for every hash of each media.
+func Write(w io.Writer, format Format, medias []medhash.Media) error {
+	bw := bufio.NewWriter(w)
+
+	for _, med := range medias {
+		if format == FormatBSD {
+			for _, alg := range medhash.Algorithms() {
+				if digest := med.Hash.Get(alg); digest != "" {
+					fmt.Fprintln(bw, FormatBSDTag(alg, med.Path, digest))
+				}
+			}
+			continue
+		}
+
+		alg := format.Algorithm()
+		if alg == "" {
+			return fmt.Errorf("unknown checksum format: %s", format)
+		}
+
+		digest := med.Hash.Get(alg)
+		if digest == "" {
+			return fmt.Errorf("%s: no %s hash", med.Path, alg)
+		}
+		if format == FormatSFV {
+			// SFV has no escaping.
+			if strings.ContainsAny(med.Path, "\r\n") {
+				return fmt.Errorf("%q: path cannot be written to SFV", med.Path)
+			}
+			fmt.Fprintln(bw, med.Path+" "+strings.ToUpper(digest))
+		} else {
+			fmt.Fprintln(bw, FormatGNU(med.Path, digest))
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Read reads the checksum file in format from r.
+// If format is empty, the format of each line is detected, and the algorithm of GNU coreutils
+// lines is inferred from the length of the digest.
+// Paths are cleaned, and lines of the same path are merged into a single media.
+// Empty lines and lines starting with # (or ; for FormatSFV) are skipped.
+// It is an error for a digest not to be a hex digest of the length of its algorithm.
+// Medias are returned in the order they first appear in r.
+func Read(r io.Reader, format Format) ([]medhash.Media, error) {
+	medias := make([]medhash.Media, 0)
+	index := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") ||
+			(format == FormatSFV && strings.HasPrefix(line, ";")) {
+			continue
+		}
+
+		alg, path, digest, err := parseLine(line, format)
+		if err == nil && !validDigest(alg, digest) {
+			err = fmt.Errorf("invalid %s digest: %q", alg, digest)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+
+		path = cleanPath(path)
+		i, ok := index[path]
+		if !ok {
+			i = len(medias)
+			index[path] = i
+			medias = append(medias, medhash.Media{Path: path})
+		}
+		medias[i].Hash.Set(alg, strings.ToLower(digest))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return medias, nil
+}
+
+// parseLine parses a single line in format.
+func parseLine(line string, format Format) (alg, path, digest string, err error) {
+	if format == FormatSFV {
+		return parseSFV(line)
+	}
+
+	escaped := strings.HasPrefix(line, "\\")
+	if escaped {
+		line = line[1:]
+	}
+
+	if format == FormatBSD || (format == "" && strings.Contains(line, " (") &&
+		strings.Contains(line, ") = ")) {
+		alg, path, digest, err = parseBSD(line)
+	} else {
+		alg, path, digest, err = parseGNU(line, format)
+	}
+	if err != nil {
+		return
+	}
+
+	if escaped {
+		path = unescape(path)
+	}
+	return
+}
+
+// parseGNU parses a line in the GNU coreutils format.
+// The line must not contain the escape prefix.
+func parseGNU(line string, format Format) (alg, path, digest string, err error) {
+	digest, path, ok := strings.Cut(line, " ")
+	if !ok || path == "" {
+		err = fmt.Errorf("malformed line: %q", line)
+		return
+	}
+	// The second separator character is either a space (text mode) or an asterisk (binary mode).
+	if path[0] == ' ' || path[0] == '*' {
+		path = path[1:]
+	}
+
+	alg = format.Algorithm()
+	if alg == "" {
+		alg, digest = inferAlgorithm(digest)
+	}
+	if alg == "" {
+		err = fmt.Errorf("cannot infer algorithm of digest: %q", digest)
+	}
+	return
+}
+
+// parseBSD parses a line in the BSD tag format.
+// The line must not contain the escape prefix.
+func parseBSD(line string) (alg, path, digest string, err error) {
+	tag, rest, ok := strings.Cut(line, " (")
+	end := strings.LastIndex(rest, ") = ")
+	if !ok || end < 0 {
+		err = fmt.Errorf("malformed line: %q", line)
+		return
+	}
+	path = rest[:end]
+	digest = rest[end+len(") = "):]
+
+	for a, t := range tags {
+		if strings.EqualFold(t, tag) {
+			alg = a
+		}
+	}
+	if alg == "" {
+		err = fmt.Errorf("unsupported algorithm: %s", tag)
+	}
+	return
+}
+
+// parseSFV parses a line in the SFV format.
+// The checksum is the last field, as the path may contain spaces.
+// Backslash path separators, as written by Windows tools, are converted to slashes.
+func parseSFV(line string) (alg, path, digest string, err error) {
+	line = strings.TrimRight(line, " \t")
+	i := strings.LastIndexAny(line, " \t")
+	if i < 0 {
+		err = fmt.Errorf("malformed line: %q", line)
+		return
+	}
+
+	alg = "crc32"
+	path = strings.ReplaceAll(strings.TrimRight(line[:i], " \t"), "\\", "/")
+	digest = line[i+1:]
+	if path == "" || len(digest) != 8 {
+		err = fmt.Errorf("malformed line: %q", line)
+	}
+	return
+}
+
+// inferAlgorithm infers the algorithm of digest from its length.
+// A 64-character digest is assumed to be SHA256.
+// An 8-character digest is assumed to be CRC32.
+// The XXH3_ prefix of xxhsum is stripped from digest.
+func inferAlgorithm(digest string) (alg, stripped string) {
+	if d, ok := strings.CutPrefix(digest, "XXH3_"); ok {
+		return "xxh3", d
+	}
+
+	switch len(digest) {
+	case 8:
+		return "crc32", digest
+	case 16:
+		return "xxh3", digest
+	case 32:
+		return "md5", digest
+	case 40:
+		return "sha1", digest
+	case 64:
+		return "sha256", digest
+	case 128:
+		return "sha512", digest
+	default:
+		return "", digest
+	}
+}
+
+// validDigest reports whether digest is a hex digest of the length of alg.
+func validDigest(alg, digest string) bool {
+	if len(digest) != digestLens[alg] {
+		return false
+	}
+	_, err := hex.DecodeString(digest)
+	return err == nil
+}
+
+// cleanPath returns the shortest path equivalent to p, as path.Clean does.
+func cleanPath(p string) string {
+	return path.Clean(p)
+}
+
+// escape escapes path as GNU coreutils do.
+// prefix is a backslash if path is escaped.
+func escape(path string) (prefix, escaped string) {
+	if !strings.ContainsAny(path, "\\\r\n") {
+		return "", path
+	}
+	return "\\", escaper.Replace(path)
+}
+
+// unescape reverses escape.
+func unescape(path string) string {
+	return unescaper.Replace(path)
+}
+
+var (
+	escaper   = strings.NewReplacer("\\", "\\\\", "\r", "\\r", "\n", "\\n")
+	unescaper = strings.NewReplacer("\\\\", "\\", "\\r", "\r", "\\n", "\n")
+)
@@ -0,0 +1,212 @@
+package checksum_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/checksum"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	md5A    = "0cc175b9c0f1b6a831c399e269772661"
+	sha1A   = "86f7e437faa5a7fce15d1ddcb9eaeaea377667b8"
+	sha256A = "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"
+	crc32A  = "e8b7be43"
+)
+
+func TestFormatGNU(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	require.Equal(sha256A+"  plain", checksum.FormatGNU("plain", sha256A))
+	require.Equal("\\"+sha256A+"  a\\\\b\\nc", checksum.FormatGNU("a\\b\nc", sha256A))
+}
+
+func TestFormatBSDTag(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	require.Equal("SHA256 (plain) = "+sha256A, checksum.FormatBSDTag("sha256", "plain", sha256A))
+	require.Equal("\\SHA3-256 (a\\nb) = abc", checksum.FormatBSDTag("sha3", "a\nb", "abc"))
+}
+
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		id       string
+		format   checksum.Format
+		input    string
+		expected []medhash.Media
+	}{
+		{
+			id:     "gnu/text",
+			format: checksum.FormatSHA256Sum,
+			input:  sha256A + "  a\n",
+			expected: []medhash.Media{
+				{Path: "a", Hash: medhash.Hash{SHA256: sha256A}},
+			},
+		},
+		{
+			id:     "gnu/binary",
+			format: checksum.FormatMD5Sum,
+			input:  md5A + " *dir/a\n",
+			expected: []medhash.Media{
+				{Path: "dir/a", Hash: medhash.Hash{MD5: md5A}},
+			},
+		},
+		{
+			id:     "gnu/escaped",
+			format: checksum.FormatSHA1Sum,
+			input:  "\\" + sha1A + "  a\\\\b\\nc\n",
+			expected: []medhash.Media{
+				{Path: "a\\b\nc", Hash: medhash.Hash{SHA1: sha1A}},
+			},
+		},
+		{
+			id:     "gnu/infer",
+			format: "",
+			input:  "# comment\n\n" + md5A + "  a\r\n" + sha1A + "  a\n",
+			expected: []medhash.Media{
+				{Path: "a", Hash: medhash.Hash{MD5: md5A, SHA1: sha1A}},
+			},
+		},
+		{
+			id:     "gnu/clean",
+			format: checksum.FormatMD5Sum,
+			input:  md5A + "  ./dir//a\n" + md5A + "  dir/a\n",
+			expected: []medhash.Media{
+				{Path: "dir/a", Hash: medhash.Hash{MD5: md5A}},
+			},
+		},
+		{
+			id:     "bsd",
+			format: checksum.FormatBSD,
+			input:  "MD5 (a) = " + md5A + "\nSHA256 (b (1)) = " + sha256A + "\n",
+			expected: []medhash.Media{
+				{Path: "a", Hash: medhash.Hash{MD5: md5A}},
+				{Path: "b (1)", Hash: medhash.Hash{SHA256: sha256A}},
+			},
+		},
+		{
+			id:     "sfv",
+			format: checksum.FormatSFV,
+			input: "; Generated by a scanner\n" + "Reel 1\\scan 0001.dpx " + strings.ToUpper(crc32A) +
+				"\r\n" + "b\t" + crc32A + "\n",
+			expected: []medhash.Media{
+				{Path: "Reel 1/scan 0001.dpx", Hash: medhash.Hash{CRC32: crc32A}},
+				{Path: "b", Hash: medhash.Hash{CRC32: crc32A}},
+			},
+		},
+		{
+			id:     "gnu/infer/crc32",
+			format: "",
+			input:  crc32A + "  a\n",
+			expected: []medhash.Media{
+				{Path: "a", Hash: medhash.Hash{CRC32: crc32A}},
+			},
+		},
+		{
+			id:     "bsd/infer",
+			format: "",
+			input:  "SHA1 (a) = " + sha1A + "\n",
+			expected: []medhash.Media{
+				{Path: "a", Hash: medhash.Hash{SHA1: sha1A}},
+			},
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.id, func(t *testing.T) {
+			t.Parallel()
+
+			medias, err := checksum.Read(strings.NewReader(testCase.input), testCase.format)
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, medias)
+		})
+	}
+}
+
+func TestReadMalformed(t *testing.T) {
+	t.Parallel()
+
+	_, err := checksum.Read(strings.NewReader("abc\n"), "")
+	require.Error(t, err)
+
+	_, err = checksum.Read(strings.NewReader("FOO (a) = abc\n"), checksum.FormatBSD)
+	require.Error(t, err)
+}
+
+func TestReadInvalidDigest(t *testing.T) {
+	t.Parallel()
+
+	inputs := map[checksum.Format]string{
+		checksum.FormatSHA256Sum: md5A + "  a\n",
+		checksum.FormatMD5Sum:    strings.Repeat("z", 32) + "  a\n",
+		checksum.FormatBSD:       "MD5 (a) = " + sha1A + "\n",
+		checksum.FormatSFV:       "a 1A2B3C4G\n",
+	}
+	for format, input := range inputs {
+		_, err := checksum.Read(strings.NewReader(input), format)
+		require.Error(t, err, format)
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	t.Parallel()
+
+	medias := []medhash.Media{
+		{Path: "a", Hash: medhash.Hash{MD5: md5A, SHA1: sha1A, SHA256: sha256A}},
+		{Path: "dir/b\nc", Hash: medhash.Hash{MD5: md5A, SHA1: sha1A, SHA256: sha256A}},
+	}
+
+	for _, format := range checksum.Formats {
+		t.Run(string(format), func(t *testing.T) {
+			t.Parallel()
+
+			require := require.New(t)
+
+			var buf bytes.Buffer
+			err := checksum.Write(&buf, format, medias)
+			// The medias have no SHA512 or CRC32 hashes.
+			if format == checksum.FormatSHA512Sum || format == checksum.FormatSFV {
+				require.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			actual, err := checksum.Read(&buf, format)
+			require.NoError(err)
+			require.Len(actual, len(medias))
+			for i, med := range medias {
+				require.Equal(med.Path, actual[i].Path)
+				if alg := format.Algorithm(); alg != "" {
+					require.Equal(med.Hash.Get(alg), actual[i].Hash.Get(alg))
+				} else {
+					require.Equal(med.Hash, actual[i].Hash)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteSFV(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	medias := []medhash.Media{{Path: "dir/a b", Hash: medhash.Hash{CRC32: crc32A}}}
+
+	var buf bytes.Buffer
+	require.NoError(checksum.Write(&buf, checksum.FormatSFV, medias))
+	require.Equal("dir/a b "+strings.ToUpper(crc32A)+"\n", buf.String())
+
+	actual, err := checksum.Read(&buf, checksum.FormatSFV)
+	require.NoError(err)
+	require.Equal(medias, actual)
+
+	medias[0].Path = "a\nb"
+	require.Error(checksum.Write(&buf, checksum.FormatSFV, medias))
+}
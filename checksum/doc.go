@@ -0,0 +1,7 @@
+// Package checksum reads and writes checksum files of other tools.
+//
+// The GNU coreutils formats (e.g. sha256sum), the BSD tag format, and the SFV format are
+// supported.
+// Checksum files are converted to and from medhash.Media, so that they can be imported into and
+// exported from a MedHash Manifest.
+package checksum
@@ -0,0 +1,456 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ghifari160/medhash-tools/cmd"
+	"github.com/ghifari160/medhash-tools/color"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/urfave/cli/v3"
+)
+
+func init() {
+	cmd.RegisterCmd(CommandAudit())
+}
+
+func CommandAudit() *cli.Command {
+	return &cli.Command{
+		Name:      "audit",
+		Usage:     "verify a manifest and record the result in its audit log",
+		ArgsUsage: "[dir]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "manifest",
+				Aliases: []string{"m"},
+				Usage:   "use this manifest",
+			},
+			&cli.StringFlag{
+				Name:  "log",
+				Usage: "append to this audit log instead of the one next to the manifest",
+			},
+		},
+		MutuallyExclusiveFlags: []cli.MutuallyExclusiveFlags{
+			{
+				Flags: [][]cli.Flag{
+					{
+						&cli.BoolFlag{
+							Name:  "all",
+							Usage: "use all algorithms",
+						},
+					},
+					cmd.HashAlgs(),
+				},
+			},
+		},
+		Action: AuditAction,
+		Commands: []*cli.Command{
+			CommandHistory(),
+		},
+	}
+}
+
+func CommandHistory() *cli.Command {
+	return &cli.Command{
+		Name:      "history",
+		Usage:     "summarise past audits",
+		ArgsUsage: "[dir]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "manifest",
+				Aliases: []string{"m"},
+				Usage:   "use the audit log of this manifest",
+			},
+			&cli.StringFlag{
+				Name:  "log",
+				Usage: "use this audit log",
+			},
+		},
+		Action: HistoryAction,
+	}
+}
+
+func AuditAction(ctx context.Context, command *cli.Command) error {
+	manPath, err := manifestPath(command)
+	if err != nil {
+		return err
+	}
+	logPath := command.String("log")
+	if logPath == "" {
+		logPath = LogPath(manPath)
+	}
+
+	// Without algorithm flags, every algorithm of the Manifest is verified.
+	var config medhash.Config
+	if cmd.AlgsSet(command) {
+		config = cmd.HashConfig(command)
+	}
+
+	cmd.Printf("Auditing MedHash for %s", manPath)
+
+	record, err := AuditFunc(manPath, config, time.Now())
+	if err != nil {
+		return cmd.Result(err)
+	}
+
+	cmd.Printf("Recording audit in %s", logPath)
+	if err := AppendRecord(logPath, record); err != nil {
+		return cmd.Result(err)
+	}
+
+	var errs error
+	for _, failure := range record.Failures {
+		errs = cmd.JoinErrors(errs, failure.Err())
+	}
+	return cmd.Result(errs)
+}
+
+func HistoryAction(ctx context.Context, command *cli.Command) error {
+	logPath := command.String("log")
+	if logPath == "" {
+		manPath, err := manifestPath(command)
+		if err != nil {
+			return err
+		}
+		logPath = LogPath(manPath)
+	}
+
+	records, err := ReadLog(logPath)
+	if err != nil {
+		return cmd.Result(err)
+	}
+	if len(records) < 1 {
+		return cmd.Result(fmt.Errorf("%s: no audit recorded", logPath))
+	}
+
+	cmd.Printf("Audit history of %s", logPath)
+	w := command.Root().Writer
+	for i, record := range records {
+		status := cmd.MsgStatusOK
+		if !record.Passed() {
+			status = cmd.MsgStatusError
+		}
+
+		var counts []string
+		for _, s := range statuses {
+			counts = append(counts, fmt.Sprintf("%s %d", s, record.Counts[s]))
+		}
+		color.Fprintf(w, "  %s: %s (%s)\n", record.Time.Format(time.RFC3339), status,
+			strings.Join(counts, ", "))
+
+		if i > 0 && record.Digest != records[i-1].Digest {
+			color.Fprintf(w, "    manifest changed since the previous audit\n")
+		}
+	}
+
+	failing := Failing(records)
+	for _, f := range failing {
+		since := "since " + f.Since.Format(time.RFC3339)
+		if f.LastPassed.IsZero() {
+			since += ", never passed"
+		} else {
+			since += ", last passed " + f.LastPassed.Format(time.RFC3339)
+		}
+		color.Fprintf(w, "  %s: %s %s\n", f.Path, statusLabel(f.Status), since)
+	}
+
+	last := records[len(records)-1]
+	color.Fprintf(w, "   Audits: %d, from %s to %s\n", len(records), records[0].Time.Format(time.RFC3339),
+		last.Time.Format(time.RFC3339))
+	color.Fprintf(w, "  Failing: %d\n", len(failing))
+
+	if !last.Passed() {
+		return cmd.Result(errors.New("last audit failed"))
+	}
+	return cmd.Result(nil)
+}
+
+// manifestPath returns the path of the Manifest selected by command.
+func manifestPath(command *cli.Command) (string, error) {
+	if manPath := command.String("manifest"); manPath != "" {
+		return manPath, nil
+	}
+
+	switch command.Args().Len() {
+	case 0:
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", cli.Exit(fmt.Errorf("cannot get working directory: %w", err), cmd.ExitIO)
+		}
+		return filepath.Join(cwd, medhash.DefaultManifestName), nil
+	case 1:
+		return filepath.Join(command.Args().First(), medhash.DefaultManifestName), nil
+	default:
+		return "", cli.Exit("at most one target directory can be audited", cmd.ExitUsage)
+	}
+}
+
+// Status is the audit status of a media.
+type Status string
+
+const (
+	StatusOK       Status = "ok"
+	StatusMismatch Status = "mismatch"
+	StatusMissing  Status = "missing"
+	StatusError    Status = "error"
+)
+
+// statuses lists all statuses in the order they are summarised.
+var statuses = []Status{StatusOK, StatusMismatch, StatusMissing, StatusError}
+
+// Failure is a media that failed an audit.
+type Failure struct {
+	Path   string `json:"path"`
+	Status Status `json:"status"`
+	Error  string `json:"error"`
+}
+
+// Err returns failure as an error.
+// The error matches medhash.ErrMismatch or fs.ErrNotExist with errors.Is, according to its status.
+func (failure Failure) Err() error {
+	return failureErr{failure}
+}
+
+// failureErr is the error of a Failure.
+type failureErr struct {
+	failure Failure
+}
+
+func (err failureErr) Error() string {
+	return err.failure.Error
+}
+
+func (err failureErr) Is(target error) bool {
+	switch err.failure.Status {
+	case StatusMismatch:
+		return target == medhash.ErrMismatch
+	case StatusMissing:
+		return target == fs.ErrNotExist
+	default:
+		return false
+	}
+}
+
+// Record is the record of an audit, as stored in the audit log.
+type Record struct {
+	Time      time.Time `json:"time"`
+	Generator string    `json:"generator"`
+	Manifest  string    `json:"manifest"`
+	// Digest is the SHA256 hash of the Manifest file.
+	Digest string `json:"digest"`
+	// Algorithms lists the algorithms verified.
+	Algorithms []string `json:"algorithms"`
+	// Media lists the paths of the media audited, sorted.
+	Media    []string       `json:"media"`
+	Counts   map[Status]int `json:"counts"`
+	Failures []Failure      `json:"failures"`
+}
+
+// Passed reports whether every media of the audit was verified.
+func (record Record) Passed() bool {
+	return len(record.Failures) == 0
+}
+
+// Audited reports whether the media at path was audited.
+func (record Record) Audited(path string) bool {
+	_, found := slices.BinarySearch(record.Media, path)
+	return found
+}
+
+// LogPath returns the path of the audit log of the Manifest at manPath.
+func LogPath(manPath string) string {
+	return filepath.Join(filepath.Dir(manPath), cmd.AuditLogName)
+}
+
+// AuditFunc verifies the media listed by the Manifest at manPath, including those of child
+// Manifests, and returns the record of the audit started at now.
+// Media are verified against the algorithms of config, or every algorithm of the Manifest if
+// config has no algorithm.
+// Failing media are recorded rather than returned as errors.
+func AuditFunc(manPath string, config medhash.Config, now time.Time) (Record, error) {
+	manFile, err := os.ReadFile(manPath)
+	if err != nil {
+		return Record{}, err
+	}
+	digest := sha256.Sum256(manFile)
+
+	root, err := cmd.MediaRoot(manPath)
+	if err != nil {
+		return Record{}, err
+	}
+	medias, err := cmd.ReadMedia(manPath)
+	if err != nil {
+		return Record{}, err
+	}
+
+	if len(config.Algorithms()) < 1 {
+		config = cmd.MediaConfig(&medhash.Manifest{Media: medias})
+	}
+	config.Dir = root
+
+	absMan, err := filepath.Abs(manPath)
+	if err != nil {
+		absMan = manPath
+	}
+
+	record := Record{
+		Time:       now.UTC(),
+		Generator:  cmd.Generator(""),
+		Manifest:   absMan,
+		Digest:     hex.EncodeToString(digest[:]),
+		Algorithms: config.Algorithms(),
+		Media:      make([]string, 0, len(medias)),
+		Counts:     make(map[Status]int),
+		Failures:   make([]Failure, 0),
+	}
+
+	for _, med := range medias {
+		err := med.Check(config)
+		cmd.Status(filepath.Join(root, med.Path), err)
+
+		record.Media = append(record.Media, med.Path)
+		status := status(err)
+		record.Counts[status]++
+		if err != nil {
+			record.Failures = append(record.Failures, Failure{Path: med.Path, Status: status, Error: err.Error()})
+		}
+	}
+	slices.Sort(record.Media)
+	return record, nil
+}
+
+// status returns the Status of the result of checking a media.
+func status(err error) Status {
+	if err == nil {
+		return StatusOK
+	} else if errors.Is(err, medhash.ErrMismatch) {
+		return StatusMismatch
+	} else if errors.Is(err, fs.ErrNotExist) {
+		return StatusMissing
+	} else {
+		return StatusError
+	}
+}
+
+// statusLabel returns the status label of s.
+func statusLabel(s Status) string {
+	switch s {
+	case StatusOK:
+		return cmd.MsgStatusOK
+	case StatusMismatch:
+		return cmd.MsgStatusMismatch
+	case StatusMissing:
+		return cmd.MsgStatusMissing
+	default:
+		return cmd.MsgStatusError
+	}
+}
+
+// AppendRecord appends record to the audit log at logPath, as a JSON line.
+// The audit log is created if it does not exist.
+func AppendRecord(logPath string, record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadLog reads the records of the audit log at logPath, sorted by time.
+func ReadLog(logPath string) ([]Record, error) {
+	f, err := os.Open(logPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records := make([]Record, 0)
+	scanner := bufio.NewScanner(f)
+	// Records of large failing audits can exceed the default line limit.
+	scanner.Buffer(nil, 64*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s: line %d: %w", logPath, n, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(records, func(a, b Record) int {
+		return a.Time.Compare(b.Time)
+	})
+	return records, nil
+}
+
+// FailingMedia is a media failing the last audit.
+type FailingMedia struct {
+	Path   string
+	Status Status
+	// Since is the time of the first audit of the failures leading to the last audit.
+	Since time.Time
+	// LastPassed is the time of the last audit the media passed, or zero if it never passed.
+	LastPassed time.Time
+}
+
+// Failing returns the media failing the last of records, sorted by path, with the time they first
+// started failing.
+// records must be sorted by time.
+func Failing(records []Record) []FailingMedia {
+	if len(records) < 1 {
+		return nil
+	}
+
+	last := records[len(records)-1]
+	failing := make([]FailingMedia, 0, len(last.Failures))
+	for _, failure := range last.Failures {
+		f := FailingMedia{Path: failure.Path, Status: failure.Status, Since: last.Time}
+
+		for i := len(records) - 2; i >= 0; i-- {
+			if !records[i].Audited(failure.Path) {
+				// The media was not listed by the Manifest at the time.
+				break
+			}
+			if !slices.ContainsFunc(records[i].Failures, func(other Failure) bool {
+				return other.Path == failure.Path
+			}) {
+				f.LastPassed = records[i].Time
+				break
+			}
+			f.Since = records[i].Time
+		}
+
+		failing = append(failing, f)
+	}
+
+	slices.SortFunc(failing, func(a, b FailingMedia) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return failing
+}
@@ -0,0 +1,126 @@
+package audit_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ghifari160/medhash-tools/cmd"
+	"github.com/ghifari160/medhash-tools/cmd/audit"
+	"github.com/ghifari160/medhash-tools/cmd/gen"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/ghifari160/medhash-tools/testcommon"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+func TestAudit(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+	require.NoError(os.WriteFile(filepath.Join(dir, "gone"), []byte("gone"), 0644))
+
+	conf := medhash.DefaultConfig
+	conf.Dir = dir
+	conf.Manifest = medhash.DefaultManifestName
+	require.NoError(gen.GenFunc(conf, []string{medhash.DefaultManifestName}))
+
+	manPath := conf.ManifestPath()
+	logPath := audit.LogPath(manPath)
+	require.Equal(filepath.Join(dir, cmd.AuditLogName), logPath)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	run := func(day int) audit.Record {
+		t.Helper()
+
+		record, err := audit.AuditFunc(manPath, medhash.Config{}, start.AddDate(0, 0, day))
+		require.NoError(err)
+		require.NoError(audit.AppendRecord(logPath, record))
+		return record
+	}
+
+	record := run(0)
+	require.True(record.Passed())
+	require.Equal(medhash.DefaultConfig.Algorithms(), record.Algorithms)
+	require.Equal(map[audit.Status]int{audit.StatusOK: 2}, record.Counts)
+	require.Len(record.Digest, 64)
+
+	require.NoError(os.Remove(filepath.Join(dir, "gone")))
+	record = run(1)
+	require.False(record.Passed())
+	require.Equal(map[audit.Status]int{audit.StatusOK: 1, audit.StatusMissing: 1}, record.Counts)
+
+	require.NoError(os.WriteFile(filepath.Join(dir, payload.Path), []byte("corrupt"), 0644))
+	record = run(2)
+	require.Equal(map[audit.Status]int{audit.StatusMismatch: 1, audit.StatusMissing: 1}, record.Counts)
+	require.Equal([]audit.Failure{
+		{Path: "gone", Status: audit.StatusMissing, Error: record.Failures[0].Error},
+		{Path: payload.Path, Status: audit.StatusMismatch, Error: record.Failures[1].Error},
+	}, record.Failures)
+
+	records, err := audit.ReadLog(logPath)
+	require.NoError(err)
+	require.Len(records, 3)
+
+	require.Equal([]audit.FailingMedia{
+		{Path: "gone", Status: audit.StatusMissing, Since: start.AddDate(0, 0, 1), LastPassed: start},
+		{
+			Path:       payload.Path,
+			Status:     audit.StatusMismatch,
+			Since:      start.AddDate(0, 0, 2),
+			LastPassed: start.AddDate(0, 0, 1),
+		},
+	}, audit.Failing(records))
+
+	command := audit.CommandAudit()
+	command.ExitErrHandler = func(ctx context.Context, c *cli.Command, err error) {}
+	require.Error(command.Run(t.Context(), []string{"audit", "history", dir}))
+
+	// The audit log is not listed by generated Manifests.
+	require.NoError(os.Remove(manPath))
+	command = gen.CommandGen()
+	require.NoError(command.Run(t.Context(), []string{"gen", dir}))
+	manifest := testcommon.ReadManifest(t, manPath)
+	for _, med := range manifest.Media {
+		require.NotEqual(cmd.AuditLogName, med.Path)
+	}
+}
+
+func TestFailing(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := []audit.Record{
+		{Time: start, Media: []string{"a", "b"}},
+		{
+			Time:  start.AddDate(0, 0, 1),
+			Media: []string{"a", "b", "c"},
+			Failures: []audit.Failure{
+				{Path: "a", Status: audit.StatusMismatch},
+				{Path: "c", Status: audit.StatusMismatch},
+			},
+		},
+		{
+			Time:  start.AddDate(0, 0, 2),
+			Media: []string{"a", "b", "c"},
+			Failures: []audit.Failure{
+				{Path: "a", Status: audit.StatusMismatch},
+				{Path: "b", Status: audit.StatusMissing},
+				{Path: "c", Status: audit.StatusMismatch},
+			},
+		},
+	}
+
+	require.Equal([]audit.FailingMedia{
+		{Path: "a", Status: audit.StatusMismatch, Since: start.AddDate(0, 0, 1), LastPassed: start},
+		{Path: "b", Status: audit.StatusMissing, Since: start.AddDate(0, 0, 2), LastPassed: start.AddDate(0, 0, 1)},
+		// c was not audited before it was added.
+		{Path: "c", Status: audit.StatusMismatch, Since: start.AddDate(0, 0, 1)},
+	}, audit.Failing(records))
+}
@@ -0,0 +1,506 @@
+package bag
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ghifari160/medhash-tools/bagit"
+	"github.com/ghifari160/medhash-tools/cmd"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/urfave/cli/v3"
+)
+
+func init() {
+	cmd.RegisterCmd(CommandBag())
+}
+
+func CommandBag() *cli.Command {
+	return &cli.Command{
+		Name:  "bag",
+		Usage: "create and validate BagIt bags",
+		Commands: []*cli.Command{
+			CommandCreate(),
+			CommandValidate(),
+		},
+	}
+}
+
+func CommandCreate() *cli.Command {
+	return &cli.Command{
+		Name:      "create",
+		Usage:     "convert a directory into a BagIt bag in place",
+		ArgsUsage: "<dir>",
+		Flags:     bagAlgs(),
+		Action:    CreateAction,
+	}
+}
+
+func CommandValidate() *cli.Command {
+	return &cli.Command{
+		Name:      "validate",
+		Usage:     "verify the completeness and fixity of BagIt bags",
+		ArgsUsage: "[bags...]",
+		Action:    ValidateAction,
+	}
+}
+
+// bagAlgs returns the hash algorithm flags of the algorithms with a BagIt name.
+func bagAlgs() []cli.Flag {
+	flags := make([]cli.Flag, 0)
+	for _, flag := range cmd.HashAlgs() {
+		if bagit.Algorithm(flag.Names()[0]) != "" {
+			flags = append(flags, flag)
+		}
+	}
+	return flags
+}
+
+func CreateAction(ctx context.Context, command *cli.Command) error {
+	config := cmd.HashConfig(command)
+	if len(bagit.Algorithms(config)) < 1 {
+		// BagIt recommends SHA512.
+		config = medhash.Config{SHA512: true}
+	}
+
+	if command.Args().Len() != 1 {
+		return cli.Exit("bag create requires exactly one target directory", cmd.ExitUsage)
+	}
+	config.Dir = command.Args().First()
+
+	cmd.Printf("Creating bag in %s", config.Dir)
+	return cmd.Result(CreateFunc(config, config.Dir))
+}
+
+func ValidateAction(ctx context.Context, command *cli.Command) error {
+	dirs := command.Args().Slice()
+	if len(dirs) < 1 {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return cli.Exit(fmt.Errorf("cannot get working directory: %w", err), cmd.ExitIO)
+		}
+		dirs = append(dirs, cwd)
+	}
+
+	var errs error
+	for i, dir := range dirs {
+		if len(dirs) > 1 {
+			cmd.Printf("[%d/%d] Validating bag %s", i+1, len(dirs), dir)
+		} else {
+			cmd.Printf("Validating bag %s", dir)
+		}
+
+		errs = cmd.JoinErrors(errs, ValidateFunc(dir))
+	}
+
+	return cmd.Result(errs)
+}
+
+// CreateFunc converts dir into a bag in place.
+// The contents of dir are moved into the payload directory, and hashed with the algorithms toggled
+// in config that have a BagIt name.
+// A payload manifest and a tag manifest are written for each of those algorithms.
+// If the bag cannot be created, dir is restored to its original contents.
+func CreateFunc(config medhash.Config, dir string) (err error) {
+	names := bagit.Algorithms(config)
+	if len(names) < 1 {
+		return errors.New("no hash algorithm with a BagIt name is selected")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, bagit.DeclarationFile)); err == nil {
+		return fmt.Errorf("%s is already a bag", dir)
+	}
+
+	if err := movePayload(dir); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = cmd.JoinErrors(err, unbag(dir))
+		}
+	}()
+
+	config.Dir = dir
+	manifest, err := medhash.NewWithConfig(config)
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	var errs error
+	payload := filepath.Join(dir, bagit.PayloadDir)
+	err = filepath.Walk(payload, func(p string, info fs.FileInfo, err error) error {
+		if err != nil {
+			err = fmt.Errorf("cannot access %s: %w", p, err)
+			cmd.StatusLabel(p, cmd.MsgStatusError, err)
+			errs = cmd.JoinErrors(errs, err)
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			cmd.StatusLabel(p, cmd.MsgStatusError, err)
+			errs = cmd.JoinErrors(errs, err)
+			return nil
+		}
+
+		if err := manifest.Add(rel); err != nil {
+			cmd.StatusLabel(p, cmd.MsgStatusError, err)
+			errs = cmd.JoinErrors(errs, err)
+			return nil
+		}
+		size += info.Size()
+
+		cmd.StatusLabel(p, cmd.MsgStatusOK, nil)
+		return nil
+	})
+	errs = cmd.JoinErrors(errs, err)
+	if errs != nil {
+		return errs
+	}
+
+	tagFiles := []string{bagit.DeclarationFile, bagit.InfoFile}
+
+	err = writeTags(filepath.Join(dir, bagit.DeclarationFile), []bagit.Tag{
+		{Label: bagit.TagVersion, Value: bagit.Version},
+		{Label: bagit.TagEncoding, Value: "UTF-8"},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeTags(filepath.Join(dir, bagit.InfoFile), []bagit.Tag{
+		{Label: bagit.TagBaggingDate, Value: time.Now().Format(time.DateOnly)},
+		{Label: bagit.TagSoftwareAgent, Value: cmd.Name + " v" + cmd.Version},
+		{Label: bagit.TagPayloadOxum, Value: bagit.Oxum(size, len(manifest.Media))},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		var buf bytes.Buffer
+		if err := bagit.WriteManifest(&buf, bagit.FromAlgorithm(name), manifest.Media); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, bagit.ManifestName(name)), buf.Bytes(), 0644); err != nil {
+			return err
+		}
+		tagFiles = append(tagFiles, bagit.ManifestName(name))
+	}
+
+	tagManifest, err := medhash.NewWithConfig(config)
+	if err != nil {
+		return err
+	}
+	for _, tagFile := range tagFiles {
+		if err := tagManifest.Add(tagFile); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range names {
+		var buf bytes.Buffer
+		if err := bagit.WriteManifest(&buf, bagit.FromAlgorithm(name), tagManifest.Media); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, bagit.TagManifestName(name)), buf.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// movePayload moves the contents of dir into its payload directory.
+// The contents are first moved into a temporary directory, as dir may contain an entry named after
+// the payload directory.
+func movePayload(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.MkdirTemp(dir, ".medhash-bag-")
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := os.Rename(filepath.Join(dir, entry.Name()), filepath.Join(tmp, entry.Name())); err != nil {
+			return cmd.JoinErrors(err, moveEntries(tmp, dir))
+		}
+	}
+
+	if err := os.Rename(tmp, filepath.Join(dir, bagit.PayloadDir)); err != nil {
+		return cmd.JoinErrors(err, moveEntries(tmp, dir))
+	}
+	return nil
+}
+
+// unbag undoes CreateFunc on dir.
+// The tag files of the bag in dir are removed, and the contents of its payload directory are moved
+// back into dir.
+func unbag(dir string) error {
+	var errs error
+
+	tagFiles := []string{filepath.Join(dir, bagit.DeclarationFile), filepath.Join(dir, bagit.InfoFile)}
+	for _, prefix := range []string{"manifest-", "tagmanifest-"} {
+		manPaths, err := filepath.Glob(filepath.Join(dir, prefix+"*.txt"))
+		errs = cmd.JoinErrors(errs, err)
+		tagFiles = append(tagFiles, manPaths...)
+	}
+	for _, tagFile := range tagFiles {
+		if err := os.Remove(tagFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = cmd.JoinErrors(errs, err)
+		}
+	}
+
+	// The payload directory may contain an entry named after itself.
+	tmp, err := os.MkdirTemp(dir, ".medhash-bag-")
+	if err != nil {
+		return cmd.JoinErrors(errs, err)
+	}
+	if err := os.Remove(tmp); err != nil {
+		return cmd.JoinErrors(errs, err)
+	}
+	if err := os.Rename(filepath.Join(dir, bagit.PayloadDir), tmp); err != nil {
+		return cmd.JoinErrors(errs, err)
+	}
+
+	return cmd.JoinErrors(errs, moveEntries(tmp, dir))
+}
+
+// moveEntries moves the contents of src into dst, and removes src.
+func moveEntries(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	var errs error
+	for _, entry := range entries {
+		err := os.Rename(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name()))
+		errs = cmd.JoinErrors(errs, err)
+	}
+	if errs != nil {
+		return errs
+	}
+
+	return os.Remove(src)
+}
+
+// writeTags writes tags to the tag file at p.
+func writeTags(p string, tags []bagit.Tag) error {
+	var buf bytes.Buffer
+	if err := bagit.WriteTags(&buf, tags); err != nil {
+		return err
+	}
+	return os.WriteFile(p, buf.Bytes(), 0644)
+}
+
+// ValidateFunc validates the bag in dir.
+// A bag is valid if it is complete, and every payload and tag file passes its fixity checks.
+// A bag is complete if every file listed in its payload manifests exists, every payload file is
+// listed in all of its payload manifests, and its Payload-Oxum (if any) matches the payload.
+func ValidateFunc(dir string) error {
+	declFile, err := os.ReadFile(filepath.Join(dir, bagit.DeclarationFile))
+	if err != nil {
+		return err
+	}
+	tags, err := bagit.ReadTags(bytes.NewReader(declFile))
+	if err != nil {
+		return fmt.Errorf("%s: %w", bagit.DeclarationFile, err)
+	}
+	if _, ok := bagit.Lookup(tags, bagit.TagVersion); !ok {
+		return fmt.Errorf("%s: no %s", bagit.DeclarationFile, bagit.TagVersion)
+	}
+
+	payloads, n, err := readManifests(dir, "manifest-")
+	if err != nil {
+		return err
+	}
+	if n < 1 {
+		return fmt.Errorf("%s: no payload manifest", dir)
+	}
+	tagManifests, _, err := readManifests(dir, "tagmanifest-")
+	if err != nil {
+		return err
+	}
+
+	var errs error
+	for _, med := range payloads {
+		errs = cmd.JoinErrors(errs, bagPath(med.Path, true))
+	}
+	for _, med := range tagManifests {
+		errs = cmd.JoinErrors(errs, bagPath(med.Path, false))
+	}
+	if errs != nil {
+		return errs
+	}
+
+	files, size, err := payloadFiles(dir)
+	if err != nil {
+		return err
+	}
+	errs = cmd.JoinErrors(errs, chkOxum(dir, size, len(files)))
+
+	config := medhash.AllConfig
+	config.Dir = dir
+
+	for _, medias := range [][]medhash.Media{payloads, tagManifests} {
+		for _, med := range medias {
+			err := med.Check(config)
+			errs = cmd.JoinErrors(errs, err)
+			cmd.Status(filepath.Join(dir, filepath.FromSlash(med.Path)), err)
+		}
+	}
+
+	listed := make(map[string]bool, len(payloads))
+	for _, med := range payloads {
+		listed[path.Clean(med.Path)] = true
+	}
+	for _, file := range files {
+		if listed[file] {
+			continue
+		}
+		p := filepath.Join(dir, filepath.FromSlash(file))
+		err := fmt.Errorf("%s: %w", p, cmd.ErrExtra)
+		cmd.StatusLabel(p, cmd.MsgStatusExtra, err)
+		errs = cmd.JoinErrors(errs, err)
+	}
+
+	return errs
+}
+
+// bagPath returns an error if p, listed in a manifest of a bag, escapes the bag.
+// Paths listed in payload manifests must also be located in the payload directory.
+func bagPath(p string, payload bool) error {
+	if !filepath.IsLocal(filepath.FromSlash(p)) {
+		return fmt.Errorf("%s: path escapes the bag", p)
+	}
+	if payload && !strings.HasPrefix(path.Clean(p), bagit.PayloadDir+"/") {
+		return fmt.Errorf("%s: not in the %s directory", p, bagit.PayloadDir)
+	}
+	return nil
+}
+
+// readManifests reads all manifests in dir with the name prefix (e.g. "manifest-").
+// n is the number of manifests read.
+// Hashes of the same path are merged into a single media.
+// Every payload file must be listed in every payload manifest, so it is an error for a media to
+// be missing from any of the manifests.
+func readManifests(dir, prefix string) (medias []medhash.Media, n int, err error) {
+	manPaths, err := filepath.Glob(filepath.Join(dir, prefix+"*.txt"))
+	if err != nil {
+		return
+	}
+	n = len(manPaths)
+
+	medias = make([]medhash.Media, 0)
+	index := make(map[string]int)
+	counts := make(map[string]int)
+
+	for _, manPath := range manPaths {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(manPath), prefix), ".txt")
+		alg := bagit.FromAlgorithm(name)
+		if alg == "" {
+			return nil, n, fmt.Errorf("%s: unsupported algorithm: %s", manPath, name)
+		}
+
+		f, err := os.Open(manPath)
+		if err != nil {
+			return nil, n, err
+		}
+		manMedias, err := bagit.ReadManifest(f, alg)
+		f.Close()
+		if err != nil {
+			return nil, n, fmt.Errorf("%s: %w", manPath, err)
+		}
+
+		for _, med := range manMedias {
+			i, ok := index[med.Path]
+			if !ok {
+				i = len(medias)
+				index[med.Path] = i
+				medias = append(medias, medhash.Media{Path: med.Path})
+			}
+			medias[i].Hash.Set(alg, med.Hash.Get(alg))
+			counts[med.Path]++
+		}
+	}
+
+	for _, med := range medias {
+		if counts[med.Path] != n {
+			err = cmd.JoinErrors(err, fmt.Errorf("%s: not listed in all %s*.txt", med.Path, prefix))
+		}
+	}
+	if err != nil {
+		return nil, n, err
+	}
+
+	return
+}
+
+// payloadFiles returns the slash-separated paths, relative to dir, of the regular files in the
+// payload directory of the bag in dir, and their total size.
+func payloadFiles(dir string) (files []string, size int64, err error) {
+	err = filepath.Walk(filepath.Join(dir, bagit.PayloadDir), func(p string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, path.Clean(filepath.ToSlash(rel)))
+		size += info.Size()
+		return nil
+	})
+	return
+}
+
+// chkOxum verifies the Payload-Oxum of the bag in dir, if any, against the payload of size octets
+// in count files.
+func chkOxum(dir string, size int64, count int) error {
+	infoFile, err := os.ReadFile(filepath.Join(dir, bagit.InfoFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	tags, err := bagit.ReadTags(bytes.NewReader(infoFile))
+	if err != nil {
+		return fmt.Errorf("%s: %w", bagit.InfoFile, err)
+	}
+
+	oxum, ok := bagit.Lookup(tags, bagit.TagPayloadOxum)
+	if !ok {
+		return nil
+	}
+
+	oxumSize, oxumCount, err := bagit.ParseOxum(oxum)
+	if err != nil {
+		return fmt.Errorf("%s: %w", bagit.InfoFile, err)
+	}
+	if oxumSize != size || oxumCount != count {
+		return fmt.Errorf("%s: Payload-Oxum %s does not match payload %s", bagit.InfoFile, oxum,
+			bagit.Oxum(size, count))
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package bag_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/bagit"
+	"github.com/ghifari160/medhash-tools/cmd/bag"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/ghifari160/medhash-tools/testcommon"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBag(t *testing.T) {
+	t.Parallel()
+
+	cases := []testcommon.TestCase{
+		testcommon.Case("sha512", "sha512"),
+		testcommon.Case("sha256", "sha256"),
+		testcommon.Case("sha1", "sha1"),
+		testcommon.Case("md5", "md5"),
+	}
+
+	testcommon.RunCases(t, testBag, cases)
+}
+
+func testBag(t *testing.T, alg string, opts ...testcommon.Options) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+
+	command := bag.CommandCreate()
+	err := command.Run(t.Context(), []string{"create", "--" + alg, dir})
+	require.NoError(err)
+
+	require.FileExists(filepath.Join(dir, bagit.PayloadDir, payload.Path))
+	require.FileExists(filepath.Join(dir, bagit.TagManifestName(alg)))
+
+	manFile, err := os.ReadFile(filepath.Join(dir, bagit.ManifestName(alg)))
+	require.NoError(err)
+	require.Equal(payload.Hash.Get(alg)+"  data/"+payload.Path+"\n", string(manFile))
+
+	require.NoError(bag.ValidateFunc(dir))
+}
+
+func TestBagDefault(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+	require.NoError(os.Mkdir(filepath.Join(dir, bagit.PayloadDir), 0755))
+	require.NoError(os.WriteFile(filepath.Join(dir, bagit.PayloadDir, "a"), []byte("a"), 0644))
+
+	command := bag.CommandCreate()
+	err := command.Run(t.Context(), []string{"create", dir})
+	require.NoError(err)
+
+	require.FileExists(filepath.Join(dir, bagit.ManifestName("sha512")))
+	require.FileExists(filepath.Join(dir, bagit.PayloadDir, "payload"))
+	require.FileExists(filepath.Join(dir, bagit.PayloadDir, bagit.PayloadDir, "a"))
+
+	info, err := os.ReadFile(filepath.Join(dir, bagit.InfoFile))
+	require.NoError(err)
+	require.Contains(string(info), bagit.TagPayloadOxum+": "+
+		bagit.Oxum(testcommon.PayloadSize()+1, 2))
+
+	require.NoError(bag.ValidateFunc(dir))
+}
+
+func TestValidateInvalid(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		id     string
+		tamper func(t *testing.T, dir string)
+	}{
+		{
+			id: "mismatch",
+			tamper: func(t *testing.T, dir string) {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, bagit.PayloadDir, "a"), []byte("b"), 0644))
+			},
+		},
+		{
+			id: "missing",
+			tamper: func(t *testing.T, dir string) {
+				require.NoError(t, os.Remove(filepath.Join(dir, bagit.PayloadDir, "a")))
+			},
+		},
+		{
+			id: "extra",
+			tamper: func(t *testing.T, dir string) {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, bagit.PayloadDir, "b"), []byte("b"), 0644))
+			},
+		},
+		{
+			id: "escape",
+			tamper: func(t *testing.T, dir string) {
+				appendManifest(t, dir, "../a")
+			},
+		},
+		{
+			id: "outside",
+			tamper: func(t *testing.T, dir string) {
+				appendManifest(t, dir, bagit.InfoFile)
+			},
+		},
+		{
+			id: "tag",
+			tamper: func(t *testing.T, dir string) {
+				f, err := os.OpenFile(filepath.Join(dir, bagit.InfoFile), os.O_APPEND|os.O_WRONLY, 0644)
+				require.NoError(t, err)
+				defer f.Close()
+				_, err = f.WriteString("Contact-Name: Someone\n")
+				require.NoError(t, err)
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.id, func(t *testing.T) {
+			t.Parallel()
+
+			require := require.New(t)
+			dir := t.TempDir()
+			require.NoError(os.WriteFile(filepath.Join(dir, "a"), []byte("a"), 0644))
+
+			config := medhash.Config{SHA256: true}
+			require.NoError(bag.CreateFunc(config, dir))
+			require.NoError(bag.ValidateFunc(dir))
+
+			c.tamper(t, dir)
+
+			err := bag.ValidateFunc(dir)
+			require.Error(err)
+			switch c.id {
+			case "extra":
+				require.True(strings.Contains(err.Error(), "not listed"))
+			case "escape":
+				require.ErrorContains(err, "escapes the bag")
+			case "outside":
+				require.ErrorContains(err, "not in the data directory")
+			}
+		})
+	}
+}
+
+// appendManifest lists p in the SHA256 payload manifest of the bag in dir.
+func appendManifest(t *testing.T, dir, p string) {
+	f, err := os.OpenFile(filepath.Join(dir, bagit.ManifestName("sha256")), os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.WriteString(strings.Repeat("0", 64) + "  " + p + "\n")
+	require.NoError(t, err)
+}
+
+func TestCreateRollback(t *testing.T) {
+	t.Parallel()
+
+	if os.Geteuid() == 0 {
+		t.Skip("unreadable files are readable by root")
+	}
+
+	require := require.New(t)
+	dir := t.TempDir()
+	require.NoError(os.WriteFile(filepath.Join(dir, "a"), []byte("a"), 0644))
+	require.NoError(os.WriteFile(filepath.Join(dir, "b"), []byte("b"), 0))
+
+	err := bag.CreateFunc(medhash.Config{SHA256: true}, dir)
+	require.Error(err)
+
+	// The directory is restored to its original contents.
+	entries, err := os.ReadDir(dir)
+	require.NoError(err)
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	require.Equal([]string{"a", "b"}, names)
+}
@@ -2,13 +2,15 @@ package chk
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 
+	"github.com/ghifari160/medhash-tools/archive"
 	"github.com/ghifari160/medhash-tools/cmd"
-	"github.com/ghifari160/medhash-tools/color"
 	"github.com/ghifari160/medhash-tools/medhash"
 	"github.com/urfave/cli/v3"
 )
@@ -32,6 +34,32 @@ func CommandChk() *cli.Command {
 				Aliases: []string{"m"},
 				Usage:   "use this manifest",
 			},
+			&cli.StringFlag{
+				Name:    "root",
+				Aliases: []string{"r"},
+				Usage:   "resolve media paths against this directory",
+			},
+			&cli.StringFlag{
+				Name:    "archive",
+				Aliases: []string{"a"},
+				Usage:   "verify the members of this tar or zip archive",
+			},
+			&cli.StringFlag{
+				Name:  "sample",
+				Usage: "verify only a percentage of media, rotating daily (e.g. 5%)",
+			},
+			&cli.StringFlag{
+				Name:  "seed",
+				Usage: "seed the sample with this value (e.g. a date) instead of the current date",
+			},
+			&cli.StringFlag{
+				Name:  "max-rate",
+				Usage: "limit the rate media are read at (e.g. 200MB/s)",
+			},
+			&cli.BoolFlag{
+				Name:  "strict",
+				Usage: "fail on files not listed in the manifest",
+			},
 		},
 		MutuallyExclusiveFlags: []cli.MutuallyExclusiveFlags{
 			{
@@ -71,98 +99,399 @@ func ChkAction(ctx context.Context, command *cli.Command) error {
 		config.SHA256 = command.Bool("sha256")
 		config.SHA1 = command.Bool("sha1")
 		config.MD5 = command.Bool("md5")
+		config.CRC32 = command.Bool("crc32")
+	}
+
+	root := command.String("root")
+	strict := command.Bool("strict")
+	sel := selector{files: command.StringSlice("file")}
+
+	if percent := command.String("sample"); percent != "" {
+		sample, err := cmd.NewSample(percent, command.String("seed"))
+		if err != nil {
+			return cli.Exit(err, cmd.ExitUsage)
+		}
+		sel.sample = &sample
+		cmd.Printf("Sampling %s of media with seed %s", percent, sample.Seed)
+	} else if command.String("seed") != "" {
+		return cli.Exit("--seed requires --sample", cmd.ExitUsage)
+	}
+
+	if maxRate := command.String("max-rate"); maxRate != "" {
+		rate, err := cmd.ParseRate(maxRate)
+		if err != nil {
+			return cli.Exit(err, cmd.ExitUsage)
+		}
+		config.Limiter = medhash.NewLimiter(rate)
+	}
+
+	if archivePath := command.String("archive"); archivePath != "" {
+		if command.Args().Len() > 0 || root != "" {
+			return cli.Exit("--archive cannot be used with target directories or --root", cmd.ExitUsage)
+		}
+
+		manPath := command.String("manifest")
+		if manPath == "" {
+			manPath = filepath.Join(filepath.Dir(archivePath), medhash.DefaultManifestName)
+		}
+
+		cmd.Printf("Checking MedHash for %s", archivePath)
+		return cmd.Result(chkArchive(manPath, config, archivePath, sel, strict))
 	}
 
 	dirs := command.Args().Slice()
 	if len(dirs) < 1 {
-		cwd, err := os.Getwd()
-		if err != nil {
-			return cli.Exit(fmt.Errorf("cannot get working directory: %w", err), 1)
+		if command.String("manifest") != "" {
+			// The media root is resolved from the manifest.
+			dirs = append(dirs, "")
+		} else {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return cli.Exit(fmt.Errorf("cannot get working directory: %w", err), cmd.ExitIO)
+			}
+			dirs = append(dirs, cwd)
 		}
-		dirs = append(dirs, cwd)
+	}
+
+	if root != "" && len(dirs) > 1 {
+		return cli.Exit("--root requires a single target directory", cmd.ExitUsage)
 	}
 
 	var errs error
 	for i, dir := range dirs {
 		conf := config
 		conf.Dir = dir
+		if root != "" {
+			conf.Dir = root
+		}
 
 		manPath := command.String("manifest")
 		if manPath == "" {
 			manPath = filepath.Join(dir, medhash.DefaultManifestName)
 		}
 
+		target := conf.Dir
+		if target == "" {
+			target = manPath
+		}
+
 		if len(dirs) > 1 {
-			color.Printf("[%d/%d] Checking MedHash for %s\n", i+1, len(dirs), dir)
+			cmd.Printf("[%d/%d] Checking MedHash for %s", i+1, len(dirs), target)
 		} else {
-			color.Printf("Checking MedHash for %s\n", dir)
+			cmd.Printf("Checking MedHash for %s", target)
 		}
 
-		errs = cmd.JoinErrors(errs, chk(manPath, conf, command.StringSlice("files")))
+		total, files := chkSize(manPath, conf, sel, "")
+		progress := cmd.NewProgress(total, files)
+		errs = cmd.JoinErrors(errs, chk(manPath, conf, sel, "", progress))
+		progress.Close()
+
+		errs = cmd.JoinErrors(errs, chkExtra(manPath, conf, strict))
 	}
 
-	if errs != nil {
-		color.Println(cmd.MsgFinalError)
-		for _, err := range cmd.UnwrapJoinedErrors(errs) {
-			color.Println(err)
+	return cmd.Result(errs)
+}
+
+// chk verifies the Manifest at manPath.
+// If config.Dir is empty, media paths are resolved against the root recorded in the Manifest, or
+// the directory containing the Manifest if no root is recorded.
+// Child Manifests are verified recursively, and their media are resolved against the matching
+// subdirectory of config.Dir.
+// rel is the path of the Manifest directory relative to the top-level Manifest, and is used to
+// select media.
+// The progress of reading media is reported to progress.
+func chk(manPath string, config medhash.Config, sel selector, rel string, progress *cmd.Progress) error {
+	manifest, err := cmd.ReadManifest(manPath)
+	if err != nil {
+		return err
+	}
+
+	config.Dir = mediaDir(manifest, manPath, config)
+	config.Progress = progress.Update
+	manifest.Config = config
+
+	var errs error
+	for _, med := range manifest.Media {
+		medPath := filepath.Join(config.Dir, med.Path)
+
+		if ok, err := sel.selected(path.Join(rel, med.Path)); err != nil {
+			cmd.StatusLabel(medPath, cmd.MsgStatusError, err)
+			errs = cmd.JoinErrors(errs, err)
+			continue
+		} else if !ok {
+			cmd.StatusLabel(medPath, cmd.MsgStatusSkipped, nil)
+			continue
 		}
-		return cli.Exit("", 1)
+
+		var size int64
+		if info, err := os.Stat(medPath); err == nil {
+			size = info.Size()
+		}
+
+		progress.Start(med.Path, size)
+		err := med.Check(manifest.Config)
+		progress.Finish()
+
+		errs = cmd.JoinErrors(errs, err)
+		cmd.Status(medPath, err)
 	}
 
-	color.Println(cmd.MsgFinalDone)
-	return nil
+	// Child Manifests are read from beside manPath, so that a copy without Manifests can be
+	// verified against the Manifests of its source.
+	manDir := filepath.Dir(manPath)
+	manConfig := config
+	manConfig.Dir = manDir
+	for _, child := range manifest.Manifests {
+		err := child.Check(manConfig)
+		errs = cmd.JoinErrors(errs, err)
+		cmd.Status(filepath.Join(manDir, child.Path), err)
+		// The media of a child Manifest that does not match its hashes cannot be trusted.
+		if err != nil {
+			continue
+		}
+
+		childPath := filepath.Join(manDir, filepath.FromSlash(child.Path))
+		childConfig := config
+		childConfig.Dir = filepath.Join(config.Dir, filepath.FromSlash(path.Dir(child.Path)))
+
+		errs = cmd.JoinErrors(errs, chk(childPath, childConfig, sel, path.Dir(path.Join(rel, child.Path)),
+			progress))
+	}
+
+	return errs
 }
 
-func chk(manPath string, config medhash.Config, files []string) error {
-	manFile, err := os.ReadFile(manPath)
+// chkSize returns the total size and number of the media verified by chk.
+// Media that cannot be accessed are counted without a size.
+func chkSize(manPath string, config medhash.Config, sel selector, rel string) (total int64, files int) {
+	manifest, err := cmd.ReadManifest(manPath)
 	if err != nil {
-		return err
+		return 0, 0
 	}
+	config.Dir = mediaDir(manifest, manPath, config)
 
-	var manifest medhash.Manifest
+	for _, med := range manifest.Media {
+		if ok, err := sel.selected(path.Join(rel, med.Path)); !ok || err != nil {
+			continue
+		}
+
+		files++
+		if info, err := os.Stat(filepath.Join(config.Dir, med.Path)); err == nil {
+			total += info.Size()
+		}
+	}
+
+	manDir := filepath.Dir(manPath)
+	for _, child := range manifest.Manifests {
+		childPath := filepath.Join(manDir, filepath.FromSlash(child.Path))
+		childConfig := config
+		childConfig.Dir = filepath.Join(config.Dir, filepath.FromSlash(path.Dir(child.Path)))
+
+		t, f := chkSize(childPath, childConfig, sel, path.Dir(path.Join(rel, child.Path)))
+		total += t
+		files += f
+	}
 
-	err = json.Unmarshal(manFile, &manifest)
+	return total, files
+}
+
+// chkExtra reports the files in the media root of the Manifest at manPath that are not listed by
+// the Manifest or its child Manifests.
+// If config.Dir is empty, the media root is resolved as in chk.
+// Files are not reported if any of the Manifests cannot be read, as chk already fails for them.
+// Unlisted files, and the files that cannot be accessed, are only considered errors if strict is
+// true.
+func chkExtra(manPath string, config medhash.Config, strict bool) error {
+	manifest, err := cmd.ReadManifest(manPath)
 	if err != nil {
-		return err
+		return nil
+	}
+	dir := mediaDir(manifest, manPath, config)
+
+	paths := make(map[string]bool)
+	if err := listed(manifest, filepath.Dir(manPath), "", paths); err != nil {
+		return nil
+	}
+	for _, p := range []string{manPath, filepath.Join(filepath.Dir(manPath), cmd.AuditLogName)} {
+		if rel, inside := cmd.RelToDir(dir, p); inside {
+			paths[filepath.ToSlash(rel)] = true
+		}
 	}
-	manifest.Config = config
 
 	var errs error
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			errs = cmd.JoinErrors(errs, err)
+			cmd.Status(p, err)
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if paths[filepath.ToSlash(rel)] {
+			return nil
+		}
+
+		if !strict {
+			cmd.StatusLabel(p, cmd.MsgStatusExtra, nil)
+			return nil
+		}
+		err = fmt.Errorf("%s: %w", p, cmd.ErrExtra)
+		cmd.StatusLabel(p, cmd.MsgStatusExtra, err)
+		errs = cmd.JoinErrors(errs, err)
+		return nil
+	})
+	if !strict {
+		return nil
+	}
+	return cmd.JoinErrors(errs, err)
+}
+
+// listed adds the paths of the media and child Manifests listed by manifest and its child
+// Manifests to paths.
+// Child Manifests are read from manDir.
+// rel is the path of the Manifest directory relative to the top-level Manifest.
+func listed(manifest *medhash.Manifest, manDir, rel string, paths map[string]bool) error {
 	for _, med := range manifest.Media {
-		color.Printf("  %s: ", filepath.Join(config.Dir, med.Path))
+		paths[path.Join(rel, med.Path)] = true
+	}
 
-		if len(files) > 0 {
-			skipped := true
+	for _, child := range manifest.Manifests {
+		paths[path.Join(rel, child.Path)] = true
 
-			for _, file := range files {
-				matched, err := filepath.Match(file, med.Path)
-				if err != nil {
-					color.Println(cmd.MsgStatusError)
-					errs = cmd.JoinErrors(errs, err)
+		childManifest, err := cmd.ReadManifest(filepath.Join(manDir, filepath.FromSlash(child.Path)))
+		if err != nil {
+			return err
+		}
+		childDir := path.Dir(child.Path)
+		err = listed(childManifest, filepath.Join(manDir, filepath.FromSlash(childDir)),
+			path.Join(rel, childDir), paths)
+		if err != nil {
+			return err
+		}
+	}
 
-					continue
-				}
+	return nil
+}
 
-				if matched {
-					skipped = false
-					break
-				}
-			}
+// mediaDir returns the directory the media of manifest, read from manPath, are resolved against.
+// It is config.Dir, unless config.Dir is empty.
+// Then, it is the root recorded in manifest, or the directory containing the Manifest if no root is
+// recorded.
+func mediaDir(manifest *medhash.Manifest, manPath string, config medhash.Config) string {
+	if config.Dir != "" {
+		return config.Dir
+	} else if manifest.Root != "" {
+		return filepath.FromSlash(manifest.Root)
+	} else {
+		return filepath.Dir(manPath)
+	}
+}
 
-			if skipped {
-				color.Println(cmd.MsgStatusSkipped)
-				continue
-			}
+// chkArchive verifies the members of the archive at archivePath against the Manifest at manPath.
+// Members are streamed from the archive, and are never extracted.
+// Members not listed in the Manifest are reported, but are only considered errors if strict is true.
+// Media listed in the Manifest but absent from the archive are considered errors.
+func chkArchive(manPath string, config medhash.Config, archivePath string, sel selector,
+	strict bool) error {
+	manifest, err := cmd.ReadManifest(manPath)
+	if err != nil {
+		return err
+	}
+	config.Dir = archivePath
+	manifest.Config = config
+
+	medias := make(map[string]medhash.Media, len(manifest.Media))
+	for _, med := range manifest.Media {
+		medias[med.Path] = med
+	}
+	seen := make(map[string]bool, len(manifest.Media))
+
+	var errs error
+	err = archive.Walk(archivePath, func(name string, r io.Reader) error {
+		memberPath := filepath.Join(archivePath, name)
+
+		med, ok := medias[name]
+		if !ok && strict {
+			err := fmt.Errorf("%s: %w", memberPath, cmd.ErrExtra)
+			cmd.StatusLabel(memberPath, cmd.MsgStatusExtra, err)
+			errs = cmd.JoinErrors(errs, err)
+			return nil
+		} else if !ok {
+			cmd.StatusLabel(memberPath, cmd.MsgStatusExtra, nil)
+			return nil
 		}
+		seen[name] = true
 
-		err := med.Check(manifest.Config)
-		if err != nil {
+		if ok, err := sel.selected(name); err != nil {
+			cmd.StatusLabel(memberPath, cmd.MsgStatusError, err)
 			errs = cmd.JoinErrors(errs, err)
-			color.Println(cmd.MsgStatusError)
-		} else {
-			color.Println(cmd.MsgStatusOK)
+			return nil
+		} else if !ok {
+			cmd.StatusLabel(memberPath, cmd.MsgStatusSkipped, nil)
+			return nil
 		}
+
+		err := med.CheckReader(manifest.Config, r)
+		errs = cmd.JoinErrors(errs, err)
+		cmd.Status(memberPath, err)
+		return nil
+	})
+	if err != nil {
+		errs = cmd.JoinErrors(errs, err)
+	}
+
+	for _, med := range manifest.Media {
+		if seen[med.Path] {
+			continue
+		}
+		if ok, _ := sel.selected(med.Path); !ok {
+			continue
+		}
+
+		err := fmt.Errorf("%s: %w", filepath.Join(archivePath, med.Path), fs.ErrNotExist)
+		cmd.Status(filepath.Join(archivePath, med.Path), err)
+		errs = cmd.JoinErrors(errs, err)
 	}
 
 	return errs
 }
+
+// selector selects the media to verify.
+type selector struct {
+	// files lists patterns matching the selected media.
+	// If files is empty, all media are selected.
+	files []string
+	// sample selects a subset of the media matching files.
+	// If sample is nil, all media matching files are selected.
+	sample *cmd.Sample
+}
+
+// selected reports whether p matches any of the patterns in sel.files, and is in sel.sample.
+func (sel selector) selected(p string) (bool, error) {
+	if sel.sample != nil && !sel.sample.Selected(p) {
+		return false, nil
+	}
+	if len(sel.files) < 1 {
+		return true, nil
+	}
+
+	var errs error
+	for _, file := range sel.files {
+		matched, err := filepath.Match(file, p)
+		if err != nil {
+			errs = cmd.JoinErrors(errs, err)
+			continue
+		}
+
+		if matched {
+			return true, nil
+		}
+	}
+	return false, errs
+}
@@ -1,10 +1,17 @@
 package chk_test
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/ghifari160/medhash-tools/cmd"
 	"github.com/ghifari160/medhash-tools/cmd/chk"
+	"github.com/ghifari160/medhash-tools/cmd/gen"
 	"github.com/ghifari160/medhash-tools/medhash"
 	"github.com/ghifari160/medhash-tools/testcommon"
 	"github.com/stretchr/testify/require"
@@ -27,6 +34,8 @@ func TestChk(t *testing.T) {
 		testcommon.Case("default/invalid", "default", withInvalidate(true)),
 		testcommon.Case("default/file_list/skip", "default", withFiles([]string{"payload2"})),
 		testcommon.Case("default/file_list/include", "default", withFiles([]string{"payload"})),
+		testcommon.Case("default/root/flag", "default", withExternal(true), withRoot(true)),
+		testcommon.Case("default/root/recorded", "default", withExternal(true)),
 	}
 
 	testcommon.RunCases(t, testChk, cases)
@@ -42,6 +51,8 @@ func testChk(t *testing.T, alg string, opts ...testcommon.Options) {
 	options := testcommon.MergeOptions(opts...)
 	invalidate := options.Bool("invalidate")
 	files := options.StrSlice("files")
+	external := options.Bool("external")
+	root := options.Bool("root")
 
 	var shouldError bool
 
@@ -92,7 +103,15 @@ func testChk(t *testing.T, alg string, opts ...testcommon.Options) {
 	}
 	conf.Dir = dir
 	conf.Manifest = medhash.DefaultManifestName
-	arguments = append(arguments, dir)
+	if external {
+		conf.Manifest = filepath.Join(t.TempDir(), "external.json")
+		arguments = append(arguments, "--manifest", conf.Manifest)
+		if root {
+			arguments = append(arguments, "--root", dir)
+		}
+	} else {
+		arguments = append(arguments, dir)
+	}
 
 	if invalidate {
 		payload.Hash.XXH3 = "__INVALID__"
@@ -111,7 +130,9 @@ func testChk(t *testing.T, alg string, opts ...testcommon.Options) {
 	if !invalidate {
 		require.NoError(err)
 	} else {
-		require.Error(err)
+		var exitErr cli.ExitCoder
+		require.ErrorAs(err, &exitErr)
+		require.Equal(cmd.ExitMismatch, exitErr.ExitCode())
 	}
 }
 
@@ -124,3 +145,396 @@ func withInvalidate(invalidate bool) testcommon.Options {
 func withFiles(files []string) testcommon.Options {
 	return testcommon.NewOptions("files", files)
 }
+
+// withExternal stores the Manifest outside the media directory for testing.
+func withExternal(external bool) testcommon.Options {
+	return testcommon.NewOptions("external", external)
+}
+
+// withRoot specifies the Root argument to a command for testing.
+func withRoot(root bool) testcommon.Options {
+	return testcommon.NewOptions("root", root)
+}
+
+func TestChkPerDir(t *testing.T) {
+	t.Parallel()
+
+	t.Run("valid", func(t *testing.T) {
+		testChkPerDir(t, false)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		testChkPerDir(t, true)
+	})
+}
+
+func testChkPerDir(t *testing.T, invalidate bool) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	require.NoError(os.Mkdir(sub, 0755))
+	testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+	testcommon.GenPayload(t, sub, testcommon.PayloadSize())
+
+	conf := medhash.DefaultConfig
+	conf.Dir = dir
+	require.NoError(gen.GenPerDirFunc(conf, []string{medhash.DefaultManifestName}))
+
+	if invalidate {
+		require.NoError(os.WriteFile(filepath.Join(sub, "payload"), []byte("__INVALID__"), 0644))
+	}
+
+	command := chk.CommandChk()
+	command.ExitErrHandler = func(ctx context.Context, c *cli.Command, err error) {}
+
+	err := command.Run(t.Context(), []string{"chk", "--default", dir})
+	if invalidate {
+		require.Error(err)
+	} else {
+		require.NoError(err)
+	}
+}
+
+// TestChkPerDirTampered is not run in parallel, as it captures the status messages.
+func TestChkPerDirTampered(t *testing.T) {
+	require := require.New(t)
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	require.NoError(os.Mkdir(sub, 0755))
+	payload := testcommon.GenPayload(t, sub, testcommon.PayloadSize())
+
+	conf := medhash.DefaultConfig
+	conf.Dir = dir
+	require.NoError(gen.GenPerDirFunc(conf, []string{medhash.DefaultManifestName}))
+
+	childPath := filepath.Join(sub, medhash.DefaultManifestName)
+	f, err := os.OpenFile(childPath, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(err)
+	_, err = f.WriteString("\n")
+	require.NoError(err)
+	require.NoError(f.Close())
+
+	var buf bytes.Buffer
+	cmd.SetLog(&buf, slog.LevelInfo, cmd.LogFormatText)
+	t.Cleanup(func() {
+		cmd.SetLog(os.Stderr, slog.LevelInfo, cmd.LogFormatText)
+	})
+
+	command := chk.CommandChk()
+	command.ExitErrHandler = func(ctx context.Context, c *cli.Command, err error) {}
+
+	err = command.Run(t.Context(), []string{"chk", "--default", dir})
+	require.Error(err)
+
+	// The media of the tampered child Manifest are not verified.
+	require.Contains(buf.String(), childPath+": MISMATCH")
+	require.NotContains(buf.String(), filepath.Join(sub, payload.Path))
+}
+
+func TestChkStrict(t *testing.T) {
+	t.Parallel()
+
+	t.Run("listed", func(t *testing.T) {
+		testChkStrict(t, false, true)
+	})
+
+	t.Run("extra", func(t *testing.T) {
+		testChkStrict(t, true, true)
+	})
+
+	t.Run("extra/not_strict", func(t *testing.T) {
+		testChkStrict(t, true, false)
+	})
+}
+
+func testChkStrict(t *testing.T, extra, strict bool) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	require.NoError(os.Mkdir(sub, 0755))
+	testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+	testcommon.GenPayload(t, sub, testcommon.PayloadSize())
+
+	conf := medhash.DefaultConfig
+	conf.Dir = dir
+	require.NoError(gen.GenPerDirFunc(conf, []string{medhash.DefaultManifestName}))
+
+	if extra {
+		require.NoError(os.WriteFile(filepath.Join(sub, "extra"), []byte("extra"), 0644))
+	}
+
+	command := chk.CommandChk()
+	command.ExitErrHandler = func(ctx context.Context, c *cli.Command, err error) {}
+
+	arguments := []string{"chk", "--default"}
+	if strict {
+		arguments = append(arguments, "--strict")
+	}
+	err := command.Run(t.Context(), append(arguments, dir))
+	if extra && strict {
+		var exitErr cli.ExitCoder
+		require.ErrorAs(err, &exitErr)
+		require.Equal(cmd.ExitExtra, exitErr.ExitCode())
+	} else {
+		require.NoError(err)
+	}
+}
+
+func TestChkRootDirs(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	command := chk.CommandChk()
+	command.ExitErrHandler = func(ctx context.Context, c *cli.Command, err error) {}
+	err := command.Run(t.Context(),
+		[]string{"chk", "--default", "--root", t.TempDir(), t.TempDir(), t.TempDir()})
+
+	var exitErr cli.ExitCoder
+	require.ErrorAs(err, &exitErr)
+	require.Equal(cmd.ExitUsage, exitErr.ExitCode())
+}
+
+// TestChkExtra is not run in parallel, as it captures the status messages.
+func TestChkExtra(t *testing.T) {
+	require := require.New(t)
+	dir := t.TempDir()
+	testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+
+	conf := medhash.DefaultConfig
+	conf.Dir = dir
+	require.NoError(gen.GenFunc(conf, []string{medhash.DefaultManifestName}))
+	require.NoError(os.WriteFile(filepath.Join(dir, "extra"), []byte("extra"), 0644))
+	require.NoError(os.WriteFile(filepath.Join(dir, cmd.AuditLogName), []byte("{}\n"), 0644))
+
+	var buf bytes.Buffer
+	cmd.SetLog(&buf, slog.LevelInfo, cmd.LogFormatText)
+	t.Cleanup(func() {
+		cmd.SetLog(os.Stderr, slog.LevelInfo, cmd.LogFormatText)
+	})
+
+	command := chk.CommandChk()
+	command.ExitErrHandler = func(ctx context.Context, c *cli.Command, err error) {}
+
+	// Unlisted files are reported without failing, unless --strict is specified.
+	require.NoError(command.Run(t.Context(), []string{"chk", "--default", dir}))
+	require.Contains(buf.String(), filepath.Join(dir, "extra")+": EXTRA")
+	require.NotContains(buf.String(), cmd.AuditLogName)
+}
+
+func TestChkPerDirVersion(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	require.NoError(os.Mkdir(sub, 0755))
+	testcommon.GenPayload(t, sub, testcommon.PayloadSize())
+
+	conf := medhash.DefaultConfig
+	conf.Dir = dir
+	require.NoError(gen.GenPerDirFunc(conf, []string{medhash.DefaultManifestName}))
+
+	// Child Manifests are not part of spec v0.6.0.
+	manPath := filepath.Join(dir, medhash.DefaultManifestName)
+	manifest, err := cmd.ReadManifest(manPath)
+	require.NoError(err)
+	manifest.Version = "0.6.0"
+	require.NoError(cmd.WriteManifest(manifest, manPath))
+
+	command := chk.CommandChk()
+	command.ExitErrHandler = func(ctx context.Context, c *cli.Command, err error) {}
+
+	err = command.Run(t.Context(), []string{"chk", "--default", dir})
+	require.Error(err)
+
+	var exitErr cli.ExitCoder
+	require.ErrorAs(err, &exitErr)
+	require.Equal(cmd.ExitManifest, exitErr.ExitCode())
+}
+
+func TestChkCopy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("valid", func(t *testing.T) {
+		testChkCopy(t, false)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		testChkCopy(t, true)
+	})
+}
+
+func testChkCopy(t *testing.T, invalidate bool) {
+	t.Parallel()
+
+	require := require.New(t)
+	src := t.TempDir()
+	sub := filepath.Join(src, "sub")
+	require.NoError(os.Mkdir(sub, 0755))
+	testcommon.GenPayload(t, src, testcommon.PayloadSize())
+	payload := testcommon.GenPayload(t, sub, testcommon.PayloadSize())
+
+	conf := medhash.DefaultConfig
+	conf.Dir = src
+	require.NoError(gen.GenPerDirFunc(conf, []string{medhash.DefaultManifestName}))
+
+	// The copy does not contain any Manifest.
+	dst := filepath.Join(t.TempDir(), "copy")
+	require.NoError(os.CopyFS(dst, os.DirFS(src)))
+	require.NoError(os.Remove(filepath.Join(dst, medhash.DefaultManifestName)))
+	require.NoError(os.Remove(filepath.Join(dst, "sub", medhash.DefaultManifestName)))
+
+	if invalidate {
+		require.NoError(os.WriteFile(filepath.Join(dst, "sub", payload.Path), []byte("__INVALID__"), 0644))
+	}
+
+	command := chk.CommandChk()
+	command.ExitErrHandler = func(ctx context.Context, c *cli.Command, err error) {}
+
+	err := command.Run(t.Context(), []string{"chk", "--default", "--manifest",
+		filepath.Join(src, medhash.DefaultManifestName), dst})
+	if invalidate {
+		require.Error(err)
+	} else {
+		require.NoError(err)
+	}
+}
+
+func TestChkSample(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+
+	names := make([]string, 0, 20)
+	for i := range 20 {
+		name := fmt.Sprintf("media-%02d", i)
+		require.NoError(os.WriteFile(filepath.Join(dir, name), []byte(name), 0644))
+		names = append(names, name)
+	}
+
+	conf := medhash.DefaultConfig
+	conf.Dir = dir
+	require.NoError(gen.GenFunc(conf, []string{medhash.DefaultManifestName}))
+
+	sample, err := cmd.NewSample("50%", "2024-01-01")
+	require.NoError(err)
+
+	var selected, skipped []string
+	for _, name := range names {
+		if sample.Selected(name) {
+			selected = append(selected, name)
+		} else {
+			skipped = append(skipped, name)
+		}
+	}
+	require.NotEmpty(selected)
+	require.NotEmpty(skipped)
+
+	run := func(args ...string) error {
+		command := chk.CommandChk()
+		command.ExitErrHandler = func(ctx context.Context, c *cli.Command, err error) {}
+		return command.Run(t.Context(), append(append([]string{"chk", "--default"}, args...), dir))
+	}
+
+	// Media outside the sample are not verified.
+	for _, name := range skipped {
+		require.NoError(os.WriteFile(filepath.Join(dir, name), []byte("__INVALID__"), 0644))
+	}
+	require.NoError(run("--sample", "50%", "--seed", "2024-01-01", "--max-rate", "1MB/s"))
+	// The sample of the next day selects the other half.
+	require.Error(run("--sample", "50%", "--seed", "2024-01-02"))
+	require.Error(run())
+
+	require.Error(run("--sample", "0%"))
+	require.Error(run("--seed", "seed"))
+	require.Error(run("--max-rate", "fast"))
+}
+
+func TestChkArchive(t *testing.T) {
+	t.Parallel()
+
+	cases := []testcommon.TestCase{
+		testcommon.Case("tar", "tar"),
+		testcommon.Case("tar.gz", "tar.gz"),
+		testcommon.Case("zip", "zip"),
+		testcommon.Case("tar/invalid", "tar", withInvalidate(true)),
+		testcommon.Case("tar/missing", "tar", withMissing(true)),
+		testcommon.Case("tar/extra", "tar", withExtra(true)),
+		testcommon.Case("tar/extra/strict", "tar", withExtra(true), withStrict(true)),
+	}
+
+	testcommon.RunCases(t, testChkArchive, cases)
+}
+
+func testChkArchive(t *testing.T, format string, opts ...testcommon.Options) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+
+	options := testcommon.MergeOptions(opts...)
+	invalidate := options.Bool("invalidate")
+	missing := options.Bool("missing")
+	extra := options.Bool("extra")
+	strict := options.Bool("strict")
+
+	if extra {
+		require.NoError(os.WriteFile(filepath.Join(dir, "extra"), []byte("extra"), 0644))
+	}
+
+	out := t.TempDir()
+	archivePath := filepath.Join(out, "delivery."+format)
+	testcommon.CreateArchive(t, archivePath, format, dir)
+
+	conf := medhash.DefaultConfig
+	conf.Dir = out
+	conf.Manifest = medhash.DefaultManifestName
+	if invalidate {
+		payload.Hash.XXH3 = "__INVALID__"
+	}
+	testcommon.CreateManifest(t, conf, payload, medhash.ManifestFormatVer)
+
+	if missing {
+		conf.Manifest = "missing.json"
+		payload.Path = "missing"
+		testcommon.CreateManifest(t, conf, payload, medhash.ManifestFormatVer)
+	}
+
+	command := chk.CommandChk()
+	command.ExitErrHandler = func(ctx context.Context, c *cli.Command, err error) {}
+
+	arguments := []string{"chk", "--default", "--manifest", conf.ManifestPath(),
+		"--archive", archivePath}
+	if strict {
+		arguments = append(arguments, "--strict")
+	}
+
+	err := command.Run(t.Context(), arguments)
+	if invalidate || missing || strict {
+		require.Error(err)
+	} else {
+		require.NoError(err)
+	}
+}
+
+// withMissing lists media absent from the archive in the Manifest for testing.
+func withMissing(missing bool) testcommon.Options {
+	return testcommon.NewOptions("missing", missing)
+}
+
+// withExtra adds a member absent from the Manifest to the archive for testing.
+func withExtra(extra bool) testcommon.Options {
+	return testcommon.NewOptions("extra", extra)
+}
+
+// withStrict fails files not listed in the Manifest for testing.
+func withStrict(strict bool) testcommon.Options {
+	return testcommon.NewOptions("strict", strict)
+}
@@ -2,17 +2,31 @@ package cmd
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/ghifari160/medhash-tools/color"
+	"github.com/ghifari160/medhash-tools/medhash"
 	"github.com/urfave/cli/v3"
 )
 
+// Name and Version identify MedHash Tools.
+const (
+	Name    = "MedHash Tools"
+	Version = "0.7.0"
+)
+
 func init() {
 	RegisterCmd(&cli.Command{
 		Name:  "version",
 		Usage: "print tool version",
 		Action: func(ctx context.Context, c *cli.Command) error {
-			return nil
+			_, err := fmt.Fprintf(c.Root().Writer, "%s v%s\n", Name, Version)
+			return err
 		},
 	})
 }
@@ -20,10 +34,22 @@ func init() {
 var commands []*cli.Command
 
 // RegisterCmd registers a command.
+// Invalid arguments and flags of cmd and its subcommands exit with ExitUsage.
 func RegisterCmd(cmd *cli.Command) {
+	onUsageError(cmd)
 	commands = append(commands, cmd)
 }
 
+// onUsageError sets OnUsageError as the cli.OnUsageErrorFunc of cmd and its subcommands.
+func onUsageError(cmd *cli.Command) {
+	if cmd.OnUsageError == nil {
+		cmd.OnUsageError = OnUsageError
+	}
+	for _, sub := range cmd.Commands {
+		onUsageError(sub)
+	}
+}
+
 // Commands returns all registered commands.
 // Note that each command package may need to be anonymously imported.
 func Commands() []*cli.Command {
@@ -39,7 +65,101 @@ func HashAlgs() []cli.Flag {
 		simpleBoolFlag("sha256", "use SHA256"),
 		simpleBoolFlag("sha1", "use SHA1"),
 		simpleBoolFlag("md5", "use MD5"),
+		simpleBoolFlag("crc32", "use CRC32"),
+	}
+}
+
+// AlgsSet reports whether --all or any hash algorithm flag is set in command.
+func AlgsSet(command *cli.Command) bool {
+	if command.Bool("all") {
+		return true
+	}
+	for _, flag := range HashAlgs() {
+		if command.Bool(flag.Names()[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// HashConfig returns the medhash.Config for the preset or hash algorithm flags set in command.
+// If neither --all nor any hash algorithm flag is set, medhash.DefaultConfig is returned.
+func HashConfig(command *cli.Command) medhash.Config {
+	var config medhash.Config
+
+	if command.Bool("all") {
+		return medhash.AllConfig
+	}
+
+	config.XXH3 = command.Bool("xxh3")
+	config.SHA512 = command.Bool("sha512")
+	config.SHA3 = command.Bool("sha3")
+	config.SHA256 = command.Bool("sha256")
+	config.SHA1 = command.Bool("sha1")
+	config.MD5 = command.Bool("md5")
+	config.CRC32 = command.Bool("crc32")
+
+	if len(config.Algorithms()) < 1 {
+		return medhash.DefaultConfig
 	}
+	return config
+}
+
+// Generator returns the Manifest generator of MedHash Tools.
+// If source is not empty, it describes where the hashes of the Manifest came from
+// (e.g. "imported from delivery.sfv").
+func Generator(source string) string {
+	if source == "" {
+		return Name + " v" + Version
+	}
+	return Name + " v" + Version + " (" + source + ")"
+}
+
+// AuditLogName is the name of the audit log, stored next to the audited Manifest.
+const AuditLogName = "medhash-audit.jsonl"
+
+// IgnoreManifest returns ignores with manifest appended, unless ignores already contains manifest.
+// This prevents the manifest from listing itself.
+func IgnoreManifest(ignores []string, manifest string) []string {
+	if slices.Contains(ignores, manifest) {
+		return ignores
+	}
+	return append(ignores, manifest)
+}
+
+// Ignored reports whether rel matches any of the ignore patterns.
+func Ignored(rel string, ignores []string) (bool, error) {
+	var errs error
+	for _, ignore := range ignores {
+		matched, err := filepath.Match(ignore, rel)
+		if err != nil {
+			errs = JoinErrors(errs, err)
+		}
+
+		if matched {
+			return true, errs
+		}
+	}
+	return false, errs
+}
+
+// RelToDir returns path relative to dir.
+// inside reports whether path is located inside dir.
+func RelToDir(dir, path string) (rel string, inside bool) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	rel, err = filepath.Rel(absDir, absPath)
+	if err != nil {
+		return
+	}
+	inside = rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+	return
 }
 
 // simpleBoolFlag returns a new cli.BoolFlag with just the Name and Usage set.
@@ -50,10 +170,64 @@ func simpleBoolFlag(name, usage string) *cli.BoolFlag {
 	}
 }
 
-const (
-	MsgStatusError   = color.Red + "ERROR" + color.Reset
-	MsgStatusOK      = color.Green + "OK" + color.Reset
-	MsgStatusSkipped = color.Yellow + "SKIPPED" + color.Reset
-	MsgFinalError    = color.Red + "Error!" + color.Reset
-	MsgFinalDone     = color.Green + "Done!" + color.Reset
+// MsgStatus returns the status label for the result of checking a media.
+func MsgStatus(err error) string {
+	if err == nil {
+		return MsgStatusOK
+	} else if errors.Is(err, medhash.ErrMismatch) {
+		return MsgStatusMismatch
+	} else if errors.Is(err, fs.ErrNotExist) {
+		return MsgStatusMissing
+	} else {
+		return MsgStatusError
+	}
+}
+
+// MsgStatusOKOrError returns MsgStatusOK if err is nil, and MsgStatusError otherwise.
+func MsgStatusOKOrError(err error) string {
+	if err != nil {
+		return MsgStatusError
+	}
+	return MsgStatusOK
+}
+
+// Status labels, formatted with the current color.Theme.
+// The labels are formatted again when the theme is set with SetTheme.
+var (
+	MsgStatusError    string
+	MsgStatusMismatch string
+	MsgStatusMissing  string
+	MsgStatusExtra    string
+	MsgStatusAdded    string
+	MsgStatusRemoved  string
+	MsgStatusChanged  string
+	MsgStatusMoved    string
+	MsgStatusNew      string
+	MsgStatusOK       string
+	MsgStatusSkipped  string
+	MsgFinalError     string
+	MsgFinalDone      string
 )
+
+func init() {
+	SetTheme(color.ThemeDefault)
+}
+
+// SetTheme sets the current color.Theme, and formats the status labels with it.
+func SetTheme(theme color.Theme) {
+	color.SetTheme(theme)
+
+	MsgStatusError = theme.Label(color.KindFailure, "ERROR")
+	MsgStatusMismatch = theme.Label(color.KindFailure, "MISMATCH")
+	MsgStatusMissing = theme.Label(color.KindFailure, "MISSING")
+	MsgStatusExtra = theme.Label(color.KindWarning, "EXTRA")
+	MsgStatusAdded = theme.Label(color.KindWarning, "ADDED")
+	MsgStatusRemoved = theme.Label(color.KindFailure, "REMOVED")
+	MsgStatusChanged = theme.Label(color.KindFailure, "CHANGED")
+	MsgStatusMoved = theme.Label(color.KindWarning, "MOVED")
+	MsgStatusNew = theme.Label(color.KindWarning, "NEW")
+	MsgStatusOK = theme.Label(color.KindSuccess, "OK")
+	MsgStatusSkipped = theme.Label(color.KindWarning, "SKIPPED")
+	MsgFinalError = theme.Label(color.KindFailure, "Error!")
+	MsgFinalDone = theme.Label(color.KindSuccess, "Done!")
+}
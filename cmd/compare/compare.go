@@ -0,0 +1,206 @@
+package compare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/ghifari160/medhash-tools/cmd"
+	"github.com/ghifari160/medhash-tools/cmd/diff"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/urfave/cli/v3"
+)
+
+func init() {
+	cmd.RegisterCmd(CommandCompare())
+}
+
+func CommandCompare() *cli.Command {
+	return &cli.Command{
+		Name:      "compare",
+		Usage:     "hash two directories concurrently and compare them",
+		ArgsUsage: "<src> <dst>",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:    "ignore",
+				Aliases: []string{"i"},
+				Usage:   "ignore patterns",
+			},
+			&cli.StringFlag{
+				Name:    "manifest",
+				Aliases: []string{"m"},
+				Usage:   "manifest file name written in each directory",
+				Value:   medhash.DefaultManifestName,
+			},
+		},
+		MutuallyExclusiveFlags: []cli.MutuallyExclusiveFlags{
+			{
+				Flags: [][]cli.Flag{
+					{
+						&cli.BoolFlag{
+							Name:  "default",
+							Usage: "use default preset",
+							Value: true,
+						},
+					},
+					{
+						&cli.BoolFlag{
+							Name:  "all",
+							Usage: "use all algorithms",
+						},
+					},
+					cmd.HashAlgs(),
+				},
+			},
+		},
+		Action: CompareAction,
+	}
+}
+
+func CompareAction(ctx context.Context, command *cli.Command) error {
+	if command.Args().Len() != 2 {
+		return cli.Exit("compare requires exactly two directories", cmd.ExitUsage)
+	}
+	src, dst := command.Args().Get(0), command.Args().Get(1)
+
+	config := cmd.HashConfig(command)
+	config.Manifest = command.String("manifest")
+
+	cmd.Printf("Comparing %s to %s", src, dst)
+
+	d, errs := CompareFunc(config, src, dst, command.StringSlice("ignore"))
+	if d != nil {
+		diff.PrintDiff(command.Root().Writer, *d)
+	}
+
+	code := cmd.ExitCode(errs)
+	if d != nil {
+		code = cmd.SevereExitCode(code, diff.ExitCode(*d))
+	}
+	if code != cmd.ExitOK {
+		cmd.Failln(cmd.MsgFinalError)
+		if d != nil && !d.Empty() {
+			cmd.Failln("Directories differ")
+		}
+		if errs != nil {
+			for _, err := range cmd.UnwrapJoinedErrors(errs) {
+				cmd.Failln(err)
+			}
+		}
+		return cli.Exit("", code)
+	}
+
+	cmd.Println(cmd.MsgFinalDone)
+	return nil
+}
+
+// CompareFunc generates a Manifest of src and dst concurrently using the provided config, and
+// compares them.
+// Each Manifest is written to its directory as config.Manifest, and is not hashed itself.
+// An existing Manifest is never overwritten, so that a reference Manifest is never replaced by
+// the hashes of possibly damaged media.
+// The media of both directories are read in parallel, so that directories on different devices
+// are hashed at the speed of the slowest device.
+//
+// Media that cannot be read do not stop the comparison: they are left out of the Manifest of their
+// directory, and their errors are returned along with the diff.
+// The Manifest of a directory with unreadable media is not written.
+// The returned diff is nil if the directories cannot be compared at all.
+func CompareFunc(config medhash.Config, src, dst string, ignores []string) (*medhash.Diff, error) {
+	if config.Manifest == "" {
+		config.Manifest = medhash.DefaultManifestName
+	}
+	ignores = cmd.IgnoreManifest(ignores, config.Manifest)
+
+	type result struct {
+		manifest *medhash.Manifest
+		err      error
+	}
+	results := make([]chan result, 2)
+	for i, dir := range []string{src, dst} {
+		results[i] = make(chan result, 1)
+
+		go func(ch chan<- result) {
+			conf := config
+			conf.Dir = dir
+
+			manifest, err := generate(conf, ignores)
+			ch <- result{manifest: manifest, err: err}
+		}(results[i])
+	}
+
+	srcResult, dstResult := <-results[0], <-results[1]
+	errs := cmd.JoinErrors(srcResult.err, dstResult.err)
+	if srcResult.manifest == nil || dstResult.manifest == nil {
+		return nil, errs
+	}
+
+	d := medhash.Compare(srcResult.manifest, dstResult.manifest)
+	return &d, errs
+}
+
+// generate generates a Manifest of config.Dir, and writes it to config.ManifestPath.
+// Each media is printed as it is hashed.
+// Media that cannot be read are reported in the returned errors, and the Manifest is returned
+// without them, but is not written.
+// The returned Manifest is nil if config.Dir cannot be hashed at all, or if config.ManifestPath
+// already exists.
+func generate(config medhash.Config, ignores []string) (*medhash.Manifest, error) {
+	manPath := config.ManifestPath()
+	if _, err := os.Lstat(manPath); err == nil {
+		return nil, fmt.Errorf("%s already exists, use --manifest to write another manifest", manPath)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	manifest, err := medhash.NewWithConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs error
+	err = filepath.Walk(config.Dir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			err = fmt.Errorf("cannot access %s: %w", path, err)
+			cmd.StatusLabel(path, cmd.MsgStatusError, err)
+			errs = cmd.JoinErrors(errs, err)
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(config.Dir, path)
+		if err != nil {
+			errs = cmd.JoinErrors(errs, err)
+			return nil
+		}
+		if skip, err := cmd.Ignored(rel, ignores); err != nil {
+			cmd.StatusLabel(path, cmd.MsgStatusError, err)
+			errs = cmd.JoinErrors(errs, err)
+			return nil
+		} else if skip {
+			return nil
+		}
+
+		// Both directories are walked concurrently, so each status is logged in a single call.
+		if err := manifest.Add(rel); err != nil {
+			cmd.StatusLabel(path, cmd.MsgStatusError, err)
+			errs = cmd.JoinErrors(errs, err)
+		} else {
+			cmd.StatusLabel(path, cmd.MsgStatusOK, nil)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, cmd.JoinErrors(errs, err)
+	}
+	if errs != nil {
+		return manifest, errs
+	}
+
+	return manifest, cmd.WriteManifest(manifest, manPath)
+}
@@ -0,0 +1,99 @@
+package compare_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/cmd/compare"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/ghifari160/medhash-tools/testcommon"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	src := t.TempDir()
+	dst := t.TempDir()
+	payload := testcommon.GenPayload(t, src, testcommon.PayloadSize())
+
+	data, err := os.ReadFile(filepath.Join(src, payload.Path))
+	require.NoError(err)
+	require.NoError(os.WriteFile(filepath.Join(dst, payload.Path), data, 0644))
+
+	conf := medhash.DefaultConfig
+	d, err := compare.CompareFunc(conf, src, dst, nil)
+	require.NoError(err)
+	require.True(d.Empty())
+
+	for _, dir := range []string{src, dst} {
+		conf.Dir = dir
+		conf.Manifest = medhash.DefaultManifestName
+		testcommon.VerifyManifest(t, conf, payload.Hash)
+	}
+
+	// Existing Manifests are never overwritten.
+	d, err = compare.CompareFunc(medhash.DefaultConfig, src, dst, nil)
+	require.Error(err)
+	require.Nil(d)
+	conf.Dir = src
+	testcommon.VerifyManifest(t, conf, payload.Hash)
+
+	require.NoError(os.Rename(filepath.Join(dst, payload.Path), filepath.Join(dst, "moved")))
+	require.NoError(os.WriteFile(filepath.Join(dst, "extra"), []byte("extra"), 0644))
+
+	conf = medhash.DefaultConfig
+	conf.Manifest = "compare.json"
+	d, err = compare.CompareFunc(conf, src, dst, nil)
+	require.NoError(err)
+	require.Equal([]medhash.Move{{
+		From: payload.Path,
+		To:   "moved",
+		Hash: medhash.Hash{XXH3: payload.Hash.XXH3},
+	}}, d.Moved)
+	require.Len(d.Added, 1)
+	require.Equal("extra", d.Added[0].Path)
+	require.Empty(d.Removed)
+
+	command := compare.CommandCompare()
+	command.ExitErrHandler = func(ctx context.Context, c *cli.Command, err error) {}
+	err = command.Run(t.Context(), []string{"compare", "--manifest", "other.json", src, dst})
+	require.Error(err)
+}
+
+func TestCompareUnreadable(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	src := t.TempDir()
+	dst := t.TempDir()
+	for _, dir := range []string{src, dst} {
+		require.NoError(os.WriteFile(filepath.Join(dir, "a"), []byte("a"), 0644))
+		require.NoError(os.WriteFile(filepath.Join(dir, "b"), []byte("b"), 0644))
+	}
+	require.NoError(os.WriteFile(filepath.Join(dst, "b"), []byte("rot"), 0644))
+
+	// Files matching an invalid pattern cannot be hashed, but do not stop the comparison.
+	d, err := compare.CompareFunc(medhash.DefaultConfig, src, dst, []string{"[", "a"})
+	require.ErrorIs(err, filepath.ErrBadPattern)
+	require.NotNil(d)
+
+	// The Manifest of a directory with unreadable media is not written.
+	_, err = os.Stat(filepath.Join(src, medhash.DefaultManifestName))
+	require.ErrorIs(err, os.ErrNotExist)
+
+	if os.Geteuid() == 0 {
+		t.Skip("unreadable files are readable by root")
+	}
+
+	require.NoError(os.Chmod(filepath.Join(dst, "a"), 0))
+	d, err = compare.CompareFunc(medhash.DefaultConfig, src, dst, nil)
+	require.Error(err)
+	require.NotNil(d)
+	require.Len(d.Changed, 1)
+	require.Equal("b", d.Changed[0].Path)
+}
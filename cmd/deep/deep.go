@@ -0,0 +1,235 @@
+package deep
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ghifari160/medhash-tools/cmd"
+	"github.com/ghifari160/medhash-tools/hashdeep"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/urfave/cli/v3"
+)
+
+func init() {
+	cmd.RegisterCmd(CommandHashdeep())
+}
+
+func CommandHashdeep() *cli.Command {
+	return &cli.Command{
+		Name:  "hashdeep",
+		Usage: "hashdeep compatible operations",
+		Commands: []*cli.Command{
+			CommandAudit(),
+		},
+	}
+}
+
+func CommandAudit() *cli.Command {
+	return &cli.Command{
+		Name:      "audit",
+		Usage:     "audit a directory against known hashes, as hashdeep does in audit mode",
+		ArgsUsage: "[dir]",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:     "known",
+				Aliases:  []string{"k"},
+				Usage:    "hashdeep files or MedHash Manifests of the known hashes",
+				Required: true,
+			},
+			&cli.StringSliceFlag{
+				Name:    "ignore",
+				Aliases: []string{"i"},
+				Usage:   "ignore patterns",
+			},
+		},
+		Action: AuditAction,
+	}
+}
+
+func AuditAction(ctx context.Context, command *cli.Command) error {
+	var dir string
+	switch command.Args().Len() {
+	case 0:
+		cwd, err := os.Getwd()
+		if err != nil {
+			return cli.Exit(fmt.Errorf("cannot get working directory: %w", err), cmd.ExitIO)
+		}
+		dir = cwd
+	case 1:
+		dir = command.Args().First()
+	default:
+		return cli.Exit("hashdeep audit accepts at most one target directory", cmd.ExitUsage)
+	}
+
+	knownPaths := command.StringSlice("known")
+	cmd.Printf("Auditing %s against %s", dir, strings.Join(knownPaths, ", "))
+
+	// The files that could be read are reported, along with the errors.
+	report, err := AuditFunc(dir, knownPaths, command.StringSlice("ignore"))
+
+	for _, result := range report.Results {
+		path := filepath.Join(dir, filepath.FromSlash(result.Path))
+		switch result.Status {
+		case hashdeep.StatusMatched:
+			cmd.StatusLabel(path, cmd.MsgStatusOK, nil)
+		case hashdeep.StatusMoved:
+			cmd.StatusLabel(path, cmd.MsgStatusMoved+" from "+result.Known, nil)
+		case hashdeep.StatusNew:
+			cmd.StatusLabel(path, cmd.MsgStatusNew, errors.New("not in the known hashes"))
+		case hashdeep.StatusMissing:
+			cmd.StatusLabel(path, cmd.MsgStatusMissing, fs.ErrNotExist)
+		}
+	}
+
+	cmd.Printf("          Files matched: %d", report.Counts[hashdeep.StatusMatched])
+	cmd.Printf("            Files moved: %d", report.Counts[hashdeep.StatusMoved])
+	cmd.Printf("        New files found: %d", report.Counts[hashdeep.StatusNew])
+	cmd.Printf("  Known files not found: %d", report.Counts[hashdeep.StatusMissing])
+
+	if err != nil {
+		return cmd.Result(err)
+	}
+	if !report.Passed() {
+		cmd.Failln(cmd.MsgFinalError)
+		cmd.Failln("Audit failed")
+		if report.Counts[hashdeep.StatusMissing] > 0 {
+			return cli.Exit("", cmd.ExitMissing)
+		}
+		return cli.Exit("", cmd.ExitExtra)
+	}
+
+	cmd.Println(cmd.MsgFinalDone)
+	return nil
+}
+
+// AuditFunc audits the files in dir against the known hashes read from knownPaths.
+// Files are hashed with the algorithms of the known hashes.
+// Known files inside dir, and files matching ignores, are not audited.
+// Absolute paths of known files are made relative to dir.
+// Files that cannot be read are reported in the returned errors, and are audited neither as new
+// nor as missing.
+func AuditFunc(dir string, knownPaths, ignores []string) (hashdeep.Report, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return hashdeep.Report{}, err
+	}
+
+	var known []hashdeep.Entry
+	var config medhash.Config
+	for _, knownPath := range knownPaths {
+		entries, err := readKnown(knownPath)
+		if err != nil {
+			return hashdeep.Report{}, fmt.Errorf("%s: %w", knownPath, err)
+		}
+
+		for _, entry := range entries {
+			if p := filepath.FromSlash(entry.Path); filepath.IsAbs(p) {
+				if rel, err := filepath.Rel(absDir, p); err == nil {
+					entry.Path = filepath.ToSlash(rel)
+				}
+			}
+			for _, alg := range medhash.Algorithms() {
+				if entry.Hash.Get(alg) != "" {
+					config.Toggle(alg, true)
+				}
+			}
+			known = append(known, entry)
+		}
+
+		if abs, err := filepath.Abs(knownPath); err == nil {
+			if rel, err := filepath.Rel(absDir, abs); err == nil {
+				ignores = append(ignores, rel)
+			}
+		}
+	}
+	if len(config.Algorithms()) < 1 {
+		return hashdeep.Report{}, fmt.Errorf("no known hashes")
+	}
+
+	var files []hashdeep.Entry
+	unreadable := make(map[string]bool)
+	var errs error
+	err = filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			errs = cmd.JoinErrors(errs, fmt.Errorf("cannot access %s: %w", path, err))
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			errs = cmd.JoinErrors(errs, err)
+			return nil
+		}
+		for _, ignore := range ignores {
+			if matched, _ := filepath.Match(ignore, rel); matched {
+				return nil
+			}
+		}
+
+		sum, err := hashFile(config, path)
+		if err != nil {
+			unreadable[filepath.ToSlash(rel)] = true
+			errs = cmd.JoinErrors(errs, fmt.Errorf("%s: %w", path, err))
+			return nil
+		}
+		files = append(files, hashdeep.Entry{
+			Media: medhash.Media{Path: filepath.ToSlash(rel), Hash: sum},
+			Size:  info.Size(),
+		})
+		return nil
+	})
+	if err != nil {
+		return hashdeep.Report{}, cmd.JoinErrors(errs, err)
+	}
+
+	known = slices.DeleteFunc(known, func(entry hashdeep.Entry) bool {
+		return unreadable[entry.Path]
+	})
+	return hashdeep.Audit(known, files), errs
+}
+
+// readKnown reads the known hashes from the hashdeep file or MedHash Manifest at knownPath.
+// The sizes of media listed by a MedHash Manifest are unknown.
+func readKnown(knownPath string) ([]hashdeep.Entry, error) {
+	f, err := os.Open(knownPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	if header, _ := br.Peek(len(hashdeep.Header)); string(header) == hashdeep.Header {
+		return hashdeep.Read(br)
+	}
+
+	medias, err := cmd.ReadMedia(knownPath)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]hashdeep.Entry, len(medias))
+	for i, med := range medias {
+		entries[i] = hashdeep.Entry{Media: med, Size: -1}
+	}
+	return entries, nil
+}
+
+// hashFile generates the hashes of the file at path as configured.
+func hashFile(config medhash.Config, path string) (medhash.Hash, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return medhash.Hash{}, err
+	}
+	defer f.Close()
+
+	return medhash.HashReader(config, f)
+}
@@ -0,0 +1,116 @@
+package deep_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/cmd/deep"
+	"github.com/ghifari160/medhash-tools/hashdeep"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/ghifari160/medhash-tools/testcommon"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	md5A    = "0cc175b9c0f1b6a831c399e269772661"
+	sha256A = "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"
+)
+
+func TestAudit(t *testing.T) {
+	t.Parallel()
+
+	cases := []testcommon.TestCase{
+		testcommon.Case("hashdeep", hashdeep.FormatName),
+		testcommon.Case("manifest", "manifest"),
+	}
+
+	testcommon.RunCases(t, testAudit, cases)
+}
+
+func testAudit(t *testing.T, format string, opts ...testcommon.Options) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+	require.NoError(os.WriteFile(filepath.Join(dir, "a"), []byte("a"), 0644))
+
+	known := []medhash.Media{
+		payload,
+		{Path: "b", Hash: medhash.Hash{MD5: md5A, SHA256: sha256A}},
+	}
+
+	var knownPath string
+	if format == hashdeep.FormatName {
+		knownPath = filepath.Join(dir, "known.txt")
+		f, err := os.Create(knownPath)
+		require.NoError(err)
+		entries := []hashdeep.Entry{
+			{Media: known[0], Size: testcommon.PayloadSize()},
+			{Media: known[1], Size: 1},
+		}
+		require.NoError(hashdeep.Write(f, []string{"md5", "sha256"}, entries))
+		require.NoError(f.Close())
+	} else {
+		conf := medhash.Config{SHA256: true, MD5: true, Dir: dir, Manifest: medhash.DefaultManifestName}
+		knownPath = conf.ManifestPath()
+		manifest, err := medhash.NewWithConfig(conf)
+		require.NoError(err)
+		for _, med := range known {
+			manifest.AddHash(med.Path, med.Hash)
+		}
+		testcommon.WriteManifest(t, manifest)
+	}
+
+	report, err := deep.AuditFunc(dir, []string{knownPath}, nil)
+	require.NoError(err)
+	require.Equal([]hashdeep.Result{
+		{Path: "a", Status: hashdeep.StatusMoved, Known: "b"},
+		{Path: payload.Path, Status: hashdeep.StatusMatched},
+	}, report.Results)
+	require.True(report.Passed())
+
+	require.NoError(os.Remove(filepath.Join(dir, payload.Path)))
+	require.NoError(os.WriteFile(filepath.Join(dir, "c"), []byte("c"), 0644))
+
+	report, err = deep.AuditFunc(dir, []string{knownPath}, nil)
+	require.NoError(err)
+	require.Equal([]hashdeep.Result{
+		{Path: "a", Status: hashdeep.StatusMoved, Known: "b"},
+		{Path: "c", Status: hashdeep.StatusNew},
+		{Path: payload.Path, Status: hashdeep.StatusMissing},
+	}, report.Results)
+	require.False(report.Passed())
+}
+
+func TestAuditUnreadable(t *testing.T) {
+	t.Parallel()
+
+	if os.Geteuid() == 0 {
+		t.Skip("unreadable files are readable by root")
+	}
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+	require.NoError(os.WriteFile(filepath.Join(dir, "a"), []byte("a"), 0644))
+	require.NoError(os.WriteFile(filepath.Join(dir, "b"), []byte("b"), 0644))
+
+	conf := medhash.Config{MD5: true, Dir: dir, Manifest: medhash.DefaultManifestName}
+	manifest, err := medhash.NewWithConfig(conf)
+	require.NoError(err)
+	manifest.AddHash(payload.Path, payload.Hash)
+	manifest.AddHash("a", medhash.Hash{MD5: md5A})
+	testcommon.WriteManifest(t, manifest)
+
+	// Unreadable files are neither missing nor new, and do not abort the audit.
+	require.NoError(os.Chmod(filepath.Join(dir, "a"), 0))
+	require.NoError(os.Chmod(filepath.Join(dir, "b"), 0))
+
+	report, err := deep.AuditFunc(dir, []string{conf.ManifestPath()}, nil)
+	require.Error(err)
+	require.Equal([]hashdeep.Result{
+		{Path: payload.Path, Status: hashdeep.StatusMatched},
+	}, report.Results)
+}
@@ -0,0 +1,136 @@
+package diff
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ghifari160/medhash-tools/cmd"
+	"github.com/ghifari160/medhash-tools/color"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/urfave/cli/v3"
+)
+
+func init() {
+	cmd.RegisterCmd(CommandDiff())
+}
+
+func CommandDiff() *cli.Command {
+	return &cli.Command{
+		Name:      "diff",
+		Usage:     "compare two manifests without reading the media",
+		ArgsUsage: "<a> <b>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "output format (text, json)",
+				Value: "text",
+			},
+		},
+		Action: DiffAction,
+	}
+}
+
+func DiffAction(ctx context.Context, command *cli.Command) error {
+	if command.Args().Len() != 2 {
+		return cli.Exit("diff requires exactly two manifests", cmd.ExitUsage)
+	}
+	aPath, bPath := command.Args().Get(0), command.Args().Get(1)
+
+	format := command.String("format")
+	switch format {
+	case "text":
+		cmd.Printf("Comparing %s to %s", aPath, bPath)
+	case "json":
+	default:
+		return cli.Exit(fmt.Sprintf("unknown format: %s", format), cmd.ExitUsage)
+	}
+
+	diff, err := DiffFunc(aPath, bPath)
+	if err != nil {
+		return cmd.Result(err)
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(command.Root().Writer)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(diff); err != nil {
+			return cli.Exit(err, cmd.ExitIO)
+		}
+	} else {
+		PrintDiff(command.Root().Writer, diff)
+	}
+
+	// As with diff(1), differences fail the command.
+	code := ExitCode(diff)
+	if code != cmd.ExitOK {
+		cmd.Failln(cmd.MsgFinalError)
+		cmd.Failln("Manifests differ")
+		return cli.Exit("", code)
+	}
+	if format == "text" {
+		cmd.Println(cmd.MsgFinalDone)
+	}
+	return nil
+}
+
+// ExitCode returns the exit code for the differences in diff.
+// Changed media exit as mismatches, removed media as missing, and added media as extra.
+// Moved media fail with cmd.ExitFailure.
+func ExitCode(diff medhash.Diff) int {
+	switch {
+	case len(diff.Changed) > 0:
+		return cmd.ExitMismatch
+	case len(diff.Removed) > 0:
+		return cmd.ExitMissing
+	case len(diff.Added) > 0:
+		return cmd.ExitExtra
+	case !diff.Empty():
+		return cmd.ExitFailure
+	default:
+		return cmd.ExitOK
+	}
+}
+
+// DiffFunc compares the media listed by the Manifests at aPath and bPath.
+// Media of child Manifests are compared with their path relative to the top-level Manifest.
+func DiffFunc(aPath, bPath string) (medhash.Diff, error) {
+	a, err := cmd.ReadMedia(aPath)
+	if err != nil {
+		return medhash.Diff{}, err
+	}
+	b, err := cmd.ReadMedia(bPath)
+	if err != nil {
+		return medhash.Diff{}, err
+	}
+
+	return medhash.Compare(&medhash.Manifest{Media: a}, &medhash.Manifest{Media: b}), nil
+}
+
+// PrintDiff prints the differences in diff to w, followed by a summary.
+func PrintDiff(w io.Writer, diff medhash.Diff) {
+	for _, med := range diff.Removed {
+		color.Fprintf(w, "  %s: %s\n", med.Path, cmd.MsgStatusRemoved)
+	}
+	for _, med := range diff.Added {
+		color.Fprintf(w, "  %s: %s\n", med.Path, cmd.MsgStatusAdded)
+	}
+	for _, change := range diff.Changed {
+		color.Fprintf(w, "  %s: %s (%s)\n", change.Path, cmd.MsgStatusChanged,
+			strings.Join(change.Algorithms, ", "))
+	}
+	for _, move := range diff.Moved {
+		color.Fprintf(w, "  %s: %s from %s\n", move.To, cmd.MsgStatusMoved, move.From)
+	}
+	for _, med := range diff.Uncompared {
+		color.Fprintf(w, "  %s: %s (no shared algorithm)\n", med.Path, cmd.MsgStatusSkipped)
+	}
+
+	color.Fprintf(w, "     Added: %d\n", len(diff.Added))
+	color.Fprintf(w, "   Removed: %d\n", len(diff.Removed))
+	color.Fprintf(w, "   Changed: %d\n", len(diff.Changed))
+	color.Fprintf(w, "     Moved: %d\n", len(diff.Moved))
+	color.Fprintf(w, "Uncompared: %d\n", len(diff.Uncompared))
+}
@@ -0,0 +1,71 @@
+package diff_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/cmd"
+	"github.com/ghifari160/medhash-tools/cmd/diff"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/ghifari160/medhash-tools/testcommon"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+
+	aPath := writeManifest(t, dir, "a.json", []medhash.Media{
+		{Path: "clip.mov", Hash: medhash.Hash{XXH3: "1"}},
+		{Path: "old/take.mov", Hash: medhash.Hash{XXH3: "2"}},
+	})
+	bPath := writeManifest(t, dir, "b.json", []medhash.Media{
+		{Path: "clip.mov", Hash: medhash.Hash{XXH3: "3"}},
+		{Path: "new/take.mov", Hash: medhash.Hash{XXH3: "2"}},
+	})
+
+	d, err := diff.DiffFunc(aPath, bPath)
+	require.NoError(err)
+	require.Len(d.Changed, 1)
+	require.Equal("clip.mov", d.Changed[0].Path)
+	require.Equal([]medhash.Move{{
+		From: "old/take.mov",
+		To:   "new/take.mov",
+		Hash: medhash.Hash{XXH3: "2"},
+	}}, d.Moved)
+
+	var out bytes.Buffer
+	command := diff.CommandDiff()
+	command.Writer = &out
+	command.ExitErrHandler = func(ctx context.Context, c *cli.Command, err error) {}
+	err = command.Run(t.Context(), []string{"diff", "--format", "json", aPath, bPath})
+	var exitErr cli.ExitCoder
+	require.ErrorAs(err, &exitErr)
+	require.Equal(cmd.ExitMismatch, exitErr.ExitCode())
+
+	var decoded medhash.Diff
+	require.NoError(json.Unmarshal(out.Bytes(), &decoded))
+	require.Equal(d, decoded)
+
+	command = diff.CommandDiff()
+	err = command.Run(t.Context(), []string{"diff", aPath, aPath})
+	require.NoError(err)
+}
+
+// writeManifest writes a Manifest listing medias to name in dir, and returns its path.
+func writeManifest(t testing.TB, dir, name string, medias []medhash.Media) string {
+	t.Helper()
+
+	manifest, err := medhash.NewWithConfig(medhash.Config{Dir: dir, Manifest: name})
+	require.NoError(t, err)
+	for _, med := range medias {
+		manifest.AddHash(med.Path, med.Hash)
+	}
+	testcommon.WriteManifest(t, manifest)
+	return manifest.Config.ManifestPath()
+}
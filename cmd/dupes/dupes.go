@@ -0,0 +1,426 @@
+package dupes
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/ghifari160/medhash-tools/cmd"
+	"github.com/ghifari160/medhash-tools/color"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/urfave/cli/v3"
+)
+
+func init() {
+	cmd.RegisterCmd(CommandDupes())
+}
+
+func CommandDupes() *cli.Command {
+	return &cli.Command{
+		Name:      "dupes",
+		Usage:     "find duplicate media across manifests and directories",
+		ArgsUsage: "[manifests or dirs...]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "manifest",
+				Aliases: []string{"m"},
+				Usage:   "manifest file name, skipped in directories",
+				Value:   medhash.DefaultManifestName,
+			},
+			&cli.StringSliceFlag{
+				Name:    "ignore",
+				Aliases: []string{"i"},
+				Usage:   "ignore patterns",
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "output format (text, json)",
+				Value: "text",
+			},
+		},
+		MutuallyExclusiveFlags: []cli.MutuallyExclusiveFlags{
+			{
+				Flags: [][]cli.Flag{
+					{
+						&cli.BoolFlag{
+							Name:  "default",
+							Usage: "use default preset",
+							Value: true,
+						},
+					},
+					{
+						&cli.BoolFlag{
+							Name:  "all",
+							Usage: "use all algorithms",
+						},
+					},
+					cmd.HashAlgs(),
+				},
+			},
+		},
+		Action: DupesAction,
+	}
+}
+
+func DupesAction(ctx context.Context, command *cli.Command) error {
+	config := cmd.HashConfig(command)
+	config.Manifest = command.String("manifest")
+
+	format := command.String("format")
+	if format != "text" && format != "json" {
+		return cli.Exit(fmt.Sprintf("unknown format: %s", format), cmd.ExitUsage)
+	}
+
+	sources := command.Args().Slice()
+	if len(sources) < 1 {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return cli.Exit(fmt.Errorf("cannot get working directory: %w", err), cmd.ExitIO)
+		}
+		sources = append(sources, cwd)
+	}
+
+	// The duplicates of the sources that could be read are reported, along with the errors.
+	report, err := DupesFunc(config, sources, command.StringSlice("ignore"))
+
+	if format == "json" {
+		enc := json.NewEncoder(command.Root().Writer)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return cli.Exit(err, cmd.ExitIO)
+		}
+		if err != nil {
+			return cmd.Result(err)
+		}
+		return nil
+	}
+
+	w := command.Root().Writer
+	for _, group := range report.Groups {
+		size := "unknown size"
+		if group.Size >= 0 {
+			size = cmd.FormatSize(group.Size)
+		}
+		color.Fprintf(w, "%s:%s (%d media, %s)\n", group.Algorithm, group.Digest, len(group.Media), size)
+		for _, p := range group.Media {
+			color.Fprintf(w, "  %s\n", p)
+		}
+	}
+	color.Fprintf(w, "Duplicate groups: %d\n", len(report.Groups))
+	color.Fprintf(w, "    Wasted space: %s\n", cmd.FormatSize(report.Wasted))
+	return cmd.Result(err)
+}
+
+// Strength lists the Hash field names of all supported algorithms, from the most to the least
+// collision resistant.
+// Media are compared by the strongest algorithm they share.
+var Strength = []string{"sha512", "sha3", "sha256", "sha1", "md5", "xxh3", "crc32"}
+
+// Group is a group of identical media.
+type Group struct {
+	// Algorithm is the strongest algorithm shared by every media of the group.
+	// If no algorithm is shared by every media, it is the strongest algorithm of the first media.
+	Algorithm string `json:"algorithm"`
+	Digest    string `json:"digest"`
+	// Size is the size of each media in bytes, or -1 if unknown.
+	Size int64 `json:"size"`
+	// Wasted is the space used by all but one media of the group, or 0 if Size is unknown.
+	Wasted int64 `json:"wasted"`
+	// Media lists the paths of the media, sorted.
+	Media []string `json:"media"`
+}
+
+// Report lists the groups of identical media, largest waste first.
+type Report struct {
+	Groups []Group `json:"groups"`
+	Wasted int64   `json:"wasted"`
+}
+
+// entry is a media of any source, with its path on the local file system.
+type entry struct {
+	path string
+	hash medhash.Hash
+	// size is negative if unknown.
+	size int64
+}
+
+// DupesFunc finds identical media across sources.
+// Each source is either a Manifest, whose media are read without being hashed, or a directory,
+// whose files are hashed as configured along with the algorithms of the Manifests.
+// Files of a directory are only hashed if their size matches that of another media, and the
+// Manifests of the directory, named config.Manifest or medhash.DefaultManifestName if empty, are
+// skipped.
+// Empty media are never reported.
+// Sources and files that cannot be read do not prevent the others from being compared: their
+// errors are returned along with the Report of the others.
+func DupesFunc(config medhash.Config, sources, ignores []string) (Report, error) {
+	var entries []entry
+	var files []entry
+	var errs error
+
+	manifestName := config.Manifest
+	if manifestName == "" {
+		manifestName = medhash.DefaultManifestName
+	}
+	for _, source := range sources {
+		info, err := os.Stat(source)
+		if err != nil {
+			errs = cmd.JoinErrors(errs, err)
+			continue
+		}
+
+		if info.IsDir() {
+			found, err := walk(source, manifestName, ignores)
+			errs = cmd.JoinErrors(errs, err)
+			files = append(files, found...)
+			continue
+		}
+
+		found, err := readManifest(source)
+		if err != nil {
+			errs = cmd.JoinErrors(errs, fmt.Errorf("%s: %w", source, err))
+			continue
+		}
+		for _, e := range found {
+			for _, alg := range medhash.Algorithms() {
+				if e.hash.Get(alg) != "" {
+					config.Toggle(alg, true)
+				}
+			}
+		}
+		entries = append(entries, found...)
+	}
+
+	sizes := make(map[int64]int)
+	unknown := false
+	for _, e := range slices.Concat(entries, files) {
+		if e.size < 0 {
+			unknown = true
+		}
+		sizes[e.size]++
+	}
+
+	for _, file := range files {
+		if file.size == 0 || (!unknown && sizes[file.size] < 2) {
+			continue
+		}
+
+		f, err := os.Open(file.path)
+		if err != nil {
+			errs = cmd.JoinErrors(errs, err)
+			continue
+		}
+		file.hash, err = medhash.HashReader(config, f)
+		f.Close()
+		if err != nil {
+			errs = cmd.JoinErrors(errs, fmt.Errorf("%s: %w", file.path, err))
+			continue
+		}
+		entries = append(entries, file)
+	}
+
+	return group(dedupe(entries)), errs
+}
+
+// walk returns the regular files in dir, except those named manifestName or matching ignores.
+func walk(dir, manifestName string, ignores []string) ([]entry, error) {
+	var files []entry
+	var errs error
+	err := filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			errs = cmd.JoinErrors(errs, fmt.Errorf("cannot access %s: %w", path, err))
+			return nil
+		}
+		if !info.Mode().IsRegular() || info.Name() == manifestName {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			errs = cmd.JoinErrors(errs, err)
+			return nil
+		}
+		for _, ignore := range ignores {
+			if matched, _ := filepath.Match(ignore, rel); matched {
+				return nil
+			}
+		}
+
+		files = append(files, entry{path: path, size: info.Size()})
+		return nil
+	})
+	return files, cmd.JoinErrors(errs, err)
+}
+
+// readManifest returns the media listed by the Manifest at manPath, resolved against its media
+// root.
+// The size of each media is read from the media root, and is unknown if the media is absent.
+func readManifest(manPath string) ([]entry, error) {
+	root, err := cmd.MediaRoot(manPath)
+	if err != nil {
+		return nil, err
+	}
+	medias, err := cmd.ReadMedia(manPath)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]entry, 0, len(medias))
+	for _, med := range medias {
+		e := entry{path: filepath.Join(root, filepath.FromSlash(med.Path)), hash: med.Hash, size: -1}
+		if info, err := os.Stat(e.path); err == nil {
+			e.size = info.Size()
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// dedupe merges the entries of the same path, so that a media listed by a Manifest and found in a
+// directory is not its own duplicate.
+// Empty entries are dropped.
+func dedupe(entries []entry) []entry {
+	byPath := make(map[string]int, len(entries))
+	deduped := make([]entry, 0, len(entries))
+	for _, e := range entries {
+		if e.size == 0 {
+			continue
+		}
+
+		key := e.path
+		if abs, err := filepath.Abs(e.path); err == nil {
+			key = abs
+		}
+
+		i, ok := byPath[key]
+		if !ok {
+			byPath[key] = len(deduped)
+			deduped = append(deduped, e)
+			continue
+		}
+		for _, alg := range medhash.Algorithms() {
+			if deduped[i].hash.Get(alg) == "" {
+				deduped[i].hash.Set(alg, e.hash.Get(alg))
+			}
+		}
+		if deduped[i].size < 0 {
+			deduped[i].size = e.size
+		}
+	}
+	return deduped
+}
+
+// group groups identical entries.
+// Entries are identical if their hashes of the strongest algorithm they share are equal, and their
+// sizes are equal when both are known.
+func group(entries []entry) Report {
+	parent := make([]int, len(entries))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	// Only entries sharing a digest can be identical.
+	buckets := make(map[string][]int)
+	for i, e := range entries {
+		for _, alg := range Strength {
+			if digest := e.hash.Get(alg); digest != "" {
+				buckets[alg+":"+digest] = append(buckets[alg+":"+digest], i)
+			}
+		}
+	}
+	for _, bucket := range buckets {
+		for n, i := range bucket {
+			for _, j := range bucket[:n] {
+				if identical(entries[i], entries[j]) {
+					parent[find(i)] = find(j)
+					break
+				}
+			}
+		}
+	}
+
+	members := make(map[int][]int)
+	for i := range entries {
+		members[find(i)] = append(members[find(i)], i)
+	}
+
+	report := Report{Groups: make([]Group, 0)}
+	for _, indices := range members {
+		if len(indices) < 2 {
+			continue
+		}
+
+		g := Group{Size: -1, Media: make([]string, 0, len(indices))}
+		for _, i := range indices {
+			g.Media = append(g.Media, entries[i].path)
+			if entries[i].size >= 0 {
+				g.Size = entries[i].size
+			}
+		}
+		slices.Sort(g.Media)
+
+		for _, alg := range Strength {
+			shared := true
+			for _, i := range indices {
+				if entries[i].hash.Get(alg) == "" {
+					shared = false
+					break
+				}
+			}
+			if shared {
+				g.Algorithm = alg
+				break
+			}
+		}
+		if g.Algorithm == "" {
+			for _, alg := range Strength {
+				if entries[indices[0]].hash.Get(alg) != "" {
+					g.Algorithm = alg
+					break
+				}
+			}
+		}
+		g.Digest = entries[indices[0]].hash.Get(g.Algorithm)
+
+		if g.Size > 0 {
+			g.Wasted = g.Size * int64(len(indices)-1)
+		}
+		report.Wasted += g.Wasted
+		report.Groups = append(report.Groups, g)
+	}
+
+	slices.SortFunc(report.Groups, func(a, b Group) int {
+		if c := cmp.Compare(b.Wasted, a.Wasted); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Media[0], b.Media[0])
+	})
+
+	return report
+}
+
+// identical reports whether a and b are identical.
+func identical(a, b entry) bool {
+	if a.size >= 0 && b.size >= 0 && a.size != b.size {
+		return false
+	}
+	for _, alg := range Strength {
+		da, db := a.hash.Get(alg), b.hash.Get(alg)
+		if da != "" && db != "" {
+			return da == db
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package dupes_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/cmd/dupes"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/ghifari160/medhash-tools/testcommon"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDupes(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+
+	data, err := os.ReadFile(filepath.Join(dir, payload.Path))
+	require.NoError(err)
+	require.NoError(os.WriteFile(filepath.Join(dir, "copy"), data, 0644))
+	require.NoError(os.WriteFile(filepath.Join(dir, "unique"), []byte("unique"), 0644))
+	require.NoError(os.WriteFile(filepath.Join(dir, "empty1"), nil, 0644))
+	require.NoError(os.WriteFile(filepath.Join(dir, "empty2"), nil, 0644))
+
+	report, err := dupes.DupesFunc(medhash.DefaultConfig, []string{dir}, nil)
+	require.NoError(err)
+	require.Equal([]dupes.Group{{
+		Algorithm: "xxh3",
+		Digest:    payload.Hash.XXH3,
+		Size:      testcommon.PayloadSize(),
+		Wasted:    testcommon.PayloadSize(),
+		Media:     []string{filepath.Join(dir, "copy"), filepath.Join(dir, payload.Path)},
+	}}, report.Groups)
+	require.Equal(testcommon.PayloadSize(), report.Wasted)
+}
+
+func TestDupesManifests(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+
+	// The media of a Manifest are compared by their strongest shared algorithm, without being read.
+	a, err := medhash.NewWithConfig(medhash.Config{Dir: dir, Manifest: "a.json"})
+	require.NoError(err)
+	a.AddHash("a/clip.mov", medhash.Hash{XXH3: "1", SHA256: "x"})
+	a.AddHash("a/other.mov", medhash.Hash{XXH3: "1", SHA256: "y"})
+	testcommon.WriteManifest(t, a)
+
+	b, err := medhash.NewWithConfig(medhash.Config{Dir: dir, Manifest: "b.json"})
+	require.NoError(err)
+	b.AddHash("b/clip.mov", medhash.Hash{SHA256: "x"})
+	testcommon.WriteManifest(t, b)
+
+	// Sources that cannot be read are reported without preventing the others from being compared.
+	sources := []string{a.Config.ManifestPath(), filepath.Join(dir, "absent.json"), b.Config.ManifestPath()}
+	report, err := dupes.DupesFunc(medhash.DefaultConfig, sources, nil)
+	require.ErrorIs(err, fs.ErrNotExist)
+	require.Equal([]dupes.Group{{
+		Algorithm: "sha256",
+		Digest:    "x",
+		Size:      -1,
+		Media:     []string{filepath.Join(dir, "a", "clip.mov"), filepath.Join(dir, "b", "clip.mov")},
+	}}, report.Groups)
+	require.Zero(report.Wasted)
+}
+
+func TestDupesManifestName(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	require.NoError(os.WriteFile(filepath.Join(dir, "a"), []byte("same"), 0644))
+	require.NoError(os.WriteFile(filepath.Join(dir, "custom.json"), []byte("same"), 0644))
+
+	report, err := dupes.DupesFunc(medhash.DefaultConfig, []string{dir}, nil)
+	require.NoError(err)
+	require.Len(report.Groups, 1)
+
+	// Manifests of the directory are skipped.
+	conf := medhash.DefaultConfig
+	conf.Manifest = "custom.json"
+	report, err = dupes.DupesFunc(conf, []string{dir}, nil)
+	require.NoError(err)
+	require.Empty(report.Groups)
+}
@@ -0,0 +1,447 @@
+package edit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghifari160/medhash-tools/cmd"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/urfave/cli/v3"
+)
+
+func init() {
+	cmd.RegisterCmd(CommandAdd())
+	cmd.RegisterCmd(CommandRm())
+	cmd.RegisterCmd(CommandLs())
+}
+
+// manifestFlag returns the flag selecting the Manifest to edit.
+func manifestFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:    "manifest",
+		Aliases: []string{"m"},
+		Usage:   "use this manifest",
+		Value:   medhash.DefaultManifestName,
+	}
+}
+
+func CommandAdd() *cli.Command {
+	return &cli.Command{
+		Name:      "add",
+		Usage:     "add files to an existing manifest",
+		ArgsUsage: "<files or dirs...>",
+		Flags: []cli.Flag{
+			manifestFlag(),
+		},
+		MutuallyExclusiveFlags: []cli.MutuallyExclusiveFlags{
+			{
+				Flags: [][]cli.Flag{
+					{
+						&cli.BoolFlag{
+							Name:  "all",
+							Usage: "use all algorithms",
+						},
+					},
+					cmd.HashAlgs(),
+				},
+			},
+		},
+		Action: AddAction,
+	}
+}
+
+func CommandRm() *cli.Command {
+	return &cli.Command{
+		Name:      "rm",
+		Usage:     "remove media from an existing manifest",
+		ArgsUsage: "<patterns...>",
+		Flags: []cli.Flag{
+			manifestFlag(),
+		},
+		Action: RmAction,
+	}
+}
+
+func CommandLs() *cli.Command {
+	return &cli.Command{
+		Name:      "ls",
+		Usage:     "list media of a manifest",
+		ArgsUsage: "[patterns...]",
+		Flags: []cli.Flag{
+			manifestFlag(),
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "output format (text, json)",
+				Value: "text",
+			},
+		},
+		MutuallyExclusiveFlags: []cli.MutuallyExclusiveFlags{
+			{
+				Flags: [][]cli.Flag{
+					{
+						&cli.BoolFlag{
+							Name:  "all",
+							Usage: "print all digests",
+						},
+					},
+					cmd.HashAlgs(),
+				},
+			},
+		},
+		Action: LsAction,
+	}
+}
+
+func AddAction(ctx context.Context, command *cli.Command) error {
+	if command.Args().Len() < 1 {
+		return cli.Exit("add requires at least one file", cmd.ExitUsage)
+	}
+
+	// Without algorithm flags, files are hashed with the algorithms already in the Manifest.
+	var config medhash.Config
+	if cmd.AlgsSet(command) {
+		config = cmd.HashConfig(command)
+	}
+
+	manPath := command.String("manifest")
+	cmd.Printf("Adding to MedHash %s", manPath)
+	return cmd.Result(AddFunc(manPath, config, command.Args().Slice()))
+}
+
+func RmAction(ctx context.Context, command *cli.Command) error {
+	if command.Args().Len() < 1 {
+		return cli.Exit("rm requires at least one pattern", cmd.ExitUsage)
+	}
+
+	manPath := command.String("manifest")
+	cmd.Printf("Removing from MedHash %s", manPath)
+
+	removed, err := RmFunc(manPath, command.Args().Slice())
+	for _, p := range removed {
+		cmd.StatusLabel(p, cmd.MsgStatusRemoved, nil)
+	}
+	return cmd.Result(err)
+}
+
+func LsAction(ctx context.Context, command *cli.Command) error {
+	format := command.String("format")
+	if format != "text" && format != "json" {
+		return cli.Exit(fmt.Sprintf("unknown format: %s", format), cmd.ExitUsage)
+	}
+
+	medias, err := LsFunc(command.String("manifest"), command.Args().Slice())
+	if err != nil {
+		return cmd.Result(err)
+	}
+
+	out := command.Root().Writer
+	if format == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(medias); err != nil {
+			return cli.Exit(err, cmd.ExitIO)
+		}
+		return nil
+	}
+
+	var algs []string
+	if cmd.AlgsSet(command) {
+		algs = cmd.HashConfig(command).Algorithms()
+	}
+	for _, med := range medias {
+		fields := []string{med.Path}
+		for _, alg := range algs {
+			digest := med.Hash.Get(alg)
+			if digest == "" {
+				digest = "-"
+			}
+			fields = append(fields, alg+":"+digest)
+		}
+		fmt.Fprintln(out, strings.Join(fields, "  "))
+	}
+	return nil
+}
+
+// AddFunc adds files to the Manifest at manPath, and rewrites it.
+// Directories are added recursively, except for the Manifest itself.
+// Files are hashed as configured, or with the algorithms of the media already listed if config has
+// no algorithm.
+// Files must be inside the media root, and not already listed.
+// Files that cannot be added do not prevent the others from being added.
+func AddFunc(manPath string, config medhash.Config, files []string) error {
+	manifest, root, err := load(manPath)
+	if err != nil {
+		return err
+	}
+
+	if len(config.Algorithms()) < 1 {
+		config = cmd.MediaConfig(manifest)
+	}
+	config.Dir = root
+	config.Manifest = manPath
+	manifest.Config = config
+
+	absMan, err := filepath.Abs(manPath)
+	if err != nil {
+		return err
+	}
+
+	var errs error
+	added := 0
+	for _, file := range files {
+		err := filepath.Walk(file, func(p string, info fs.FileInfo, err error) error {
+			if err != nil {
+				errs = cmd.JoinErrors(errs, fmt.Errorf("cannot access %s: %w", p, err))
+				return nil
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+
+			abs, err := filepath.Abs(p)
+			if err != nil {
+				errs = cmd.JoinErrors(errs, err)
+				return nil
+			}
+			if abs == absMan {
+				return nil
+			}
+
+			rel, err := filepath.Rel(root, abs)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				err := fmt.Errorf("%s: not in media root %s", p, root)
+				cmd.StatusLabel(p, cmd.MsgStatusError, err)
+				errs = cmd.JoinErrors(errs, err)
+				return nil
+			}
+			rel = filepath.ToSlash(rel)
+
+			if listed(manifest, rel) {
+				err := fmt.Errorf("%s: already in manifest", p)
+				cmd.StatusLabel(p, cmd.MsgStatusError, err)
+				errs = cmd.JoinErrors(errs, err)
+				return nil
+			}
+
+			if err := manifest.Add(rel); err != nil {
+				cmd.StatusLabel(p, cmd.MsgStatusError, err)
+				errs = cmd.JoinErrors(errs, err)
+				return nil
+			}
+			cmd.StatusLabel(p, cmd.MsgStatusOK, nil)
+			added++
+			return nil
+		})
+		errs = cmd.JoinErrors(errs, err)
+	}
+
+	if added > 0 {
+		errs = cmd.JoinErrors(errs, cmd.WriteManifest(manifest, manPath))
+	}
+	return errs
+}
+
+// RmFunc removes the media matching any of patterns from the Manifest at manPath, and rewrites it.
+// Media of child Manifests are matched with their path relative to the top-level Manifest, as
+// LsFunc lists them, and are removed from the child Manifest listing them.
+// The hashes of rewritten child Manifests are updated in their parent Manifest.
+// Patterns are matched against media paths with path.Match.
+// It is an error for a pattern not to match any media.
+// RmFunc returns the paths of the removed media.
+func RmFunc(manPath string, patterns []string) ([]string, error) {
+	tree, err := loadTree(manPath, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var errs error
+	var removed []string
+	for _, pattern := range patterns {
+		pattern = filepath.ToSlash(pattern)
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = cmd.JoinErrors(errs, fmt.Errorf("%s: %w", pattern, err))
+			continue
+		}
+
+		matches, err := tree.remove(pattern)
+		errs = cmd.JoinErrors(errs, err)
+		if len(matches) < 1 && err == nil {
+			errs = cmd.JoinErrors(errs, fmt.Errorf("%s: no matching media", pattern))
+			continue
+		}
+		removed = append(removed, matches...)
+	}
+
+	if len(removed) > 0 {
+		errs = cmd.JoinErrors(errs, tree.write())
+	}
+	return removed, errs
+}
+
+// tree is a Manifest loaded with its child Manifests, for editing.
+type tree struct {
+	path     string
+	manifest *medhash.Manifest
+	// prefix is the path of the directory of the Manifest, relative to the top-level Manifest.
+	prefix string
+	// children are the trees of manifest.Manifests, in the same order.
+	children []*tree
+	modified bool
+}
+
+// loadTree loads the Manifest at manPath and its child Manifests.
+func loadTree(manPath, prefix string) (*tree, error) {
+	manifest, _, err := load(manPath)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &tree{path: manPath, manifest: manifest, prefix: prefix}
+	dir := filepath.Dir(manPath)
+	for _, child := range manifest.Manifests {
+		childTree, err := loadTree(filepath.Join(dir, filepath.FromSlash(child.Path)),
+			path.Join(prefix, path.Dir(child.Path)))
+		if err != nil {
+			return nil, err
+		}
+		t.children = append(t.children, childTree)
+	}
+	return t, nil
+}
+
+// remove removes the media of t and its children matching pattern, and returns their paths
+// relative to the top-level Manifest.
+func (t *tree) remove(pattern string) ([]string, error) {
+	var errs error
+	var removed []string
+
+	var matches []string
+	for _, med := range t.manifest.Media {
+		if matched, _ := path.Match(pattern, path.Join(t.prefix, med.Path)); matched {
+			matches = append(matches, med.Path)
+		}
+	}
+	for _, p := range matches {
+		if err := t.manifest.Remove(p); err != nil {
+			errs = cmd.JoinErrors(errs, err)
+			continue
+		}
+		removed = append(removed, path.Join(t.prefix, p))
+		t.modified = true
+	}
+
+	for _, child := range t.children {
+		childRemoved, err := child.remove(pattern)
+		errs = cmd.JoinErrors(errs, err)
+		removed = append(removed, childRemoved...)
+	}
+	return removed, errs
+}
+
+// write rewrites the modified Manifests of t, children first, and updates the hashes of the
+// rewritten children in their parent.
+func (t *tree) write() error {
+	dir := filepath.Dir(t.path)
+	for i, child := range t.children {
+		if err := child.write(); err != nil {
+			return err
+		}
+		if !child.modified {
+			continue
+		}
+
+		entry := &t.manifest.Manifests[i]
+		hash, err := hashFile(filepath.Join(dir, filepath.FromSlash(entry.Path)), entry.Hash)
+		if err != nil {
+			return err
+		}
+		entry.Hash = hash
+		t.modified = true
+	}
+
+	if !t.modified {
+		return nil
+	}
+	return cmd.WriteManifest(t.manifest, t.path)
+}
+
+// hashFile hashes the file at p with the algorithms of hash.
+func hashFile(p string, hash medhash.Hash) (medhash.Hash, error) {
+	config := cmd.MediaConfig(&medhash.Manifest{Media: []medhash.Media{{Hash: hash}}})
+
+	f, err := os.Open(p)
+	if err != nil {
+		return medhash.Hash{}, err
+	}
+	defer f.Close()
+
+	return medhash.HashReader(config, f)
+}
+
+// LsFunc returns the media listed by the Manifest at manPath matching any of patterns, or all
+// media if patterns is empty.
+// Media of child Manifests are listed with their path relative to the top-level Manifest.
+// Patterns are matched against media paths with path.Match.
+func LsFunc(manPath string, patterns []string) ([]medhash.Media, error) {
+	medias, err := cmd.ReadMedia(manPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(patterns) < 1 {
+		return medias, nil
+	}
+
+	filtered := make([]medhash.Media, 0, len(medias))
+	for _, med := range medias {
+		for _, pattern := range patterns {
+			matched, err := path.Match(filepath.ToSlash(pattern), med.Path)
+			if err != nil {
+				return nil, err
+			}
+			if matched {
+				filtered = append(filtered, med)
+				break
+			}
+		}
+	}
+	return filtered, nil
+}
+
+// load reads the Manifest at manPath, and returns it with its media root.
+// Only Manifests of the current specification version can be edited, as editing older Manifests
+// would mix versions.
+func load(manPath string) (*medhash.Manifest, string, error) {
+	manifest, err := cmd.ReadManifest(manPath)
+	if err != nil {
+		return nil, "", err
+	}
+	if manifest.Version != medhash.ManifestFormatVer {
+		return nil, "", cmd.VersionError(manPath, manifest.Version)
+	}
+
+	root, err := cmd.MediaRoot(manPath)
+	if err != nil {
+		return nil, "", err
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return nil, "", err
+	}
+	return manifest, root, nil
+}
+
+// listed reports whether media is listed by manifest.
+func listed(manifest *medhash.Manifest, media string) bool {
+	for _, med := range manifest.Media {
+		if med.Path == media {
+			return true
+		}
+	}
+	return false
+}
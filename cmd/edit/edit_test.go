@@ -0,0 +1,118 @@
+package edit_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/cmd/edit"
+	"github.com/ghifari160/medhash-tools/cmd/gen"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/ghifari160/medhash-tools/testcommon"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+func TestAddRm(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+
+	conf := medhash.Config{Dir: dir, Manifest: medhash.DefaultManifestName, SHA256: true, MD5: true}
+	require.NoError(gen.GenFunc(conf, []string{medhash.DefaultManifestName}))
+	manPath := conf.ManifestPath()
+
+	sub := filepath.Join(dir, "sub")
+	require.NoError(os.Mkdir(sub, 0755))
+	require.NoError(os.WriteFile(filepath.Join(sub, "b"), []byte("b"), 0644))
+	require.NoError(os.WriteFile(filepath.Join(dir, "a"), []byte("a"), 0644))
+
+	// Files are hashed with the algorithms of the Manifest.
+	require.NoError(edit.AddFunc(manPath, medhash.Config{}, []string{sub, filepath.Join(dir, "a")}))
+
+	manifest := testcommon.ReadManifest(t, manPath)
+	require.Equal(medhash.ManifestFormatVer, manifest.Version)
+	require.Len(manifest.Media, 3)
+	require.Equal("a", manifest.Media[0].Path)
+	require.Equal(payload.Path, manifest.Media[1].Path)
+	require.Equal("sub/b", manifest.Media[2].Path)
+	require.Empty(manifest.Media[0].Hash.XXH3)
+	require.NotEmpty(manifest.Media[0].Hash.SHA256)
+	require.NotEmpty(manifest.Media[0].Hash.MD5)
+
+	require.Error(edit.AddFunc(manPath, medhash.Config{}, []string{filepath.Join(dir, "a")}))
+	outside := filepath.Join(t.TempDir(), "outside")
+	require.NoError(os.WriteFile(outside, []byte("outside"), 0644))
+	require.Error(edit.AddFunc(manPath, medhash.Config{}, []string{outside}))
+
+	removed, err := edit.RmFunc(manPath, []string{"sub/*", "a"})
+	require.NoError(err)
+	require.Equal([]string{"sub/b", "a"}, removed)
+
+	manifest = testcommon.ReadManifest(t, manPath)
+	require.Len(manifest.Media, 1)
+	require.Equal(payload.Path, manifest.Media[0].Path)
+
+	_, err = edit.RmFunc(manPath, []string{"nothing"})
+	require.Error(err)
+}
+
+func TestRmChild(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	require.NoError(os.Mkdir(sub, 0755))
+	require.NoError(os.WriteFile(filepath.Join(sub, "a"), []byte("a"), 0644))
+	require.NoError(os.WriteFile(filepath.Join(sub, "b"), []byte("b"), 0644))
+
+	conf := medhash.DefaultConfig
+	conf.Dir = dir
+	require.NoError(gen.GenPerDirFunc(conf, []string{medhash.DefaultManifestName}))
+	manPath := filepath.Join(dir, medhash.DefaultManifestName)
+
+	// Media of child Manifests are removed as they are listed.
+	removed, err := edit.RmFunc(manPath, []string{"sub/a"})
+	require.NoError(err)
+	require.Equal([]string{"sub/a"}, removed)
+
+	medias, err := edit.LsFunc(manPath, nil)
+	require.NoError(err)
+	require.Len(medias, 1)
+	require.Equal("sub/b", medias[0].Path)
+
+	// The hash of the rewritten child Manifest is updated in its parent.
+	manifest := testcommon.ReadManifest(t, manPath)
+	require.Len(manifest.Manifests, 1)
+	require.NoError(manifest.Manifests[0].Check(medhash.Config{Dir: dir, XXH3: true}))
+}
+
+func TestLs(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+
+	manifest, err := medhash.NewWithConfig(medhash.Config{Dir: dir})
+	require.NoError(err)
+	manifest.AddHash("clip.mov", medhash.Hash{XXH3: "1", SHA256: "a"})
+	manifest.AddHash("sub/take.mov", medhash.Hash{XXH3: "2"})
+	testcommon.WriteManifest(t, manifest)
+	manPath := manifest.Config.ManifestPath()
+
+	medias, err := edit.LsFunc(manPath, []string{"sub/*"})
+	require.NoError(err)
+	require.Equal([]medhash.Media{manifest.Media[1]}, medias)
+
+	var out bytes.Buffer
+	command := edit.CommandLs()
+	command.Writer = &out
+	command.ExitErrHandler = func(ctx context.Context, c *cli.Command, err error) {}
+	require.NoError(command.Run(t.Context(), []string{"ls", "-m", manPath, "--sha256", "--xxh3"}))
+	require.Equal("clip.mov  xxh3:1  sha256:a\nsub/take.mov  xxh3:2  sha256:-\n", out.String())
+}
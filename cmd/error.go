@@ -15,13 +15,11 @@ func JoinErrors(errs ...error) error {
 
 // UnwrapJoinedErrors calls errs.Unwrap if it returns []error (i.e. errs is the result of
 // errors.Join).
-// If errs.Unwrap returns an error, UnwrapJoinedErrors returns []error{errs.Unwrap()}.
 // Otherwise, UnwrapJoinedErrors returns []error{errs}.
+// Errors wrapping a single error are not unwrapped, as that would discard their context.
 func UnwrapJoinedErrors(errs error) []error {
 	if joinedErrs, ok := errs.(interface{ Unwrap() []error }); ok {
 		return joinedErrs.Unwrap()
-	} else if unwrapable, ok := errs.(interface{ Unwrap() error }); ok {
-		return []error{unwrapable.Unwrap()}
 	} else {
 		return []error{errs}
 	}
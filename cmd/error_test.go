@@ -85,6 +85,18 @@ func TestUnwrapJoinedErrors(t *testing.T) {
 	}
 }
 
+func TestUnwrapJoinedErrorsWrapped(t *testing.T) {
+	assert := assert.New(t)
+
+	// Errors wrapping a single error keep their context.
+	err := fmt.Errorf("media: %w", errors.New("mismatch"))
+	assert.Equal([]error{err}, cmd.UnwrapJoinedErrors(err))
+
+	unwrapped := cmd.UnwrapJoinedErrors(cmd.JoinErrors(err, errors.New("other")))
+	assert.Len(unwrapped, 2)
+	assert.Equal("media: mismatch", unwrapped[0].Error())
+}
+
 func testJoinErrors(t *testing.T, errs []error) {
 	assert := assert.New(t)
 
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/urfave/cli/v3"
+)
+
+// Exit codes.
+// Each class of failure exits with a distinct code, so that scripts can react to them differently.
+// Exit code 3 is not used, as the help command exits with it for unknown topics.
+const (
+	// ExitOK is the exit code of successful commands.
+	ExitOK = 0
+	// ExitFailure is the exit code of failures that do not belong to any other class.
+	ExitFailure = 1
+	// ExitUsage is the exit code of invalid arguments and flags.
+	ExitUsage = 2
+	// ExitMismatch is the exit code of media not matching their hashes.
+	ExitMismatch = 4
+	// ExitMissing is the exit code of missing media.
+	ExitMissing = 5
+	// ExitExtra is the exit code of files that are not listed where every file must be listed
+	// (e.g. in the payload of a bag).
+	ExitExtra = 6
+	// ExitManifest is the exit code of Manifests that cannot be read, parsed, or are of an
+	// unsupported version.
+	ExitManifest = 7
+	// ExitSignature is reserved for signature verification failures.
+	// Signatures are not supported yet.
+	ExitSignature = 8
+	// ExitIO is the exit code of I/O errors.
+	ExitIO = 9
+)
+
+// ErrManifest is matched by errors of missing or invalid Manifests.
+// Use errors.Is to test for it.
+var ErrManifest = errors.New("invalid manifest")
+
+// ErrExtra is the error wrapped by errors of files that are not listed where every file must be
+// listed.
+// Use errors.Is to test for it.
+var ErrExtra = errors.New("not listed in manifest")
+
+// exitSeverity lists the exit codes of failure classes, from the most to the least severe.
+var exitSeverity = []int{
+	ExitMismatch, ExitSignature, ExitMissing, ExitExtra, ExitManifest, ExitIO, ExitFailure,
+}
+
+// ExitCode returns the exit code for errs.
+// If errs joins errors of several classes, the exit code of the most severe class is returned.
+// ExitOK is returned if errs is nil.
+func ExitCode(errs error) int {
+	if errs == nil {
+		return ExitOK
+	}
+
+	var codes []int
+	for _, err := range UnwrapJoinedErrors(errs) {
+		codes = append(codes, exitClass(err))
+	}
+	return SevereExitCode(codes...)
+}
+
+// SevereExitCode returns the exit code of the most severe failure class among codes.
+// ExitOK is returned if codes only contains ExitOK.
+func SevereExitCode(codes ...int) int {
+	for _, code := range exitSeverity {
+		if slices.Contains(codes, code) {
+			return code
+		}
+	}
+	for _, code := range codes {
+		if code != ExitOK {
+			return ExitFailure
+		}
+	}
+	return ExitOK
+}
+
+// Exit returns a cli.ExitCoder exiting with the exit code for errs.
+// errs is expected to be already printed, so the returned error has no message.
+func Exit(errs error) error {
+	return cli.Exit("", ExitCode(errs))
+}
+
+// Result logs the final status for errs, and returns the error the command exits with.
+// The errors joined by errs are logged one per line.
+func Result(errs error) error {
+	if errs != nil {
+		Failln(MsgFinalError)
+		for _, err := range UnwrapJoinedErrors(errs) {
+			Failln(err)
+		}
+		return Exit(errs)
+	}
+
+	Println(MsgFinalDone)
+	return nil
+}
+
+// OnUsageError is the cli.OnUsageErrorFunc of all commands.
+// It shows the help of command, and exits with ExitUsage.
+func OnUsageError(ctx context.Context, command *cli.Command, err error, isSubcommand bool) error {
+	_ = cli.ShowSubcommandHelp(command)
+	return cli.Exit("Incorrect Usage: "+err.Error(), ExitUsage)
+}
+
+// exitClass returns the exit code of the class of err.
+func exitClass(err error) int {
+	var pathErr *fs.PathError
+	var linkErr *os.LinkError
+	var syscallErr *os.SyscallError
+
+	switch {
+	case errors.Is(err, medhash.ErrMismatch):
+		return ExitMismatch
+	case errors.Is(err, ErrManifest):
+		return ExitManifest
+	case errors.Is(err, fs.ErrNotExist):
+		return ExitMissing
+	case errors.Is(err, ErrExtra):
+		return ExitExtra
+	case errors.As(err, &pathErr), errors.As(err, &linkErr), errors.As(err, &syscallErr),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return ExitIO
+	default:
+		return ExitFailure
+	}
+}
@@ -0,0 +1,70 @@
+package cmd_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/cmd"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v3"
+)
+
+func TestExitCode(t *testing.T) {
+	mismatch := fmt.Errorf("a: %w", medhash.ErrMismatch)
+	missing := &fs.PathError{Op: "open", Path: "b", Err: fs.ErrNotExist}
+	extra := fmt.Errorf("c: %w", cmd.ErrExtra)
+	manifest := cmd.ManifestError(&fs.PathError{Op: "open", Path: "medhash.json", Err: fs.ErrNotExist})
+	ioErr := &fs.PathError{Op: "read", Path: "d", Err: io.ErrUnexpectedEOF}
+
+	cases := []struct {
+		id   string
+		errs error
+		code int
+	}{
+		{id: "nil", errs: nil, code: cmd.ExitOK},
+		{id: "failure", errs: errors.New("failure"), code: cmd.ExitFailure},
+		{id: "mismatch", errs: mismatch, code: cmd.ExitMismatch},
+		{id: "missing", errs: missing, code: cmd.ExitMissing},
+		{id: "extra", errs: extra, code: cmd.ExitExtra},
+		{id: "manifest", errs: manifest, code: cmd.ExitManifest},
+		{id: "io", errs: ioErr, code: cmd.ExitIO},
+		{id: "joined/mismatch", errs: cmd.JoinErrors(ioErr, missing, mismatch), code: cmd.ExitMismatch},
+		{id: "joined/missing", errs: cmd.JoinErrors(extra, missing), code: cmd.ExitMissing},
+		{id: "joined/io", errs: cmd.JoinErrors(errors.New("failure"), ioErr), code: cmd.ExitIO},
+	}
+
+	for _, c := range cases {
+		t.Run(c.id, func(t *testing.T) {
+			assert.Equal(t, c.code, cmd.ExitCode(c.errs))
+		})
+	}
+
+	// The message of the wrapped error is kept.
+	assert.Equal(t, "open medhash.json: file does not exist", manifest.Error())
+	assert.ErrorIs(t, manifest, fs.ErrNotExist)
+}
+
+func TestOnUsageError(t *testing.T) {
+	command := &cli.Command{
+		Name:           "test",
+		Writer:         io.Discard,
+		ErrWriter:      io.Discard,
+		OnUsageError:   cmd.OnUsageError,
+		ExitErrHandler: func(ctx context.Context, c *cli.Command, err error) {},
+		Action: func(ctx context.Context, c *cli.Command) error {
+			return nil
+		},
+	}
+
+	err := command.Run(t.Context(), []string{"test", "--invalid"})
+
+	var exitErr cli.ExitCoder
+	if assert.ErrorAs(t, err, &exitErr) {
+		assert.Equal(t, cmd.ExitUsage, exitErr.ExitCode())
+	}
+}
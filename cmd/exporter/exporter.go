@@ -0,0 +1,158 @@
+package exporter
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ghifari160/medhash-tools/checksum"
+	"github.com/ghifari160/medhash-tools/cmd"
+	"github.com/ghifari160/medhash-tools/hashdeep"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/ghifari160/medhash-tools/mhl"
+	"github.com/urfave/cli/v3"
+)
+
+func init() {
+	cmd.RegisterCmd(CommandExport())
+}
+
+func CommandExport() *cli.Command {
+	return &cli.Command{
+		Name:  "export",
+		Usage: "export MedHash Manifest to a checksum file or an ASC MHL",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "format",
+				Usage:    "checksum file format (" + strings.Join(formatNames(), ", ") + ")",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:    "manifest",
+				Aliases: []string{"m"},
+				Usage:   "use this manifest",
+				Value:   medhash.DefaultManifestName,
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage: "write checksum file to this path instead of the standard output; " +
+					"for mhl, write a single hash list instead of the ascmhl history",
+			},
+		},
+		Action: ExportAction,
+	}
+}
+
+func ExportAction(ctx context.Context, command *cli.Command) error {
+	format := checksum.Format(command.String("format"))
+	manPath := command.String("manifest")
+
+	medias, err := cmd.ReadMedia(manPath)
+	if err != nil {
+		return exitErr(err)
+	}
+
+	if format == mhl.FormatName {
+		path, err := exportMHL(manPath, command.String("output"), medias)
+		if err != nil {
+			return exitErr(err)
+		}
+		cmd.Printf("Exported %s to %s", manPath, path)
+		return nil
+	}
+
+	var out io.Writer = command.Root().Writer
+	if output := command.String("output"); output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return exitErr(err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if format == hashdeep.FormatName {
+		err = exportHashdeep(out, manPath, medias)
+	} else {
+		err = checksum.Write(out, format, medias)
+	}
+	if err != nil {
+		return exitErr(err)
+	}
+	return nil
+}
+
+// exportHashdeep exports medias listed by the Manifest at manPath to w as a hashdeep file.
+// The hashdeep algorithms shared by all medias are exported.
+func exportHashdeep(w io.Writer, manPath string, medias []medhash.Media) error {
+	root, err := cmd.MediaRoot(manPath)
+	if err != nil {
+		return err
+	}
+
+	entries, err := hashdeep.Stat(root, medias)
+	if err != nil {
+		return err
+	}
+
+	algs := slices.Clone(hashdeep.Algorithms)
+	for _, med := range medias {
+		algs = slices.DeleteFunc(algs, func(alg string) bool { return med.Hash.Get(alg) == "" })
+	}
+
+	return hashdeep.Write(w, algs, entries)
+}
+
+// exportMHL exports medias listed by the Manifest at manPath to an ASC MHL.
+// If output is empty, the MHL is written as the next generation of the ascmhl history in the
+// media root.
+// The path of the written MHL is returned.
+func exportMHL(manPath, output string, medias []medhash.Media) (string, error) {
+	root, err := cmd.MediaRoot(manPath)
+	if err != nil {
+		return "", err
+	}
+
+	entries, err := mhl.Stat(root, medias)
+	if err != nil {
+		return "", err
+	}
+
+	hostname, _ := os.Hostname()
+	creator := mhl.Creator{
+		Tool:     cmd.Name,
+		Version:  cmd.Version,
+		Hostname: hostname,
+		Date:     time.Now(),
+	}
+	ignores := []string{mhl.HistoryDir, filepath.Base(manPath)}
+
+	if output == "" {
+		return mhl.WriteHistory(root, creator, ignores, entries)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return output, mhl.Write(f, creator, ignores, entries)
+}
+
+// formatNames returns the names of all supported export formats.
+func formatNames() []string {
+	return append(checksum.FormatNames(), mhl.FormatName, hashdeep.FormatName)
+}
+
+// exitErr prints err as the final status.
+func exitErr(err error) error {
+	cmd.Failln(cmd.MsgFinalError)
+	cmd.Failln(err)
+	return cmd.Exit(err)
+}
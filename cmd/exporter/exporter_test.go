@@ -0,0 +1,130 @@
+package exporter_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/checksum"
+	"github.com/ghifari160/medhash-tools/cmd/exporter"
+	"github.com/ghifari160/medhash-tools/hashdeep"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/ghifari160/medhash-tools/mhl"
+	"github.com/ghifari160/medhash-tools/testcommon"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+
+	cases := []testcommon.TestCase{
+		testcommon.Case("sha512sum", string(checksum.FormatSHA512Sum)),
+		testcommon.Case("sha256sum", string(checksum.FormatSHA256Sum)),
+		testcommon.Case("sha1sum", string(checksum.FormatSHA1Sum)),
+		testcommon.Case("md5sum", string(checksum.FormatMD5Sum)),
+		testcommon.Case("bsd", string(checksum.FormatBSD)),
+	}
+
+	testcommon.RunCases(t, testExport, cases)
+}
+
+func testExport(t *testing.T, format string, opts ...testcommon.Options) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+
+	conf := medhash.AllConfig
+	conf.Dir = dir
+	conf.Manifest = medhash.DefaultManifestName
+	testcommon.CreateManifest(t, conf, payload, medhash.ManifestFormatVer)
+
+	var out bytes.Buffer
+	command := exporter.CommandExport()
+	command.Writer = &out
+
+	err := command.Run(t.Context(), []string{"export", "--format", format,
+		"--manifest", conf.ManifestPath()})
+	require.NoError(err)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if alg := checksum.Format(format).Algorithm(); alg != "" {
+		require.Equal([]string{checksum.FormatGNU(payload.Path, payload.Hash.Get(alg))}, lines)
+	} else {
+		require.Len(lines, len(medhash.AllConfig.Algorithms()))
+		for i, alg := range medhash.AllConfig.Algorithms() {
+			require.Equal(checksum.FormatBSDTag(alg, payload.Path, payload.Hash.Get(alg)), lines[i])
+		}
+	}
+}
+
+func TestExportMHL(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+
+	conf := medhash.AllConfig
+	conf.Dir = dir
+	conf.Manifest = medhash.DefaultManifestName
+	testcommon.CreateManifest(t, conf, payload, medhash.ManifestFormatVer)
+
+	command := exporter.CommandExport()
+	command.Writer = &bytes.Buffer{}
+
+	err := command.Run(t.Context(), []string{"export", "--format", mhl.FormatName,
+		"--manifest", conf.ManifestPath()})
+	require.NoError(err)
+
+	lists, err := filepath.Glob(filepath.Join(dir, mhl.HistoryDir, "0001_*.mhl"))
+	require.NoError(err)
+	require.Len(lists, 1)
+	require.FileExists(filepath.Join(dir, mhl.HistoryDir, mhl.ChainFile))
+
+	f, err := os.Open(lists[0])
+	require.NoError(err)
+	defer f.Close()
+
+	medias, err := mhl.Read(f)
+	require.NoError(err)
+	require.Equal([]medhash.Media{
+		{
+			Path: payload.Path,
+			Hash: medhash.Hash{XXH3: payload.Hash.XXH3, SHA1: payload.Hash.SHA1, MD5: payload.Hash.MD5},
+		},
+	}, medias)
+}
+
+func TestExportHashdeep(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+
+	conf := medhash.AllConfig
+	conf.Dir = dir
+	conf.Manifest = medhash.DefaultManifestName
+	testcommon.CreateManifest(t, conf, payload, medhash.ManifestFormatVer)
+
+	var out bytes.Buffer
+	command := exporter.CommandExport()
+	command.Writer = &out
+
+	err := command.Run(t.Context(), []string{"export", "--format", hashdeep.FormatName,
+		"--manifest", conf.ManifestPath()})
+	require.NoError(err)
+
+	entries, err := hashdeep.Read(&out)
+	require.NoError(err)
+	require.Len(entries, 1)
+	require.Equal(testcommon.PayloadSize(), entries[0].Size)
+	require.Equal(payload.Path, entries[0].Path)
+	for _, alg := range hashdeep.Algorithms {
+		require.Equal(payload.Hash.Get(alg), entries[0].Hash.Get(alg))
+	}
+}
@@ -4,12 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/ghifari160/medhash-tools/archive"
 	"github.com/ghifari160/medhash-tools/cmd"
-	"github.com/ghifari160/medhash-tools/color"
 	"github.com/ghifari160/medhash-tools/medhash"
 	"github.com/urfave/cli/v3"
 )
@@ -28,6 +30,26 @@ func CommandGen() *cli.Command {
 				Aliases: []string{"i"},
 				Usage:   "ignore patterns",
 			},
+			&cli.StringFlag{
+				Name:    "manifest",
+				Aliases: []string{"m"},
+				Usage:   "manifest file name",
+				Value:   medhash.DefaultManifestName,
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "write manifest to this path instead of the target directory",
+			},
+			&cli.BoolFlag{
+				Name:  "per-dir",
+				Usage: "write a manifest in each directory, listed by its parent manifest",
+			},
+			&cli.StringFlag{
+				Name:    "archive",
+				Aliases: []string{"a"},
+				Usage:   "generate manifest of the members of this tar or zip archive",
+			},
 		},
 		MutuallyExclusiveFlags: []cli.MutuallyExclusiveFlags{
 			{
@@ -67,148 +89,387 @@ func GenAction(ctx context.Context, command *cli.Command) error {
 		config.SHA256 = command.Bool("sha256")
 		config.SHA1 = command.Bool("sha1")
 		config.MD5 = command.Bool("md5")
+		config.CRC32 = command.Bool("crc32")
 	}
+	config.Manifest = command.String("manifest")
+	cmd.Debugf("Using algorithms: %s", strings.Join(config.Algorithms(), ", "))
 
 	dirs := command.Args().Slice()
 	if len(dirs) < 1 {
 		cwd, err := os.Getwd()
 		if err != nil {
-			return cli.Exit(fmt.Errorf("cannot get working directory: %w", err), 1)
+			return cli.Exit(fmt.Errorf("cannot get working directory: %w", err), cmd.ExitIO)
 		}
 		dirs = append(dirs, cwd)
 	}
 
-	var manifestIgnored bool
 	ignores := command.StringSlice("ignore")
+	perDir := command.Bool("per-dir")
 
-	for _, ignore := range ignores {
-		if ignore == medhash.DefaultManifestName {
-			manifestIgnored = true
+	if command.String("output") != "" && command.IsSet("manifest") {
+		return cli.Exit("--output cannot be used with --manifest", cmd.ExitUsage)
+	}
+
+	if archivePath := command.String("archive"); archivePath != "" {
+		if command.Args().Len() > 0 || perDir {
+			return cli.Exit("--archive cannot be used with target directories or --per-dir", cmd.ExitUsage)
 		}
+
+		config.Dir = filepath.Dir(archivePath)
+		if output := command.String("output"); output != "" {
+			output, err := filepath.Abs(output)
+			if err != nil {
+				return cli.Exit(fmt.Errorf("cannot resolve output path: %w", err), cmd.ExitIO)
+			}
+			config.Manifest = output
+		}
+
+		cmd.Printf("Generating MedHash for %s", archivePath)
+		return cmd.Result(GenArchiveFunc(config, archivePath, ignores))
 	}
 
-	if !manifestIgnored {
-		ignores = append(ignores, medhash.DefaultManifestName)
+	if output := command.String("output"); output != "" {
+		if perDir {
+			return cli.Exit("--output cannot be used with --per-dir", cmd.ExitUsage)
+		}
+		if len(dirs) > 1 {
+			return cli.Exit("--output requires a single target directory", cmd.ExitUsage)
+		}
+
+		output, err := filepath.Abs(output)
+		if err != nil {
+			return cli.Exit(fmt.Errorf("cannot resolve output path: %w", err), cmd.ExitIO)
+		}
+		config.Manifest = output
+
+		if rel, inside := cmd.RelToDir(dirs[0], output); inside {
+			ignores = cmd.IgnoreManifest(ignores, rel)
+		}
+	} else {
+		ignores = cmd.IgnoreManifest(ignores, config.Manifest)
 	}
+	ignores = cmd.IgnoreManifest(ignores, cmd.AuditLogName)
 
 	var errs error
 	for i, dir := range dirs {
 		if len(dirs) > 1 {
-			color.Printf("[%d/%d] Generating MedHash for %s\n", i+1, len(dirs), dir)
+			cmd.Printf("[%d/%d] Generating MedHash for %s", i+1, len(dirs), dir)
 		} else {
-			color.Printf("Generating MedHash for %s\n", dir)
+			cmd.Printf("Generating MedHash for %s", dir)
 		}
 
 		config := config
 		config.Dir = dir
 
-		err := GenFunc(config, ignores)
+		var err error
+		if perDir {
+			err = GenPerDirFunc(config, ignores)
+		} else {
+			err = GenFunc(config, ignores)
+		}
 		if err != nil {
 			errs = cmd.JoinErrors(errs, err)
 		}
 	}
 
-	if errs != nil {
-		color.Println(cmd.MsgFinalError)
-		for _, err := range cmd.UnwrapJoinedErrors(errs) {
-			color.Println(err)
-		}
-		return cli.Exit("", 1)
-	}
-
-	color.Println(cmd.MsgFinalDone)
-	return nil
+	return cmd.Result(errs)
 }
 
 // GenFunc generates a Manifest using the provided config.
+// The Manifest is written to config.ManifestPath.
+// If config.Manifest is empty, medhash.DefaultManifestName is used.
+// If the Manifest is written outside config.Dir, the absolute path of config.Dir is recorded as the
+// Manifest root.
 func GenFunc(config medhash.Config, ignores []string) error {
 	manifest, err := medhash.NewWithConfig(config)
 	if err != nil {
 		return err
 	}
 
+	manPath := manifest.Config.ManifestPath()
+	if _, inside := cmd.RelToDir(config.Dir, manPath); !inside {
+		root, err := filepath.Abs(config.Dir)
+		if err != nil {
+			return err
+		}
+		manifest.Root = filepath.ToSlash(root)
+	}
+
+	// Media are listed ahead of hashing, so that the progress of the whole generation is reported.
+	type entry struct {
+		path string
+		rel  string
+		size int64
+	}
+	var entries []entry
+	var total int64
+
 	var errs error
 	err = filepath.Walk(config.Dir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			err = fmt.Errorf("cannot access %s: %w", path, err)
+			cmd.StatusLabel(path, cmd.MsgStatusError, err)
+			errs = cmd.JoinErrors(errs, err)
+			return nil
+		}
 		if !info.Mode().IsRegular() {
 			return nil
 		}
 
-		color.Printf("  %s: ", path)
-
+		rel, err := filepath.Rel(config.Dir, path)
 		if err != nil {
-			color.Println(cmd.MsgStatusError)
-			errs = cmd.JoinErrors(errs, fmt.Errorf("cannot access %s: %w", path, err))
+			cmd.StatusLabel(path, cmd.MsgStatusError, err)
+			errs = cmd.JoinErrors(errs, err)
 			return nil
 		}
 
-		c := config
-		c.Dir = config.Dir
+		if skip, err := cmd.Ignored(rel, ignores); err != nil {
+			cmd.StatusLabel(path, cmd.MsgStatusError, err)
+			errs = cmd.JoinErrors(errs, err)
+		} else if skip {
+			cmd.StatusLabel(path, cmd.MsgStatusSkipped, nil)
+			return nil
+		}
 
-		rel, err := filepath.Rel(c.Dir, path)
-		if err != nil {
-			color.Println(cmd.MsgStatusError)
+		entries = append(entries, entry{path: path, rel: rel, size: info.Size()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		errs = cmd.JoinErrors(errs, err)
+	}
+
+	progress := cmd.NewProgress(total, len(entries))
+	manifest.Config.Progress = progress.Update
+	for _, e := range entries {
+		progress.Start(e.rel, e.size)
+		err := manifest.Add(e.rel)
+		progress.Finish()
+
+		errs = cmd.JoinErrors(errs, err)
+		cmd.Status(e.path, err)
+	}
+	progress.Close()
+
+	errs = cmd.JoinErrors(errs, sanityCheck(manifest, nil))
+	errs = cmd.JoinErrors(errs, writeManifest(manifest, manPath))
+	return errs
+}
+
+// GenPerDirFunc generates a Manifest in each directory of config.Dir using the provided config.
+// Each Manifest lists the media in its directory, and the Manifest of each of its subdirectories.
+// Directories without any media are skipped.
+func GenPerDirFunc(config medhash.Config, ignores []string) error {
+	if config.Manifest == "" {
+		config.Manifest = medhash.DefaultManifestName
+	}
+
+	// Each media is read twice, once to be hashed, and once to be sanity checked.
+	total, files := dirSize(config, "", ignores)
+	progress := cmd.NewProgress(2*total, 2*files)
+	defer progress.Close()
+
+	_, err := genDir(config, "", ignores, progress)
+	return err
+}
+
+// dirSize returns the total size and number of the media of the directory rel in config.Dir, and
+// its subdirectories, as generated by genDir.
+func dirSize(config medhash.Config, rel string, ignores []string) (total int64, files int) {
+	entries, err := os.ReadDir(filepath.Join(config.Dir, rel))
+	if err != nil {
+		return 0, 0
+	}
+
+	for _, entry := range entries {
+		entryRel := filepath.Join(rel, entry.Name())
+		if skip, err := cmd.Ignored(entryRel, ignores); skip || err != nil {
+			continue
+		}
+
+		if entry.IsDir() {
+			t, f := dirSize(config, entryRel, ignores)
+			total += t
+			files += f
+			continue
+		}
+		if !entry.Type().IsRegular() || entry.Name() == config.Manifest {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			total += info.Size()
+			files++
+		}
+	}
+	return total, files
+}
+
+// genDir generates a Manifest for the directory rel in config.Dir.
+// Subdirectories are generated first, so that their Manifest can be added to the Manifest of rel.
+// written reports whether a Manifest is written for rel.
+// The progress of reading media is reported to progress.
+func genDir(config medhash.Config, rel string, ignores []string, progress *cmd.Progress) (written bool,
+	errs error) {
+	dir := filepath.Join(config.Dir, rel)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, fmt.Errorf("cannot access %s: %w", dir, err)
+	}
+
+	c := config
+	c.Dir = dir
+	c.Progress = progress.Update
+
+	manifest, err := medhash.NewWithConfig(c)
+	if err != nil {
+		return false, err
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		entryRel := filepath.Join(rel, entry.Name())
+
+		if skip, err := cmd.Ignored(entryRel, ignores); err != nil {
+			cmd.StatusLabel(path, cmd.MsgStatusError, err)
 			errs = cmd.JoinErrors(errs, err)
+			continue
+		} else if skip {
+			cmd.StatusLabel(path, cmd.MsgStatusSkipped, nil)
+			continue
 		}
 
-		for _, ignore := range ignores {
-			matched, err := filepath.Match(ignore, rel)
-			if err != nil {
-				color.Println(cmd.MsgStatusError)
+		if entry.IsDir() {
+			childWritten, err := genDir(config, entryRel, ignores, progress)
+			errs = cmd.JoinErrors(errs, err)
+
+			if childWritten {
+				child := filepath.Join(entry.Name(), manifest.Config.Manifest)
+
+				err := manifest.AddManifest(child)
+				cmd.StatusLabel(filepath.Join(dir, child), cmd.MsgStatusOKOrError(err), err)
 				errs = cmd.JoinErrors(errs, err)
 			}
+			continue
+		}
 
-			if matched {
-				color.Println(cmd.MsgStatusSkipped)
-				return nil
-			}
+		if !entry.Type().IsRegular() || entry.Name() == manifest.Config.Manifest {
+			continue
 		}
 
-		err = manifest.Add(rel)
-		if err != nil {
-			color.Println(cmd.MsgStatusError)
+		var size int64
+		if info, err := entry.Info(); err == nil {
+			size = info.Size()
+		}
+
+		progress.Start(entry.Name(), size)
+		err := manifest.Add(entry.Name())
+		progress.Finish()
+
+		cmd.StatusLabel(path, cmd.MsgStatusOKOrError(err), err)
+		errs = cmd.JoinErrors(errs, err)
+	}
+
+	if len(manifest.Media) < 1 && len(manifest.Manifests) < 1 {
+		return false, errs
+	}
+
+	errs = cmd.JoinErrors(errs, sanityCheck(manifest, progress))
+	err = writeManifest(manifest, manifest.Config.ManifestPath())
+	if err != nil {
+		return false, cmd.JoinErrors(errs, err)
+	}
+	return true, errs
+}
+
+// GenArchiveFunc generates a Manifest of the members of the archive at archivePath using the
+// provided config.
+// Members are streamed from the archive, and are never extracted.
+// Unlike GenFunc, the media are not sanity checked, as that would require reading the archive twice.
+// The Manifest is written to config.ManifestPath.
+func GenArchiveFunc(config medhash.Config, archivePath string, ignores []string) error {
+	manifest, err := medhash.NewWithConfig(config)
+	if err != nil {
+		return err
+	}
+
+	var errs error
+	err = archive.Walk(archivePath, func(name string, r io.Reader) error {
+		path := filepath.Join(archivePath, name)
+
+		if skip, err := cmd.Ignored(filepath.FromSlash(name), ignores); err != nil {
+			cmd.StatusLabel(path, cmd.MsgStatusError, err)
 			errs = cmd.JoinErrors(errs, err)
-		} else {
-			color.Println(cmd.MsgStatusOK)
+		} else if skip {
+			cmd.StatusLabel(path, cmd.MsgStatusSkipped, nil)
+			return nil
 		}
 
+		err := manifest.AddReader(name, r)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", name, err)
+			cmd.StatusLabel(path, cmd.MsgStatusError, err)
+			return err
+		}
+
+		cmd.StatusLabel(path, cmd.MsgStatusOK, nil)
 		return nil
 	})
 	if err != nil {
 		errs = cmd.JoinErrors(errs, err)
+		return errs
 	}
 
-	color.Println("Sanity checking files")
+	errs = cmd.JoinErrors(errs, writeManifest(manifest, manifest.Config.ManifestPath()))
+	return errs
+}
 
-	for _, med := range manifest.Media {
-		c := config
+// sanityCheck verifies the hashes of all media in manifest.
+// The progress of reading media is reported to progress, or to a new Progress if progress is nil.
+func sanityCheck(manifest *medhash.Manifest, progress *cmd.Progress) error {
+	sizes := make([]int64, len(manifest.Media))
+	var total int64
+	for i, med := range manifest.Media {
+		if info, err := os.Stat(filepath.Join(manifest.Config.Dir, filepath.FromSlash(med.Path))); err == nil {
+			sizes[i] = info.Size()
+			total += info.Size()
+		}
+	}
 
-		color.Printf("  %s: ", (filepath.Join(c.Dir, med.Path)))
+	if progress == nil {
+		cmd.Println("Sanity checking files")
+		progress = cmd.NewProgress(total, len(manifest.Media))
+		defer progress.Close()
+	} else {
+		cmd.Printf("Sanity checking files in %s", manifest.Config.Dir)
+	}
+	manifest.Config.Progress = progress.Update
 
+	var errs error
+	for i, med := range manifest.Media {
+		progress.Start(med.Path, sizes[i])
 		err := manifest.Check(med.Path)
-		if err != nil {
-			errs = cmd.JoinErrors(errs, err)
-			color.Println(cmd.MsgStatusError)
-		} else {
-			color.Println(cmd.MsgStatusOK)
-		}
+		progress.Finish()
+
+		cmd.StatusLabel(filepath.Join(manifest.Config.Dir, med.Path), cmd.MsgStatusOKOrError(err), err)
+		errs = cmd.JoinErrors(errs, err)
 	}
+	return errs
+}
 
+// writeManifest writes manifest to path.
+func writeManifest(manifest *medhash.Manifest, path string) error {
 	manFile, err := json.MarshalIndent(manifest, "", "  ")
 	if err != nil {
-		errs = cmd.JoinErrors(errs, err)
-		return errs
+		return err
 	}
 
-	f, err := os.Create(filepath.Join(config.Dir, medhash.DefaultManifestName))
+	f, err := os.Create(path)
 	if err != nil {
-		errs = cmd.JoinErrors(errs, err)
-		return errs
+		return err
 	}
 	defer f.Close()
 
 	_, err = f.Write(manFile)
-	if err != nil {
-		errs = cmd.JoinErrors(errs, err)
-	}
-	return errs
+	return err
 }
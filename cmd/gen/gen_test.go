@@ -1,13 +1,17 @@
 package gen_test
 
 import (
+	"context"
+	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/ghifari160/medhash-tools/cmd"
 	"github.com/ghifari160/medhash-tools/cmd/gen"
 	"github.com/ghifari160/medhash-tools/medhash"
 	"github.com/ghifari160/medhash-tools/testcommon"
 	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
 )
 
 func TestGen(t *testing.T) {
@@ -23,6 +27,9 @@ func TestGen(t *testing.T) {
 
 		testcommon.Case("default", "default"),
 		testcommon.Case("all", "all"),
+
+		testcommon.Case("manifest", "default", withManifest("delivery.json")),
+		testcommon.Case("output", "default", withOutput(true)),
 	}
 
 	testcommon.RunCases(t, testGen, cases)
@@ -35,12 +42,17 @@ func testGen(t *testing.T, alg string, opts ...testcommon.Options) {
 	dir := t.TempDir()
 	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
 
+	options := testcommon.MergeOptions(opts...)
+	manifest := options.Str("manifest")
+	if !options.IsStr("manifest") {
+		manifest = medhash.DefaultManifestName
+	}
+
 	command := gen.CommandGen()
 	var conf medhash.Config
 
-	arguments := make([]string, 3)
+	arguments := make([]string, 2)
 	arguments[0] = "gen"
-	arguments[2] = dir
 
 	switch alg {
 	case "xxh3":
@@ -69,10 +81,123 @@ func testGen(t *testing.T, alg string, opts ...testcommon.Options) {
 		arguments[1] = "--default"
 	}
 	conf.Dir = dir
-	conf.Manifest = medhash.DefaultManifestName
+	conf.Manifest = manifest
+
+	if options.IsStr("manifest") {
+		arguments = append(arguments, "--manifest", manifest)
+	}
+	if options.Bool("output") {
+		conf.Manifest = filepath.Join(t.TempDir(), "output.json")
+		arguments = append(arguments, "--output", conf.Manifest)
+	}
+	arguments = append(arguments, dir)
 
 	err := command.Run(t.Context(), arguments)
 	require.NoError(err)
-	require.FileExists(filepath.Join(dir, conf.Manifest))
+	require.FileExists(conf.ManifestPath())
+	if conf.Manifest != medhash.DefaultManifestName {
+		require.NoFileExists(filepath.Join(dir, medhash.DefaultManifestName))
+	}
 	testcommon.VerifyManifest(t, conf, payload.Hash)
+
+	man := testcommon.ReadManifest(t, conf.ManifestPath())
+	if options.Bool("output") {
+		require.Equal(filepath.ToSlash(dir), man.Root)
+	} else {
+		require.Empty(man.Root)
+	}
+}
+
+// withManifest specifies the Manifest name argument to a command for testing.
+func withManifest(manifest string) testcommon.Options {
+	return testcommon.NewOptions("manifest", manifest)
+}
+
+// withOutput writes the Manifest outside the target directory for testing.
+func withOutput(output bool) testcommon.Options {
+	return testcommon.NewOptions("output", output)
+}
+
+func TestGenOutputManifest(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	output := filepath.Join(t.TempDir(), "output.json")
+
+	command := gen.CommandGen()
+	command.ExitErrHandler = func(ctx context.Context, c *cli.Command, err error) {}
+	err := command.Run(t.Context(),
+		[]string{"gen", "--default", "--output", output, "--manifest", "other.json", dir})
+
+	var exitErr cli.ExitCoder
+	require.ErrorAs(err, &exitErr)
+	require.Equal(cmd.ExitUsage, exitErr.ExitCode())
+	require.NoFileExists(output)
+}
+
+func TestGenPerDir(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	require.NoError(os.Mkdir(sub, 0755))
+	require.NoError(os.Mkdir(filepath.Join(dir, "empty"), 0755))
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+	subPayload := testcommon.GenPayload(t, sub, testcommon.PayloadSize())
+
+	command := gen.CommandGen()
+	err := command.Run(t.Context(), []string{"gen", "--default", "--per-dir", dir})
+	require.NoError(err)
+
+	conf := medhash.DefaultConfig
+	conf.Dir = dir
+	conf.Manifest = medhash.DefaultManifestName
+	testcommon.VerifyManifest(t, conf, payload.Hash)
+
+	man := testcommon.ReadManifest(t, conf.ManifestPath())
+	require.Len(man.Media, 1)
+	require.Len(man.Manifests, 1)
+	require.Equal("sub/"+medhash.DefaultManifestName, man.Manifests[0].Path)
+	require.NotEmpty(man.Manifests[0].Hash.XXH3)
+	require.NoFileExists(filepath.Join(dir, "empty", medhash.DefaultManifestName))
+
+	conf.Dir = sub
+	testcommon.VerifyManifest(t, conf, subPayload.Hash)
+}
+
+func TestGenArchive(t *testing.T) {
+	t.Parallel()
+
+	for _, format := range []string{"tar", "tar.gz", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			testGenArchive(t, format)
+		})
+	}
+}
+
+func testGenArchive(t *testing.T, format string) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+
+	out := t.TempDir()
+	archivePath := filepath.Join(out, "delivery."+format)
+	testcommon.CreateArchive(t, archivePath, format, dir)
+
+	command := gen.CommandGen()
+	err := command.Run(t.Context(), []string{"gen", "--all", "--archive", archivePath})
+	require.NoError(err)
+
+	conf := medhash.AllConfig
+	conf.Dir = out
+	conf.Manifest = medhash.DefaultManifestName
+	testcommon.VerifyManifest(t, conf, payload.Hash)
+
+	man := testcommon.ReadManifest(t, conf.ManifestPath())
+	require.Len(man.Media, 1)
+	require.Equal(payload.Path, man.Media[0].Path)
 }
@@ -0,0 +1,131 @@
+package hash
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ghifari160/medhash-tools/checksum"
+	"github.com/ghifari160/medhash-tools/cmd"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/urfave/cli/v3"
+)
+
+// Stdin is the file argument that reads from the standard input.
+const Stdin = "-"
+
+func init() {
+	cmd.RegisterCmd(CommandHash())
+}
+
+func CommandHash() *cli.Command {
+	return &cli.Command{
+		Name:      "hash",
+		Usage:     "print hashes of files or the standard input",
+		ArgsUsage: "[files...]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "output format (sum, json)",
+				Value: "sum",
+			},
+		},
+		MutuallyExclusiveFlags: []cli.MutuallyExclusiveFlags{
+			{
+				Flags: [][]cli.Flag{
+					{
+						&cli.BoolFlag{
+							Name:  "default",
+							Usage: "use default preset",
+							Value: true,
+						},
+					},
+					{
+						&cli.BoolFlag{
+							Name:  "all",
+							Usage: "use all algorithms",
+						},
+					},
+					cmd.HashAlgs(),
+				},
+			},
+		},
+		Action: HashAction,
+	}
+}
+
+func HashAction(ctx context.Context, command *cli.Command) error {
+	config := cmd.HashConfig(command)
+	format := command.String("format")
+
+	if format != "sum" && format != "json" {
+		return cli.Exit(fmt.Sprintf("unknown format: %s", format), cmd.ExitUsage)
+	}
+
+	files := command.Args().Slice()
+	if len(files) < 1 {
+		files = append(files, Stdin)
+	}
+
+	out := command.Root().Writer
+
+	var errs error
+	medias := make([]medhash.Media, 0, len(files))
+	for _, file := range files {
+		sum, err := hashFile(command.Root().Reader, file, config)
+		if err != nil {
+			errs = cmd.JoinErrors(errs, fmt.Errorf("%s: %w", file, err))
+			continue
+		}
+
+		med := medhash.Media{Path: file, Hash: sum}
+		if format == "sum" {
+			printSum(out, config.Algorithms(), med)
+		} else {
+			medias = append(medias, med)
+		}
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		errs = cmd.JoinErrors(errs, enc.Encode(medias))
+	}
+
+	if errs != nil {
+		return cmd.Result(errs)
+	}
+
+	return nil
+}
+
+// printSum prints the algs digests of med to out.
+// A single digest is printed as a GNU coreutils line, and multiple digests as BSD tag lines, one per
+// algorithm, as digests of different algorithms may have the same length.
+func printSum(out io.Writer, algs []string, med medhash.Media) {
+	if len(algs) == 1 {
+		fmt.Fprintln(out, checksum.FormatGNU(med.Path, med.Hash.Get(algs[0])))
+		return
+	}
+	for _, alg := range algs {
+		fmt.Fprintln(out, checksum.FormatBSDTag(alg, med.Path, med.Hash.Get(alg)))
+	}
+}
+
+// hashFile generates the hashes for file as configured.
+// If file is Stdin, stdin is hashed instead.
+func hashFile(stdin io.Reader, file string, config medhash.Config) (medhash.Hash, error) {
+	if file == Stdin {
+		return medhash.HashReader(config, stdin)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return medhash.Hash{}, err
+	}
+	defer f.Close()
+
+	return medhash.HashReader(config, f)
+}
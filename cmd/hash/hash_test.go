@@ -0,0 +1,139 @@
+package hash_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/checksum"
+	"github.com/ghifari160/medhash-tools/cmd/hash"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/ghifari160/medhash-tools/testcommon"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+func TestHash(t *testing.T) {
+	t.Parallel()
+
+	cases := []testcommon.TestCase{
+		testcommon.Case("xxh3", "xxh3"),
+		testcommon.Case("sha512", "sha512"),
+		testcommon.Case("sha3", "sha3"),
+		testcommon.Case("sha256", "sha256"),
+		testcommon.Case("sha1", "sha1"),
+		testcommon.Case("md5", "md5"),
+
+		testcommon.Case("default", "default"),
+		testcommon.Case("stdin", "sha256", withStdin(true)),
+		testcommon.Case("json", "all", withFormat("json")),
+		testcommon.Case("json/stdin", "all", withFormat("json"), withStdin(true)),
+		testcommon.Case("sum/multiple", "all", withFormat("sum")),
+	}
+
+	testcommon.RunCases(t, testHash, cases)
+}
+
+func testHash(t *testing.T, alg string, opts ...testcommon.Options) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+	payloadPath := filepath.Join(dir, payload.Path)
+
+	options := testcommon.MergeOptions(opts...)
+	stdin := options.Bool("stdin")
+	format := "sum"
+	if options.IsStr("format") {
+		format = options.Str("format")
+	}
+
+	var out bytes.Buffer
+
+	command := hash.CommandHash()
+	command.Writer = &out
+	command.ExitErrHandler = func(ctx context.Context, c *cli.Command, err error) {}
+
+	var conf medhash.Config
+	arguments := []string{"hash", "--format", format}
+
+	switch alg {
+	case "xxh3":
+		conf.XXH3 = true
+		arguments = append(arguments, "--xxh3")
+	case "sha512":
+		conf.SHA512 = true
+		arguments = append(arguments, "--sha512")
+	case "sha3":
+		conf.SHA3 = true
+		arguments = append(arguments, "--sha3")
+	case "sha256":
+		conf.SHA256 = true
+		arguments = append(arguments, "--sha256")
+	case "sha1":
+		conf.SHA1 = true
+		arguments = append(arguments, "--sha1")
+	case "md5":
+		conf.MD5 = true
+		arguments = append(arguments, "--md5")
+	case "all":
+		conf = medhash.AllConfig
+		arguments = append(arguments, "--all")
+	default:
+		conf = medhash.DefaultConfig
+		arguments = append(arguments, "--default")
+	}
+
+	path := payloadPath
+	if stdin {
+		f, err := os.Open(payloadPath)
+		require.NoError(err)
+		defer f.Close()
+		command.Reader = f
+		path = hash.Stdin
+	}
+	arguments = append(arguments, path)
+
+	require.NoError(command.Run(t.Context(), arguments))
+
+	switch format {
+	case "sum":
+		algs := conf.Algorithms()
+		if len(algs) == 1 {
+			expected := payload.Hash.Get(algs[0]) + "  " + path
+			require.Equal(expected, strings.TrimSpace(out.String()))
+			break
+		}
+
+		// Multiple digests are printed one per line, in the BSD tag format.
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		require.Len(lines, len(algs))
+		for i, alg := range algs {
+			require.Equal(checksum.FormatBSDTag(alg, path, payload.Hash.Get(alg)), lines[i])
+		}
+
+	case "json":
+		var medias []medhash.Media
+		require.NoError(json.Unmarshal(out.Bytes(), &medias))
+		require.Len(medias, 1)
+		require.Equal(path, medias[0].Path)
+		for _, a := range conf.Algorithms() {
+			require.Equal(payload.Hash.Get(a), medias[0].Hash.Get(a))
+		}
+	}
+}
+
+// withStdin reads the payload from stdin for testing.
+func withStdin(stdin bool) testcommon.Options {
+	return testcommon.NewOptions("stdin", stdin)
+}
+
+// withFormat specifies the Format argument to a command for testing.
+func withFormat(format string) testcommon.Options {
+	return testcommon.NewOptions("format", format)
+}
@@ -0,0 +1,176 @@
+package importer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghifari160/medhash-tools/checksum"
+	"github.com/ghifari160/medhash-tools/cmd"
+	"github.com/ghifari160/medhash-tools/hashdeep"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/ghifari160/medhash-tools/mhl"
+	"github.com/ghifari160/medhash-tools/par2"
+	"github.com/urfave/cli/v3"
+)
+
+func init() {
+	cmd.RegisterCmd(CommandImport())
+}
+
+func CommandImport() *cli.Command {
+	return &cli.Command{
+		Name:      "import",
+		Usage:     "import a checksum file or an ASC MHL into a MedHash Manifest",
+		ArgsUsage: "<checksum file> [dir]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name: "format",
+				Usage: "checksum file format (" + strings.Join(checksum.FormatNames(), ", ") + ", " +
+					mhl.FormatName + ", " + hashdeep.FormatName + ", " + par2.FormatName + "); detected if unset",
+			},
+			&cli.StringFlag{
+				Name:    "manifest",
+				Aliases: []string{"m"},
+				Usage:   "manifest file name",
+				Value:   medhash.DefaultManifestName,
+			},
+		},
+		Action: ImportAction,
+	}
+}
+
+func ImportAction(ctx context.Context, command *cli.Command) error {
+	args := command.Args().Slice()
+	if len(args) < 1 || len(args) > 2 {
+		return cli.Exit("import requires a checksum file and an optional target directory", cmd.ExitUsage)
+	}
+
+	var config medhash.Config
+	config.Manifest = command.String("manifest")
+	sumsPath := args[0]
+	if len(args) > 1 {
+		config.Dir = args[1]
+	} else {
+		config.Dir = filepath.Dir(sumsPath)
+		// MHL in an ascmhl history folder describe its parent directory.
+		if filepath.Base(config.Dir) == mhl.HistoryDir {
+			config.Dir = filepath.Dir(config.Dir)
+		}
+	}
+
+	cmd.Printf("Importing %s into %s", sumsPath, config.ManifestPath())
+
+	err := ImportFunc(config, sumsPath, checksum.Format(command.String("format")))
+	return cmd.Result(err)
+}
+
+// ImportFunc converts the checksum file at sumsPath in format into a Manifest.
+// If format is empty, it is detected.
+// Files with the .mhl extension are read as ASC MHL, or legacy MHL, files with the .sfv extension
+// are read as SFV, and files with the .par2 extension are read as PAR2.
+// Files starting with the hashdeep header are read as hashdeep files.
+// The hashes are imported as is, and the media are not read.
+// Media without a hash supported by MedHash are imported without hashes.
+// Absolute media paths are made relative to config.Dir.
+// It is an error for media paths to be outside of config.Dir.
+// The checksum file is recorded as the source of the hashes in the Manifest generator.
+// The Manifest is written to config.ManifestPath.
+// It is an error for config.ManifestPath to already exist.
+func ImportFunc(config medhash.Config, sumsPath string, format checksum.Format) error {
+	manPath := config.ManifestPath()
+	if _, err := os.Lstat(manPath); err == nil {
+		return fmt.Errorf("%s already exists, use --manifest to write another manifest", manPath)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	f, err := os.Open(sumsPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	medias, err := read(f, sumsPath, format)
+	if err != nil {
+		return fmt.Errorf("%s: %w", sumsPath, err)
+	}
+
+	manifest, err := medhash.NewWithConfig(config)
+	if err != nil {
+		return err
+	}
+	manifest.Generator = cmd.Generator("imported from " + filepath.Base(sumsPath))
+
+	for i, med := range medias {
+		p := filepath.FromSlash(med.Path)
+		if filepath.IsAbs(p) {
+			rel, inside := cmd.RelToDir(config.Dir, p)
+			if !inside {
+				return fmt.Errorf("%s is outside of %s", med.Path, config.Dir)
+			}
+			p = rel
+		} else if !filepath.IsLocal(p) {
+			return fmt.Errorf("%s is outside of %s", med.Path, config.Dir)
+		}
+		medias[i].Path = filepath.ToSlash(filepath.Clean(p))
+
+		label := cmd.MsgStatusOK
+		if med.Hash == (medhash.Hash{}) {
+			label = cmd.MsgStatusSkipped
+		}
+		cmd.StatusLabel(filepath.Join(config.Dir, p), label, nil)
+	}
+	manifest.AddMedia(medias...)
+
+	return cmd.WriteManifest(manifest, manPath)
+}
+
+// read reads the medias of the checksum file sumsPath in format from r.
+// If format is empty, it is detected.
+func read(r io.Reader, sumsPath string, format checksum.Format) ([]medhash.Media, error) {
+	br := bufio.NewReader(r)
+
+	if format == "" {
+		if header, _ := br.Peek(len(hashdeep.Header)); string(header) == hashdeep.Header {
+			format = hashdeep.FormatName
+		} else {
+			switch strings.ToLower(filepath.Ext(sumsPath)) {
+			case ".mhl":
+				format = mhl.FormatName
+			case ".sfv":
+				format = checksum.FormatSFV
+			case ".par2":
+				format = par2.FormatName
+			}
+		}
+	}
+
+	switch format {
+	case mhl.FormatName:
+		return mhl.Read(br)
+
+	case par2.FormatName:
+		return par2.Read(br)
+
+	case hashdeep.FormatName:
+		entries, err := hashdeep.Read(br)
+		if err != nil {
+			return nil, err
+		}
+		medias := make([]medhash.Media, len(entries))
+		for i, entry := range entries {
+			medias[i] = entry.Media
+		}
+		return medias, nil
+
+	default:
+		return checksum.Read(br, format)
+	}
+}
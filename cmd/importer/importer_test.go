@@ -0,0 +1,210 @@
+package importer_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ghifari160/medhash-tools/checksum"
+	"github.com/ghifari160/medhash-tools/cmd/importer"
+	"github.com/ghifari160/medhash-tools/hashdeep"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/ghifari160/medhash-tools/mhl"
+	"github.com/ghifari160/medhash-tools/testcommon"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	cases := []testcommon.TestCase{
+		testcommon.Case("sha512sum", string(checksum.FormatSHA512Sum)),
+		testcommon.Case("sha256sum", string(checksum.FormatSHA256Sum)),
+		testcommon.Case("sha1sum", string(checksum.FormatSHA1Sum)),
+		testcommon.Case("md5sum", string(checksum.FormatMD5Sum)),
+		testcommon.Case("bsd", string(checksum.FormatBSD)),
+		testcommon.Case("detect/binary", string(checksum.FormatSHA256Sum), withDetect(true)),
+	}
+
+	testcommon.RunCases(t, testImport, cases)
+}
+
+func testImport(t *testing.T, format string, opts ...testcommon.Options) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+
+	options := testcommon.MergeOptions(opts...)
+	detect := options.Bool("detect")
+
+	var conf medhash.Config
+	var content string
+	if alg := checksum.Format(format).Algorithm(); alg != "" {
+		conf.Dir = dir
+		conf.Manifest = medhash.DefaultManifestName
+		conf.SHA512 = alg == "sha512"
+		conf.SHA256 = alg == "sha256"
+		conf.SHA1 = alg == "sha1"
+		conf.MD5 = alg == "md5"
+		content = checksum.FormatGNU(payload.Path, payload.Hash.Get(alg)) + "\n"
+		if detect {
+			content = fmt.Sprintf("%s *%s\n", payload.Hash.Get(alg), payload.Path)
+		}
+	} else {
+		conf = medhash.AllConfig
+		conf.Dir = dir
+		conf.Manifest = medhash.DefaultManifestName
+		for _, alg := range conf.Algorithms() {
+			content += checksum.FormatBSDTag(alg, payload.Path, payload.Hash.Get(alg)) + "\n"
+		}
+	}
+
+	sumsPath := filepath.Join(dir, "CHECKSUMS")
+	require.NoError(os.WriteFile(sumsPath, []byte(content), 0644))
+
+	if detect {
+		format = ""
+	}
+	require.NoError(importer.ImportFunc(conf, sumsPath, checksum.Format(format)))
+	testcommon.VerifyManifest(t, conf, payload.Hash)
+
+	man := testcommon.ReadManifest(t, conf.ManifestPath())
+	require.Len(man.Media, 1)
+	require.NoError(man.Media[0].Check(conf))
+}
+
+func TestImportOutside(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	var conf medhash.Config
+	conf.Dir = dir
+	conf.Manifest = medhash.DefaultManifestName
+
+	for _, p := range []string{"../a", "dir/../../a"} {
+		sumsPath := filepath.Join(dir, "CHECKSUMS")
+		content := checksum.FormatGNU(p, strings.Repeat("0", 32)) + "\n"
+		require.NoError(t, os.WriteFile(sumsPath, []byte(content), 0644))
+
+		require.Error(t, importer.ImportFunc(conf, sumsPath, checksum.FormatMD5Sum), p)
+		require.NoFileExists(t, conf.ManifestPath())
+	}
+}
+
+func TestImportExisting(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+
+	var conf medhash.Config
+	conf.Dir = dir
+	conf.Manifest = medhash.DefaultManifestName
+
+	require.NoError(os.WriteFile(conf.ManifestPath(), []byte("{}"), 0644))
+	sumsPath := filepath.Join(dir, "CHECKSUMS")
+	content := checksum.FormatGNU("a", strings.Repeat("0", 32)) + "\n"
+	require.NoError(os.WriteFile(sumsPath, []byte(content), 0644))
+
+	require.Error(importer.ImportFunc(conf, sumsPath, checksum.FormatMD5Sum))
+	manFile, err := os.ReadFile(conf.ManifestPath())
+	require.NoError(err)
+	require.Equal("{}", string(manFile))
+}
+
+// withDetect omits the checksum file format, and writes the binary marker for testing.
+func withDetect(detect bool) testcommon.Options {
+	return testcommon.NewOptions("detect", detect)
+}
+
+func TestImportMHL(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+
+	entries, err := mhl.Stat(dir, []medhash.Media{payload})
+	require.NoError(err)
+	listPath, err := mhl.WriteHistory(dir, mhl.Creator{Date: time.Now()}, nil, entries)
+	require.NoError(err)
+
+	var conf medhash.Config
+	conf.Dir = dir
+	conf.Manifest = medhash.DefaultManifestName
+	conf.XXH3 = true
+	conf.SHA1 = true
+	conf.MD5 = true
+
+	require.NoError(importer.ImportFunc(conf, listPath, ""))
+	testcommon.VerifyManifest(t, conf, payload.Hash)
+
+	man := testcommon.ReadManifest(t, conf.ManifestPath())
+	require.Len(man.Media, 1)
+	require.NoError(man.Media[0].Check(conf))
+}
+
+func TestImportHashdeep(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+
+	// hashdeep records absolute paths unless invoked with relative paths.
+	abs := payload
+	abs.Path = filepath.ToSlash(filepath.Join(dir, payload.Path))
+
+	sumsPath := filepath.Join(dir, "known.txt")
+	f, err := os.Create(sumsPath)
+	require.NoError(err)
+	entries := []hashdeep.Entry{{Media: abs, Size: testcommon.PayloadSize()}}
+	require.NoError(hashdeep.Write(f, hashdeep.Algorithms, entries))
+	require.NoError(f.Close())
+
+	var conf medhash.Config
+	conf.Dir = dir
+	conf.Manifest = medhash.DefaultManifestName
+	conf.SHA256 = true
+	conf.SHA1 = true
+	conf.MD5 = true
+
+	require.NoError(importer.ImportFunc(conf, sumsPath, ""))
+	testcommon.VerifyManifest(t, conf, payload.Hash)
+
+	man := testcommon.ReadManifest(t, conf.ManifestPath())
+	require.Len(man.Media, 1)
+	require.Equal(payload.Path, man.Media[0].Path)
+	require.NoError(man.Media[0].Check(conf))
+}
+
+func TestImportSFV(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+
+	sumsPath := filepath.Join(dir, "delivery.sfv")
+	sfv := payload.Path + " " + payload.Hash.CRC32 + "\n"
+	require.NoError(os.WriteFile(sumsPath, []byte(sfv), 0644))
+
+	var conf medhash.Config
+	conf.Dir = dir
+	conf.Manifest = medhash.DefaultManifestName
+	conf.CRC32 = true
+
+	require.NoError(importer.ImportFunc(conf, sumsPath, ""))
+	testcommon.VerifyManifest(t, conf, payload.Hash)
+
+	man := testcommon.ReadManifest(t, conf.ManifestPath())
+	require.Len(man.Media, 1)
+	require.NoError(man.Media[0].Check(conf))
+	require.Contains(man.Generator, "imported from delivery.sfv")
+}
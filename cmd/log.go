@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ghifari160/medhash-tools/color"
+	"github.com/urfave/cli/v3"
+)
+
+// Log formats.
+const (
+	// LogFormatText logs status messages as human-readable lines.
+	LogFormatText = "text"
+	// LogFormatJSON logs status messages as JSON lines.
+	LogFormatJSON = "json"
+)
+
+func init() {
+	SetLog(os.Stderr, slog.LevelInfo, LogFormatText)
+}
+
+// LogFlags returns the global flags configuring the log of status messages, and the formatting of
+// the output.
+func LogFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:    "quiet",
+			Aliases: []string{"q"},
+			Usage:   "only log failures",
+		},
+		&cli.BoolFlag{
+			Name:  "verbose",
+			Usage: "log debugging messages",
+		},
+		&cli.StringFlag{
+			Name:  "log-format",
+			Usage: "log status messages as text or json",
+			Value: LogFormatText,
+		},
+		&cli.StringFlag{
+			Name:  "color",
+			Usage: "format the output with colors (auto, always, never)",
+			Value: string(color.ModeAuto),
+		},
+		&cli.StringFlag{
+			Name:    "theme",
+			Usage:   "format status labels with this theme (" + strings.Join(themeNames(), ", ") + ")",
+			Value:   color.ThemeDefault.Name,
+			Sources: cli.EnvVars("MEDHASH_THEME"),
+		},
+	}
+}
+
+// SetupLog configures the log of status messages, and the formatting of the output, from the flags
+// of LogFlags set in command.
+// Status messages are logged to the standard error, so that the standard output only contains
+// results.
+// SetupLog is meant to be the Before action of the root command.
+func SetupLog(ctx context.Context, command *cli.Command) (context.Context, error) {
+	level := slog.LevelInfo
+	if command.Bool("quiet") && command.Bool("verbose") {
+		return ctx, cli.Exit("--quiet cannot be used with --verbose", ExitUsage)
+	} else if command.Bool("quiet") {
+		level = slog.LevelWarn
+	} else if command.Bool("verbose") {
+		level = slog.LevelDebug
+	}
+
+	format := command.String("log-format")
+	if format != LogFormatText && format != LogFormatJSON {
+		return ctx, cli.Exit(fmt.Sprintf("unknown log format %q", format), ExitUsage)
+	}
+
+	mode, err := color.ParseMode(command.String("color"))
+	if err != nil {
+		return ctx, cli.Exit(err, ExitUsage)
+	}
+	color.SetMode(mode)
+
+	theme, err := color.ParseTheme(command.String("theme"))
+	if err != nil {
+		return ctx, cli.Exit(err, ExitUsage)
+	}
+	SetTheme(theme)
+
+	SetLog(os.Stderr, level, format)
+	return ctx, nil
+}
+
+// themeNames returns the names of the built-in themes.
+func themeNames() []string {
+	names := make([]string, len(color.Themes))
+	for i, theme := range color.Themes {
+		names[i] = theme.Name
+	}
+	return names
+}
+
+// SetLog sets the default slog.Logger to log status messages of level and above to w, in format.
+func SetLog(w io.Writer, level slog.Leveler, format string) {
+	out.mu.Lock()
+	out.w = w
+	out.format = format
+	out.tty = format == LogFormatText && color.IsTerminalWriter(w)
+	out.mu.Unlock()
+
+	var handler slog.Handler
+	if format == LogFormatJSON {
+		handler = slog.NewJSONHandler(out, &slog.HandlerOptions{Level: level})
+	} else {
+		handler = &textHandler{level: level}
+	}
+	slog.SetDefault(slog.New(handler))
+}
+
+// Printf logs a status message at info level.
+func Printf(format string, a ...any) {
+	logMsg(slog.LevelInfo, fmt.Sprintf(format, a...))
+}
+
+// Println logs a status message at info level.
+func Println(a ...any) {
+	logMsg(slog.LevelInfo, fmt.Sprint(a...))
+}
+
+// Debugf logs a status message at debug level.
+// Debug messages are only logged with --verbose.
+func Debugf(format string, a ...any) {
+	logMsg(slog.LevelDebug, fmt.Sprintf(format, a...))
+}
+
+// Failf logs a failure at error level.
+// Failures are logged even with --quiet.
+func Failf(format string, a ...any) {
+	logMsg(slog.LevelError, fmt.Sprintf(format, a...))
+}
+
+// Failln logs a failure at error level.
+// Failures are logged even with --quiet.
+func Failln(a ...any) {
+	logMsg(slog.LevelError, fmt.Sprint(a...))
+}
+
+// Status logs the status of the media at path, as the result err of checking it.
+// See StatusLabel.
+func Status(path string, err error) {
+	StatusLabel(path, MsgStatus(err), err)
+}
+
+// StatusLabel logs label as the status of the media at path.
+// The status is a failure, logged at error level, if err is not nil.
+func StatusLabel(path, label string, err error) {
+	level := slog.LevelInfo
+	attrs := []any{"path", path, "status", color.Plain(label)}
+	if err != nil {
+		level = slog.LevelError
+		attrs = append(attrs, "error", err.Error())
+	}
+	slog.Log(context.Background(), level, path+": "+color.Plain(label), attrs...)
+}
+
+// logMsg logs msg at level, with attrs.
+// In the JSON format, msg is stripped of its formatting, symbols, and surrounding whitespace.
+func logMsg(level slog.Level, msg string, attrs ...any) {
+	out.mu.Lock()
+	format := out.format
+	out.mu.Unlock()
+
+	if format == LogFormatJSON {
+		msg = strings.TrimSpace(color.Plain(msg))
+	}
+	slog.Log(context.Background(), level, msg, attrs...)
+}
+
+// statusLabels returns the status labels rendered by the text format.
+func statusLabels() []string {
+	return []string{
+		MsgStatusError, MsgStatusMismatch, MsgStatusMissing, MsgStatusExtra, MsgStatusAdded,
+		MsgStatusRemoved, MsgStatusChanged, MsgStatusMoved, MsgStatusNew, MsgStatusOK,
+		MsgStatusSkipped,
+	}
+}
+
+// textHandler is a slog.Handler logging status messages as human-readable lines.
+// Statuses are logged as "  path: LABEL", and other records as their message.
+type textHandler struct {
+	level slog.Leveler
+}
+
+func (h *textHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *textHandler) Handle(ctx context.Context, record slog.Record) error {
+	var path, status string
+	record.Attrs(func(attr slog.Attr) bool {
+		switch attr.Key {
+		case "path":
+			path = attr.Value.String()
+		case "status":
+			status = attr.Value.String()
+		}
+		return true
+	})
+
+	line := record.Message
+	if status != "" {
+		label := status
+		// Labels may be followed by details, such as the source of a move.
+		for _, l := range statusLabels() {
+			if detail, ok := strings.CutPrefix(status, color.Plain(l)); ok &&
+				(detail == "" || strings.HasPrefix(detail, " ")) {
+				label = l + detail
+				break
+			}
+		}
+		line = fmt.Sprintf("  %s: %s", path, label)
+	}
+
+	_, err := out.Write([]byte(line + "\n"))
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h
+}
+
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	return h
+}
+
+// out is the output of status messages.
+var out = new(output)
+
+// output is the output of status messages, shared by the log and the progress bars.
+// On terminals, progress bars are drawn as an overlay below the logged lines.
+type output struct {
+	mu      sync.Mutex
+	w       io.Writer
+	format  string
+	tty     bool
+	overlay string
+}
+
+// Write writes p above the overlay.
+// Formatting is stripped unless the output is a terminal.
+func (o *output) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.clear()
+	_, err := color.Fprint(o.w, string(p))
+	o.draw()
+	return len(p), err
+}
+
+// overlays reports whether an overlay can be drawn on the output.
+// Overlays are only drawn on terminals, with the text format, and when info messages are logged.
+func (o *output) overlays() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.tty && slog.Default().Enabled(context.Background(), slog.LevelInfo)
+}
+
+// setOverlay replaces the overlay with s.
+func (o *output) setOverlay(s string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.clear()
+	o.overlay = s
+	o.draw()
+}
+
+// clear clears the overlay.
+// The cursor is left at the start of the first line of the overlay.
+func (o *output) clear() {
+	if !o.tty || o.overlay == "" {
+		return
+	}
+	o.w.Write([]byte("\r" + color.ClearLine + strings.Repeat(color.CursorUp+color.ClearLine,
+		strings.Count(o.overlay, "\n"))))
+}
+
+// draw draws the overlay.
+func (o *output) draw() {
+	if !o.tty || o.overlay == "" {
+		return
+	}
+	color.Fprint(o.w, o.overlay)
+}
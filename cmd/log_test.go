@@ -0,0 +1,103 @@
+package cmd_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/cmd"
+	"github.com/ghifari160/medhash-tools/color"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog(t *testing.T) {
+	t.Cleanup(func() {
+		cmd.SetLog(os.Stderr, slog.LevelInfo, cmd.LogFormatText)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		require := require.New(t)
+
+		var buf bytes.Buffer
+		cmd.SetLog(&buf, slog.LevelInfo, cmd.LogFormatText)
+
+		cmd.Printf("Checking MedHash for %s", "dir")
+		cmd.StatusLabel("dir/a", cmd.MsgStatusOK, nil)
+		cmd.Status("dir/b", errors.New("cannot read"))
+		cmd.StatusLabel("dir/c", cmd.MsgStatusMoved+" from dir/d", nil)
+		cmd.Debugf("not logged")
+
+		require.Equal("Checking MedHash for dir\n  dir/a: OK\n  dir/b: ERROR\n  dir/c: MOVED from dir/d\n",
+			buf.String())
+	})
+
+	t.Run("quiet", func(t *testing.T) {
+		require := require.New(t)
+
+		var buf bytes.Buffer
+		cmd.SetLog(&buf, slog.LevelWarn, cmd.LogFormatText)
+
+		cmd.Printf("Checking MedHash for %s", "dir")
+		cmd.StatusLabel("dir/a", cmd.MsgStatusOK, nil)
+		cmd.Status("dir/b", errors.New("cannot read"))
+		cmd.Failln(cmd.MsgFinalError)
+
+		require.Equal("  dir/b: ERROR\nError!\n", buf.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		require := require.New(t)
+
+		var buf bytes.Buffer
+		cmd.SetLog(&buf, slog.LevelDebug, cmd.LogFormatJSON)
+
+		cmd.Debugf("debugging")
+		cmd.Status("dir/b", errors.New("cannot read"))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(lines, 2)
+
+		var record map[string]any
+		require.NoError(json.Unmarshal([]byte(lines[0]), &record))
+		require.Equal("DEBUG", record["level"])
+		require.Equal("debugging", record["msg"])
+
+		record = nil
+		require.NoError(json.Unmarshal([]byte(lines[1]), &record))
+		require.Equal("ERROR", record["level"])
+		require.Equal("dir/b: ERROR", record["msg"])
+		require.Equal("dir/b", record["path"])
+		require.Equal("ERROR", record["status"])
+		require.Equal("cannot read", record["error"])
+	})
+
+	t.Run("theme", func(t *testing.T) {
+		require := require.New(t)
+		t.Cleanup(func() {
+			cmd.SetTheme(color.ThemeDefault)
+		})
+
+		var buf bytes.Buffer
+		cmd.SetTheme(color.ThemeSymbols)
+		cmd.SetLog(&buf, slog.LevelInfo, cmd.LogFormatText)
+
+		cmd.StatusLabel("dir/a", cmd.MsgStatusOK, nil)
+		cmd.Status("dir/b", errors.New("cannot read"))
+		require.Equal("  dir/a: ✔ OK\n  dir/b: ✘ ERROR\n", buf.String())
+
+		// Statuses are logged without symbols in the JSON format.
+		buf.Reset()
+		cmd.SetLog(&buf, slog.LevelInfo, cmd.LogFormatJSON)
+
+		cmd.StatusLabel("dir/a", cmd.MsgStatusSkipped, nil)
+
+		var record map[string]any
+		require.NoError(json.Unmarshal(buf.Bytes(), &record))
+		require.Equal("dir/a: SKIPPED", record["msg"])
+		require.Equal("SKIPPED", record["status"])
+	})
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/ghifari160/medhash-tools/medhash"
+)
+
+// ReadManifest reads the Manifest at manPath.
+// Errors of a missing or invalid Manifest match ErrManifest.
+// Manifests listing child Manifests are invalid unless they are of medhash.ManifestFormatVer.
+func ReadManifest(manPath string) (*medhash.Manifest, error) {
+	manFile, err := os.ReadFile(manPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ManifestError(err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	manifest := new(medhash.Manifest)
+	if err := json.Unmarshal(manFile, manifest); err != nil {
+		return nil, ManifestError(fmt.Errorf("%s: %w", manPath, err))
+	}
+	// Child Manifests were introduced in spec v0.7.0, and are invalid in Manifests of prior
+	// versions.
+	if len(manifest.Manifests) > 0 && manifest.Version != medhash.ManifestFormatVer {
+		return nil, ManifestError(fmt.Errorf("%s: manifest version %s does not support child manifests",
+			manPath, manifest.Version))
+	}
+	return manifest, nil
+}
+
+// MediaRoot returns the media root of the Manifest at manPath.
+// It is the root recorded in the Manifest, or the directory containing the Manifest if no root is
+// recorded.
+func MediaRoot(manPath string) (string, error) {
+	manifest, err := ReadManifest(manPath)
+	if err != nil {
+		return "", err
+	}
+	if manifest.Root != "" {
+		return filepath.FromSlash(manifest.Root), nil
+	}
+	return filepath.Dir(manPath), nil
+}
+
+// ReadMedia reads all media listed by the Manifest at manPath.
+// Media of child Manifests are read recursively, with their path relative to the top-level
+// Manifest.
+func ReadMedia(manPath string) ([]medhash.Media, error) {
+	return readMedia(manPath, "")
+}
+
+func readMedia(manPath, prefix string) ([]medhash.Media, error) {
+	manifest, err := ReadManifest(manPath)
+	if err != nil {
+		return nil, err
+	}
+
+	medias := make([]medhash.Media, 0, len(manifest.Media))
+	for _, med := range manifest.Media {
+		med.Path = path.Join(prefix, med.Path)
+		medias = append(medias, med)
+	}
+
+	dir := filepath.Dir(manPath)
+	for _, child := range manifest.Manifests {
+		childMedias, err := readMedia(filepath.Join(dir, filepath.FromSlash(child.Path)),
+			path.Join(prefix, path.Dir(child.Path)))
+		if err != nil {
+			return nil, err
+		}
+		medias = append(medias, childMedias...)
+	}
+
+	return medias, nil
+}
+
+// VersionError returns the error of the Manifest at manPath being of version instead of
+// medhash.ManifestFormatVer.
+// The error matches ErrManifest.
+func VersionError(manPath, version string) error {
+	return ManifestError(fmt.Errorf("%s: manifest version %s is not %s, upgrade it first", manPath,
+		version, medhash.ManifestFormatVer))
+}
+
+// ManifestError returns an error wrapping err that also matches ErrManifest.
+// The message of err is kept as is.
+func ManifestError(err error) error {
+	return manifestError{err}
+}
+
+// manifestError is an error reading a Manifest.
+type manifestError struct {
+	err error
+}
+
+func (err manifestError) Error() string {
+	return err.err.Error()
+}
+
+func (err manifestError) Unwrap() error {
+	return err.err
+}
+
+func (err manifestError) Is(target error) bool {
+	return target == ErrManifest
+}
+
+// WriteManifest atomically replaces the Manifest at manPath with manifest.
+// manifest is written to a temporary file in the same directory, which is then renamed to manPath,
+// so that readers never observe a partially written Manifest.
+func WriteManifest(manifest *medhash.Manifest, manPath string) error {
+	manFile, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(manPath), "."+filepath.Base(manPath)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(manFile); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), manPath)
+}
+
+// MediaConfig returns the medhash.Config with the algorithms of the media listed by manifest.
+// If manifest does not list any hash, medhash.DefaultConfig is returned.
+func MediaConfig(manifest *medhash.Manifest) medhash.Config {
+	var config medhash.Config
+	for _, med := range manifest.Media {
+		for _, alg := range medhash.Algorithms() {
+			if med.Hash.Get(alg) != "" {
+				config.Toggle(alg, true)
+			}
+		}
+	}
+	if len(config.Algorithms()) < 1 {
+		return medhash.DefaultConfig
+	}
+	return config
+}
@@ -0,0 +1,34 @@
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/cmd"
+	"github.com/ghifari160/medhash-tools/cmd/gen"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/ghifari160/medhash-tools/testcommon"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadMedia(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	require.NoError(os.Mkdir(sub, 0755))
+	testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+	testcommon.GenPayload(t, sub, testcommon.PayloadSize())
+
+	conf := medhash.DefaultConfig
+	conf.Dir = dir
+	require.NoError(gen.GenPerDirFunc(conf, []string{medhash.DefaultManifestName}))
+
+	medias, err := cmd.ReadMedia(filepath.Join(dir, medhash.DefaultManifestName))
+	require.NoError(err)
+	require.Len(medias, 2)
+	require.Equal("payload", medias[0].Path)
+	require.Equal("sub/payload", medias[1].Path)
+}
@@ -0,0 +1,195 @@
+package pack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ghifari160/medhash-tools/archive"
+	"github.com/ghifari160/medhash-tools/cmd"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/urfave/cli/v3"
+)
+
+func init() {
+	cmd.RegisterCmd(CommandPack())
+}
+
+func CommandPack() *cli.Command {
+	return &cli.Command{
+		Name:      "pack",
+		Usage:     "pack a directory into an archive with an embedded MedHash Manifest",
+		ArgsUsage: "<dir>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "output",
+				Aliases:  []string{"o"},
+				Usage:    "write archive to this path",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "archive format (tar, tar.gz, zip); detected from the output name if unset",
+			},
+			&cli.StringSliceFlag{
+				Name:    "ignore",
+				Aliases: []string{"i"},
+				Usage:   "ignore patterns",
+			},
+			&cli.StringFlag{
+				Name:    "manifest",
+				Aliases: []string{"m"},
+				Usage:   "manifest file name",
+				Value:   medhash.DefaultManifestName,
+			},
+		},
+		MutuallyExclusiveFlags: []cli.MutuallyExclusiveFlags{
+			{
+				Flags: [][]cli.Flag{
+					{
+						&cli.BoolFlag{
+							Name:  "default",
+							Usage: "use default preset",
+							Value: true,
+						},
+					},
+					{
+						&cli.BoolFlag{
+							Name:  "all",
+							Usage: "use all algorithms",
+						},
+					},
+					cmd.HashAlgs(),
+				},
+			},
+		},
+		Action: PackAction,
+	}
+}
+
+func PackAction(ctx context.Context, command *cli.Command) error {
+	config := cmd.HashConfig(command)
+	config.Manifest = command.String("manifest")
+
+	if command.Args().Len() != 1 {
+		return cli.Exit("pack requires exactly one target directory", cmd.ExitUsage)
+	}
+	config.Dir = command.Args().First()
+
+	output := command.String("output")
+	format := archive.Format(command.String("format"))
+	if format == "" {
+		format = archive.FormatFromName(output)
+	}
+
+	ignores := cmd.IgnoreManifest(command.StringSlice("ignore"), config.Manifest)
+
+	cmd.Printf("Packing MedHash for %s into %s", config.Dir, output)
+
+	errs := PackFunc(config, output, format, ignores)
+	return cmd.Result(errs)
+}
+
+// PackFunc packs config.Dir into an archive of format at output.
+// Each media is hashed as it is streamed into the archive, so that it is only read once.
+// The Manifest is appended to the archive as its final member, named config.Manifest.
+func PackFunc(config medhash.Config, output string, format archive.Format, ignores []string) error {
+	manifest, err := medhash.NewWithConfig(config)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// The archive is never packed into itself.
+	if rel, inside := cmd.RelToDir(config.Dir, output); inside {
+		ignores = append(ignores, rel)
+	}
+
+	aw, err := archive.NewWriter(f, format)
+	if err != nil {
+		return err
+	}
+
+	var errs error
+	err = filepath.Walk(config.Dir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			err = fmt.Errorf("cannot access %s: %w", path, err)
+			cmd.StatusLabel(path, cmd.MsgStatusError, err)
+			errs = cmd.JoinErrors(errs, err)
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(config.Dir, path)
+		if err != nil {
+			cmd.StatusLabel(path, cmd.MsgStatusError, err)
+			errs = cmd.JoinErrors(errs, err)
+			return nil
+		}
+
+		if skip, err := cmd.Ignored(rel, ignores); err != nil {
+			cmd.StatusLabel(path, cmd.MsgStatusError, err)
+			errs = cmd.JoinErrors(errs, err)
+			return nil
+		} else if skip {
+			cmd.StatusLabel(path, cmd.MsgStatusSkipped, nil)
+			return nil
+		}
+
+		err = packMedia(manifest, aw, rel, info)
+		if err != nil {
+			cmd.StatusLabel(path, cmd.MsgStatusError, err)
+			// The archive is unusable once a member is partially written.
+			return err
+		}
+
+		cmd.StatusLabel(path, cmd.MsgStatusOK, nil)
+		return nil
+	})
+	if err != nil {
+		return cmd.JoinErrors(errs, err)
+	}
+
+	manFile, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return cmd.JoinErrors(errs, err)
+	}
+
+	w, err := aw.Create(config.Manifest, int64(len(manFile)), 0644, time.Now())
+	if err != nil {
+		return cmd.JoinErrors(errs, err)
+	}
+	if _, err := w.Write(manFile); err != nil {
+		return cmd.JoinErrors(errs, err)
+	}
+
+	return cmd.JoinErrors(errs, aw.Close())
+}
+
+// packMedia streams the media rel into aw, and adds it to manifest.
+func packMedia(manifest *medhash.Manifest, aw *archive.Writer, rel string, info fs.FileInfo) error {
+	f, err := os.Open(filepath.Join(manifest.Config.Dir, rel))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w, err := aw.Create(filepath.ToSlash(rel), info.Size(), info.Mode(), info.ModTime())
+	if err != nil {
+		return err
+	}
+
+	return manifest.AddReader(rel, io.TeeReader(f, w))
+}
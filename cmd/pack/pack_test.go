@@ -0,0 +1,83 @@
+package pack_test
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/archive"
+	"github.com/ghifari160/medhash-tools/cmd/pack"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/ghifari160/medhash-tools/testcommon"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPack(t *testing.T) {
+	t.Parallel()
+
+	cases := []testcommon.TestCase{
+		testcommon.Case("tar", "tar"),
+		testcommon.Case("tar.gz", "tar.gz"),
+		testcommon.Case("zip", "zip"),
+	}
+
+	testcommon.RunCases(t, testPack, cases)
+}
+
+func testPack(t *testing.T, format string, opts ...testcommon.Options) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+	archivePath := filepath.Join(t.TempDir(), "delivery."+format)
+
+	command := pack.CommandPack()
+	err := command.Run(t.Context(), []string{"pack", "--all", "--output", archivePath, dir})
+	require.NoError(err)
+
+	var names []string
+	var manifest medhash.Manifest
+	err = archive.Walk(archivePath, func(name string, r io.Reader) error {
+		names = append(names, name)
+		if name == medhash.DefaultManifestName {
+			return json.NewDecoder(r).Decode(&manifest)
+		}
+		return nil
+	})
+	require.NoError(err)
+	require.Equal([]string{payload.Path, medhash.DefaultManifestName}, names)
+
+	require.Len(manifest.Media, 1)
+	require.Equal(payload.Path, manifest.Media[0].Path)
+	for _, alg := range medhash.AllConfig.Algorithms() {
+		require.Equal(payload.Hash.Get(alg), manifest.Media[0].Hash.Get(alg))
+	}
+}
+
+func TestPackInside(t *testing.T) {
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+	t.Chdir(dir)
+
+	// The relative target directory contains the absolute output.
+	config := medhash.DefaultConfig
+	config.Dir = "."
+	config.Manifest = medhash.DefaultManifestName
+	archivePath := filepath.Join(dir, "delivery.tar")
+	require.NoError(pack.PackFunc(config, archivePath, archive.FormatTar, nil))
+
+	var names []string
+	err := archive.Walk(archivePath, func(name string, r io.Reader) error {
+		names = append(names, name)
+		return nil
+	})
+	require.NoError(err)
+	require.Equal([]string{payload.Path, medhash.DefaultManifestName}, names)
+
+	err = pack.PackFunc(config, filepath.Join(t.TempDir(), "delivery.tar"), archive.FormatTar,
+		[]string{"["})
+	require.ErrorIs(err, filepath.ErrBadPattern)
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ghifari160/medhash-tools/color"
+)
+
+// DefaultProgressInterval is the default interval between progress messages when the progress bars
+// cannot be drawn.
+const DefaultProgressInterval = 10 * time.Second
+
+const (
+	// progressRedraw is the minimum interval between redraws of the progress bars.
+	progressRedraw = 100 * time.Millisecond
+	// progressBarWidth is the width of the progress bars.
+	progressBarWidth = 16
+	// progressNameWidth is the maximum width of the media name next to the progress bars.
+	progressNameWidth = 30
+)
+
+// Progress reports the progress of reading media, with a total of bytes and files computed ahead.
+// When status messages are logged to a terminal, Progress draws a progress bar for the current
+// media and another for the total below them.
+// Otherwise, Progress logs the progress every Interval.
+// Progress is safe for concurrent use.
+type Progress struct {
+	// Interval is the interval between progress messages when the progress bars cannot be drawn.
+	Interval time.Duration
+
+	mu    sync.Mutex
+	tty   bool
+	start time.Time
+	last  time.Time
+
+	total     int64
+	files     int
+	done      int64
+	doneFiles int
+
+	media string
+	size  int64
+	read  int64
+}
+
+// NewProgress returns a Progress of reading files media, totalling total bytes.
+func NewProgress(total int64, files int) *Progress {
+	now := time.Now()
+	return &Progress{
+		Interval: DefaultProgressInterval,
+		tty:      out.overlays(),
+		start:    now,
+		last:     now,
+		total:    total,
+		files:    files,
+	}
+}
+
+// Start starts reporting the progress of reading media, of size bytes.
+func (p *Progress) Start(media string, size int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.media = media
+	p.size = size
+	p.read = 0
+	p.render(true)
+}
+
+// Update updates the progress of reading media to read bytes.
+// Update is a medhash.ProgressFunc.
+func (p *Progress) Update(media string, read int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if media != p.media {
+		return
+	}
+	p.read = read
+	p.render(false)
+}
+
+// Finish finishes reading the current media.
+func (p *Progress) Finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.media == "" {
+		return
+	}
+	p.done += p.size
+	p.doneFiles++
+	p.media = ""
+	p.size = 0
+	p.read = 0
+}
+
+// Close clears the progress bars.
+func (p *Progress) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.tty {
+		out.setOverlay("")
+	}
+}
+
+// String returns the overall progress, such as
+// "12.0% (3.1 GiB of 25.0 GiB, 3/10 files), 120.0 MiB/s, ETA 3m12s".
+func (p *Progress) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.line(time.Now())
+}
+
+// line returns the overall progress at now.
+func (p *Progress) line(now time.Time) string {
+	read := p.done + p.read
+	rate, eta := p.rate(now)
+	return fmt.Sprintf("%.1f%% (%s of %s, %d/%d files), %s/s, ETA %s", percent(read, p.total),
+		FormatSize(read), FormatSize(p.total), p.doneFiles, p.files, FormatSize(rate), eta)
+}
+
+// rate returns the overall rate in bytes per second, and the estimated time remaining at now.
+func (p *Progress) rate(now time.Time) (rate int64, eta string) {
+	elapsed := now.Sub(p.start)
+	read := p.done + p.read
+	if elapsed <= 0 || read <= 0 {
+		return 0, "--"
+	}
+
+	rate = int64(float64(read) / elapsed.Seconds())
+	if rate <= 0 {
+		return 0, "--"
+	}
+	remaining := max(p.total-read, 0)
+	return rate, (time.Duration(remaining/rate) * time.Second).String()
+}
+
+// render renders the progress.
+// Unless force is true, the progress is only rendered if it was not rendered recently.
+func (p *Progress) render(force bool) {
+	now := time.Now()
+
+	if !p.tty {
+		if now.Sub(p.last) >= p.Interval {
+			p.last = now
+			rate, _ := p.rate(now)
+			logMsg(slog.LevelInfo, "  Progress: "+p.line(now), "read", p.done+p.read, "total", p.total,
+				"files", p.doneFiles, "total_files", p.files, "rate", rate)
+		}
+		return
+	}
+
+	if !force && now.Sub(p.last) < progressRedraw {
+		return
+	}
+	p.last = now
+
+	var b strings.Builder
+	if p.media != "" {
+		fmt.Fprintf(&b, "  [%d/%d] %s %s %5.1f%%\n", p.doneFiles+1, p.files, truncate(p.media),
+			bar(p.read, p.size), percent(p.read, p.size))
+	} else {
+		b.WriteString("\n")
+	}
+
+	read := p.done + p.read
+	rate, eta := p.rate(now)
+	fmt.Fprintf(&b, "  Total %s %5.1f%% %s/%s %s/s ETA %s", bar(read, p.total), percent(read, p.total),
+		FormatSize(read), FormatSize(p.total), FormatSize(rate), eta)
+
+	out.setOverlay(b.String())
+}
+
+// percent returns n as a percentage of total.
+func percent(n, total int64) float64 {
+	if total <= 0 {
+		return 100
+	}
+	return min(float64(n)/float64(total)*100, 100)
+}
+
+// bar returns a progress bar of n out of total.
+func bar(n, total int64) string {
+	filled := progressBarWidth
+	if total > 0 {
+		filled = int(min(n*progressBarWidth/total, progressBarWidth))
+	}
+	// The filled part is formatted as a success label of the current theme.
+	return "[" + color.CurrentTheme().Formats[color.KindSuccess] + strings.Repeat("#", filled) +
+		color.Reset + color.Gray + strings.Repeat("-", progressBarWidth-filled) + color.Reset + "]"
+}
+
+// truncate truncates name to progressNameWidth, keeping its end.
+func truncate(name string) string {
+	if utf8.RuneCountInString(name) <= progressNameWidth {
+		return name
+	}
+	runes := []rune(name)
+	return "..." + string(runes[len(runes)-progressNameWidth+3:])
+}
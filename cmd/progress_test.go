@@ -0,0 +1,35 @@
+package cmd_test
+
+import (
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/cmd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgress(t *testing.T) {
+	require := require.New(t)
+
+	p := cmd.NewProgress(2048, 2)
+	require.Contains(p.String(), "0.0% (0 B of 2.0 KiB, 0/2 files)")
+	require.Contains(p.String(), "ETA --")
+
+	p.Start("a", 1024)
+	p.Update("a", 512)
+	require.Contains(p.String(), "25.0% (512 B of 2.0 KiB, 0/2 files)")
+
+	// Updates of other media are ignored.
+	p.Update("b", 1024)
+	require.Contains(p.String(), "25.0% (512 B of 2.0 KiB, 0/2 files)")
+
+	p.Update("a", 1024)
+	p.Finish()
+	p.Start("b", 1024)
+	require.Contains(p.String(), "50.0% (1.0 KiB of 2.0 KiB, 1/2 files)")
+
+	p.Update("b", 1024)
+	p.Finish()
+	require.Contains(p.String(), "100.0% (2.0 KiB of 2.0 KiB, 2/2 files)")
+	require.Contains(p.String(), "ETA 0s")
+	p.Close()
+}
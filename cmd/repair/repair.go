@@ -0,0 +1,326 @@
+package repair
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ghifari160/medhash-tools/cmd"
+	"github.com/ghifari160/medhash-tools/color"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/urfave/cli/v3"
+)
+
+func init() {
+	cmd.RegisterCmd(CommandRepair())
+}
+
+func CommandRepair() *cli.Command {
+	return &cli.Command{
+		Name:      "repair",
+		Usage:     "update the paths of moved media in a manifest",
+		ArgsUsage: "[dir]",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:    "ignore",
+				Aliases: []string{"i"},
+				Usage:   "ignore patterns",
+			},
+			&cli.StringFlag{
+				Name:    "manifest",
+				Aliases: []string{"m"},
+				Usage:   "use this manifest",
+			},
+			&cli.BoolFlag{
+				Name:    "yes",
+				Aliases: []string{"y"},
+				Usage:   "rewrite the manifest without confirmation",
+			},
+		},
+		Action: RepairAction,
+	}
+}
+
+func RepairAction(ctx context.Context, command *cli.Command) error {
+	manPath := command.String("manifest")
+	switch command.Args().Len() {
+	case 0:
+		if manPath == "" {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return cli.Exit(fmt.Errorf("cannot get working directory: %w", err), cmd.ExitIO)
+			}
+			manPath = filepath.Join(cwd, medhash.DefaultManifestName)
+		}
+	case 1:
+		if manPath == "" {
+			manPath = filepath.Join(command.Args().First(), medhash.DefaultManifestName)
+		}
+	default:
+		return cli.Exit("repair accepts at most one target directory", cmd.ExitUsage)
+	}
+
+	cmd.Printf("Repairing MedHash for %s", manPath)
+
+	plan, err := PlanFunc(manPath, command.StringSlice("ignore"))
+	if err != nil {
+		return cmd.Result(err)
+	}
+
+	for _, move := range plan.Moves {
+		cmd.Printf("  %s: %s to %s", move.From, cmd.MsgStatusMoved, move.To)
+	}
+	var errs error
+	for _, med := range plan.Missing {
+		err := fmt.Errorf("%s: %w", med.Path, fs.ErrNotExist)
+		cmd.Status(med.Path, err)
+		errs = cmd.JoinErrors(errs, err)
+	}
+
+	if len(plan.Moves) < 1 {
+		cmd.Println("Nothing to repair")
+		return cmd.Result(errs)
+	}
+
+	if !command.Bool("yes") {
+		// The prompt is written to the standard error, along with the status messages, but is never
+		// silenced.
+		color.Fprintf(command.Root().ErrWriter, "Update %d paths in %s? [y/N] ", len(plan.Moves), manPath)
+		answer, _ := bufio.NewReader(command.Root().Reader).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			cmd.Failln("Manifest not updated")
+			return cli.Exit("", cmd.ExitFailure)
+		}
+	}
+
+	errs = cmd.JoinErrors(errs, ApplyFunc(manPath, plan.Moves))
+	return cmd.Result(errs)
+}
+
+// Plan is the proposed repair of a Manifest.
+type Plan struct {
+	// Moves lists the path updates of the missing media found elsewhere.
+	Moves []medhash.Move
+	// Missing lists the missing media that were not found.
+	Missing []medhash.Media
+}
+
+// filterOrder lists the Hash field names of all supported algorithms, from the cheapest to the
+// most expensive to generate.
+var filterOrder = []string{"xxh3", "crc32", "md5", "sha1", "sha512", "sha256", "sha3"}
+
+// PlanFunc finds the media listed by the Manifest at manPath that are missing from its media root,
+// among the files of the media root that the Manifest does not list.
+// Unlisted files are hashed from the smallest, with the cheapest algorithm of the missing media,
+// until every missing media is found.
+// Only candidates matching the cheapest hash of a missing media are verified against all its
+// hashes.
+// Only the media of the top-level Manifest are repaired; child Manifests are skipped.
+func PlanFunc(manPath string, ignores []string) (Plan, error) {
+	manifest, err := cmd.ReadManifest(manPath)
+	if err != nil {
+		return Plan{}, err
+	}
+	root, err := cmd.MediaRoot(manPath)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	listed := make(map[string]bool, len(manifest.Media))
+	children := make(map[string]bool, len(manifest.Manifests))
+	var missing []medhash.Media
+	for _, med := range manifest.Media {
+		listed[med.Path] = true
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(med.Path))); err != nil {
+			missing = append(missing, med)
+		}
+	}
+	for _, child := range manifest.Manifests {
+		children[path.Dir(child.Path)] = true
+	}
+	if abs, err := filepath.Abs(manPath); err == nil {
+		if rel, err := filepath.Rel(root, abs); err == nil {
+			ignores = cmd.IgnoreManifest(ignores, rel)
+		}
+	}
+
+	plan := Plan{Moves: make([]medhash.Move, 0), Missing: make([]medhash.Media, 0)}
+	if len(missing) < 1 {
+		return plan, nil
+	}
+
+	// Each missing media is filtered by its cheapest hash.
+	var filter medhash.Config
+	filter.Dir = root
+	filterAlgs := make([]string, len(missing))
+	for i, med := range missing {
+		for _, alg := range filterOrder {
+			if med.Hash.Get(alg) != "" {
+				filterAlgs[i] = alg
+				filter.Toggle(alg, true)
+				break
+			}
+		}
+	}
+
+	// Missing media are indexed by their cheapest hash.
+	byDigest := make(map[string][]int)
+	remaining := 0
+	for i, med := range missing {
+		if alg := filterAlgs[i]; alg != "" {
+			byDigest[alg+":"+med.Hash.Get(alg)] = append(byDigest[alg+":"+med.Hash.Get(alg)], i)
+			remaining++
+		}
+	}
+
+	unlisted, err := walk(root, listed, children, ignores)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	var errs error
+	found := make([]bool, len(missing))
+	for _, candidate := range unlisted {
+		if remaining < 1 {
+			break
+		}
+
+		f, err := os.Open(filepath.Join(root, filepath.FromSlash(candidate.path)))
+		if err != nil {
+			errs = cmd.JoinErrors(errs, err)
+			continue
+		}
+		sum, err := medhash.HashReader(filter, f)
+		f.Close()
+		if err != nil {
+			errs = cmd.JoinErrors(errs, fmt.Errorf("%s: %w", candidate.path, err))
+			continue
+		}
+
+		if i, ok := match(root, missing, byDigest, found, candidate.path, sum); ok {
+			move := medhash.Move{From: missing[i].Path, To: candidate.path, Hash: missing[i].Hash}
+			plan.Moves = append(plan.Moves, move)
+			found[i] = true
+			remaining--
+		}
+	}
+	if errs != nil {
+		return Plan{}, errs
+	}
+
+	slices.SortFunc(plan.Moves, func(a, b medhash.Move) int {
+		return strings.Compare(a.From, b.From)
+	})
+	for i, med := range missing {
+		if !found[i] {
+			plan.Missing = append(plan.Missing, med)
+		}
+	}
+
+	return plan, nil
+}
+
+// match returns the index of the first missing media not yet found that the candidate at rel in
+// root matches.
+// sum is the hash of the candidate, generated with the algorithms indexed by byDigest.
+func match(root string, missing []medhash.Media, byDigest map[string][]int, found []bool,
+	rel string, sum medhash.Hash) (int, bool) {
+	for _, alg := range filterOrder {
+		digest := sum.Get(alg)
+		if digest == "" {
+			continue
+		}
+		for _, i := range byDigest[alg+":"+digest] {
+			if !found[i] && verify(root, missing[i].Hash, rel) {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// ApplyFunc rewrites the Manifest at manPath with the paths of moves updated.
+// The media remain sorted by path.
+func ApplyFunc(manPath string, moves []medhash.Move) error {
+	manifest, err := cmd.ReadManifest(manPath)
+	if err != nil {
+		return err
+	}
+
+	paths := make(map[string]string, len(moves))
+	for _, move := range moves {
+		paths[move.From] = move.To
+	}
+	if err := manifest.Rename(paths); err != nil {
+		return err
+	}
+
+	return cmd.WriteManifest(manifest, manPath)
+}
+
+// file is a regular file found by walk.
+type file struct {
+	path string
+	size int64
+}
+
+// walk returns the regular files in root that are neither listed nor ignored, sorted by size, then
+// path.
+// The directories of children are skipped, as their files are listed by child Manifests.
+func walk(root string, listed, children map[string]bool, ignores []string) ([]file, error) {
+	var files []file
+	var errs error
+	err := filepath.Walk(root, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			errs = cmd.JoinErrors(errs, fmt.Errorf("cannot access %s: %w", path, err))
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			errs = cmd.JoinErrors(errs, err)
+			return nil
+		}
+		if info.IsDir() && children[filepath.ToSlash(rel)] {
+			return filepath.SkipDir
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		for _, ignore := range ignores {
+			if matched, _ := filepath.Match(ignore, rel); matched {
+				return nil
+			}
+		}
+
+		if rel = filepath.ToSlash(rel); !listed[rel] {
+			files = append(files, file{path: rel, size: info.Size()})
+		}
+		return nil
+	})
+	slices.SortFunc(files, func(a, b file) int {
+		if c := cmp.Compare(a.size, b.size); c != 0 {
+			return c
+		}
+		return strings.Compare(a.path, b.path)
+	})
+	return files, cmd.JoinErrors(errs, err)
+}
+
+// verify reports whether the file at rel in root matches all hashes of hash.
+func verify(root string, hash medhash.Hash, rel string) bool {
+	config := medhash.Config{Dir: root}
+	for _, alg := range medhash.Algorithms() {
+		if hash.Get(alg) != "" {
+			config.Toggle(alg, true)
+		}
+	}
+	return medhash.Media{Path: rel, Hash: hash}.Check(config) == nil
+}
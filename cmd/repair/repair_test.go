@@ -0,0 +1,108 @@
+package repair_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/cmd/gen"
+	"github.com/ghifari160/medhash-tools/cmd/repair"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/ghifari160/medhash-tools/testcommon"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+func TestRepair(t *testing.T) {
+	t.Parallel()
+
+	t.Run("yes", func(t *testing.T) {
+		testRepair(t, []string{"--yes"}, "", true)
+	})
+
+	t.Run("confirm", func(t *testing.T) {
+		testRepair(t, nil, "y\n", true)
+	})
+
+	t.Run("decline", func(t *testing.T) {
+		testRepair(t, nil, "n\n", false)
+	})
+}
+
+func testRepair(t *testing.T, flags []string, answer string, repaired bool) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+	require.NoError(os.WriteFile(filepath.Join(dir, "gone"), []byte("gone"), 0644))
+
+	conf := medhash.AllConfig
+	conf.Dir = dir
+	conf.Manifest = medhash.DefaultManifestName
+	require.NoError(gen.GenFunc(conf, []string{medhash.DefaultManifestName}))
+
+	require.NoError(os.Mkdir(filepath.Join(dir, "moved"), 0755))
+	require.NoError(os.Rename(filepath.Join(dir, payload.Path), filepath.Join(dir, "moved", payload.Path)))
+	require.NoError(os.Remove(filepath.Join(dir, "gone")))
+	require.NoError(os.WriteFile(filepath.Join(dir, "unrelated"), []byte("unrelated"), 0644))
+
+	plan, err := repair.PlanFunc(conf.ManifestPath(), nil)
+	require.NoError(err)
+	require.Len(plan.Moves, 1)
+	require.Equal(payload.Path, plan.Moves[0].From)
+	require.Equal("moved/"+payload.Path, plan.Moves[0].To)
+	require.Len(plan.Missing, 1)
+	require.Equal("gone", plan.Missing[0].Path)
+
+	command := repair.CommandRepair()
+	command.Reader = strings.NewReader(answer)
+	command.ExitErrHandler = func(ctx context.Context, c *cli.Command, err error) {}
+	err = command.Run(t.Context(), append(append([]string{"repair"}, flags...), dir))
+	// The missing media is still reported.
+	require.Error(err)
+
+	manifest := testcommon.ReadManifest(t, conf.ManifestPath())
+	paths := make([]string, 0, len(manifest.Media))
+	for _, med := range manifest.Media {
+		paths = append(paths, med.Path)
+	}
+	if repaired {
+		require.Equal([]string{"gone", "moved/" + payload.Path}, paths)
+	} else {
+		require.Equal([]string{"gone", payload.Path}, paths)
+	}
+}
+
+func TestPlanDuplicates(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	require.NoError(os.WriteFile(filepath.Join(dir, "a"), []byte("same"), 0644))
+	require.NoError(os.WriteFile(filepath.Join(dir, "b"), []byte("same"), 0644))
+	require.NoError(os.WriteFile(filepath.Join(dir, "c"), []byte("other"), 0644))
+
+	conf := medhash.DefaultConfig
+	conf.Dir = dir
+	conf.Manifest = medhash.DefaultManifestName
+	require.NoError(gen.GenFunc(conf, []string{medhash.DefaultManifestName}))
+
+	require.NoError(os.Mkdir(filepath.Join(dir, "moved"), 0755))
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(os.Rename(filepath.Join(dir, name), filepath.Join(dir, "moved", name)))
+	}
+
+	// Identical media are each paired with a distinct copy.
+	plan, err := repair.PlanFunc(conf.ManifestPath(), nil)
+	require.NoError(err)
+	require.Empty(plan.Missing)
+	require.Len(plan.Moves, 3)
+	require.Equal("a", plan.Moves[0].From)
+	require.Equal("b", plan.Moves[1].From)
+	require.Equal("c", plan.Moves[2].From)
+	require.Equal("moved/c", plan.Moves[2].To)
+	require.ElementsMatch([]string{"moved/a", "moved/b"}, []string{plan.Moves[0].To, plan.Moves[1].To})
+}
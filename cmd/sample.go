@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zeebo/xxh3"
+)
+
+// Sample selects a deterministic subset of media.
+// Media are spread over a ring by the hash of their path, and a Sample selects a window of the ring
+// covering Fraction of it.
+// The window of each Seed follows the window of the previous one, so that samples of consecutive
+// dates cover every media within 1/Fraction days, and the same media are selected for the same Seed,
+// so that verification can be resumed.
+type Sample struct {
+	// Fraction is the fraction of media selected, between 0 and 1.
+	Fraction float64
+	// Seed selects the window of the sample.
+	// Dates (e.g. "2006-01-02") select the window of that day; other values select a window by their
+	// hash.
+	Seed string
+}
+
+// NewSample returns a Sample of percent (e.g. "5%") seeded by seed.
+// If seed is empty, the sample is seeded by the current date, so that the next window is selected
+// every day.
+func NewSample(percent, seed string) (Sample, error) {
+	p, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(percent), "%")), 64)
+	if err != nil || math.IsNaN(p) || p <= 0 || p > 100 {
+		return Sample{}, fmt.Errorf("invalid sample %q: must be a percentage between 0%% and 100%%", percent)
+	}
+
+	if seed == "" {
+		seed = time.Now().Format(time.DateOnly)
+	}
+	return Sample{Fraction: p / 100, Seed: seed}, nil
+}
+
+// Selected reports whether the media at path is selected.
+func (s Sample) Selected(path string) bool {
+	width := math.Ceil(s.Fraction * (1 << 64))
+	if width >= 1<<64 {
+		return true
+	}
+	w := uint64(width)
+
+	// The ring wraps around, as does uint64 arithmetic.
+	start := s.round() * w
+	return xxh3.HashString(path)-start < w
+}
+
+// round returns the index of the window selected by s.Seed.
+func (s Sample) round() uint64 {
+	if day, err := time.Parse(time.DateOnly, s.Seed); err == nil {
+		return uint64(day.Unix() / (24 * 60 * 60))
+	}
+	return xxh3.HashString(s.Seed)
+}
@@ -0,0 +1,73 @@
+package cmd_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ghifari160/medhash-tools/cmd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSample(t *testing.T) {
+	require := require.New(t)
+
+	a, err := cmd.NewSample("5%", "a")
+	require.NoError(err)
+	require.Equal(0.05, a.Fraction)
+	b, err := cmd.NewSample("5", "b")
+	require.NoError(err)
+
+	var selectedA, selectedB, both int
+	for i := range 10000 {
+		p := fmt.Sprintf("media/%d.mov", i)
+		require.Equal(a.Selected(p), a.Selected(p))
+		if a.Selected(p) {
+			selectedA++
+		}
+		if b.Selected(p) {
+			selectedB++
+		}
+		if a.Selected(p) && b.Selected(p) {
+			both++
+		}
+	}
+	require.InDelta(500, selectedA, 100)
+	require.InDelta(500, selectedB, 100)
+	// Different seeds select different media.
+	require.Less(both, 100)
+
+	all, err := cmd.NewSample("100%", "")
+	require.NoError(err)
+	require.NotEmpty(all.Seed)
+	require.True(all.Selected("media/0.mov"))
+
+	for _, s := range []string{"", "0%", "101%", "-5%", "five", "NaN%"} {
+		_, err := cmd.NewSample(s, "")
+		assert.Error(t, err, s)
+	}
+}
+
+func TestSampleRotation(t *testing.T) {
+	require := require.New(t)
+
+	// Samples of 20 consecutive days select every media exactly once.
+	start := time.Date(2024, 12, 25, 0, 0, 0, 0, time.UTC)
+	selected := make(map[string]int)
+	for day := range 20 {
+		sample, err := cmd.NewSample("5%", start.AddDate(0, 0, day).Format(time.DateOnly))
+		require.NoError(err)
+
+		for i := range 10000 {
+			p := fmt.Sprintf("media/%d.mov", i)
+			if sample.Selected(p) {
+				selected[p]++
+			}
+		}
+	}
+	require.Len(selected, 10000)
+	for p, n := range selected {
+		require.Equal(1, n, p)
+	}
+}
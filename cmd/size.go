@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var sizeUnits = []string{"KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+// FormatSize formats n bytes with binary prefixes (e.g. "1.5 GiB").
+func FormatSize(n int64) string {
+	if n < 1024 && n > -1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	size := float64(n) / 1024
+	unit := 0
+	for (size >= 1024 || size <= -1024) && unit < len(sizeUnits)-1 {
+		size /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %s", size, sizeUnits[unit])
+}
+
+// rateUnits maps the units accepted by ParseRate to their size in bytes.
+var rateUnits = map[string]float64{
+	"":    1,
+	"B":   1,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  1e12,
+	"KIB": 1 << 10,
+	"MIB": 1 << 20,
+	"GIB": 1 << 30,
+	"TIB": 1 << 40,
+}
+
+// ParseRate parses a rate in bytes per second, such as "200MB/s" or "1.5 GiB/s".
+// Units are case-insensitive, with decimal (KB, MB, GB, TB) and binary (KiB, MiB, GiB, TiB)
+// prefixes.
+// The "/s" suffix is optional.
+// It is an error for the rate to be below 1 B/s.
+func ParseRate(s string) (int64, error) {
+	rate := strings.ToUpper(strings.TrimSpace(s))
+	rate = strings.TrimSpace(strings.TrimSuffix(rate, "/S"))
+
+	i := strings.IndexFunc(rate, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(rate)
+	}
+
+	n, err := strconv.ParseFloat(rate[:i], 64)
+	unit, ok := rateUnits[strings.TrimSpace(rate[i:])]
+	if err != nil || !ok || n <= 0 || n*unit > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	// A rate of 0 disables the limiter, so rates truncated to 0 are rejected.
+	if n*unit < 1 {
+		return 0, fmt.Errorf("rate %q is below 1 B/s", s)
+	}
+	return int64(n * unit), nil
+}
@@ -0,0 +1,50 @@
+package cmd_test
+
+import (
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/cmd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatSize(t *testing.T) {
+	cases := map[int64]string{
+		0:                   "0 B",
+		1023:                "1023 B",
+		1024:                "1.0 KiB",
+		1536:                "1.5 KiB",
+		300 * 1024 * 1024:   "300.0 MiB",
+		5 << 40:             "5.0 TiB",
+		9223372036854775807: "8.0 EiB",
+	}
+
+	for n, want := range cases {
+		assert.Equal(t, want, cmd.FormatSize(n))
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	cases := map[string]int64{
+		"200MB/s":   200e6,
+		"200mb/s":   200e6,
+		"1.5 GiB/s": 1536 << 20,
+		"512KiB":    512 << 10,
+		"100":       100,
+		"64 B/s":    64,
+		" 2 TB/s ":  2e12,
+		"0.5 MiB/s": 512 << 10,
+	}
+
+	for s, want := range cases {
+		rate, err := cmd.ParseRate(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, want, rate, s)
+	}
+
+	for _, s := range []string{"", "MB/s", "0MB/s", "-1MB/s", "10 parsecs", "1.2.3MB", "0.5",
+		"0.9 B/s"} {
+		_, err := cmd.ParseRate(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -0,0 +1,199 @@
+package unpack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghifari160/medhash-tools/archive"
+	"github.com/ghifari160/medhash-tools/cmd"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/urfave/cli/v3"
+)
+
+func init() {
+	cmd.RegisterCmd(CommandUnpack())
+}
+
+func CommandUnpack() *cli.Command {
+	return &cli.Command{
+		Name:      "unpack",
+		Usage:     "extract an archive while verifying its embedded MedHash Manifest",
+		ArgsUsage: "<archive> [dir]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "manifest",
+				Aliases: []string{"m"},
+				Usage:   "manifest file name",
+				Value:   medhash.DefaultManifestName,
+			},
+		},
+		MutuallyExclusiveFlags: []cli.MutuallyExclusiveFlags{
+			{
+				Flags: [][]cli.Flag{
+					{
+						&cli.BoolFlag{
+							Name:  "default",
+							Usage: "use default preset",
+							Value: true,
+						},
+					},
+					{
+						&cli.BoolFlag{
+							Name:  "all",
+							Usage: "use all algorithms",
+						},
+					},
+					cmd.HashAlgs(),
+				},
+			},
+		},
+		Action: UnpackAction,
+	}
+}
+
+func UnpackAction(ctx context.Context, command *cli.Command) error {
+	// Without preset or algorithm flags, media are hashed with every algorithm, as the algorithms
+	// of the Manifest are only known once it is extracted.
+	var config medhash.Config
+	if cmd.AlgsSet(command) || command.IsSet("default") {
+		config = cmd.HashConfig(command)
+	} else {
+		for _, alg := range medhash.Algorithms() {
+			config.Toggle(alg, true)
+		}
+	}
+	config.Manifest = command.String("manifest")
+
+	args := command.Args().Slice()
+	if len(args) < 1 || len(args) > 2 {
+		return cli.Exit("unpack requires an archive and an optional target directory", cmd.ExitUsage)
+	}
+	archivePath := args[0]
+
+	if len(args) > 1 {
+		config.Dir = args[1]
+	} else {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return cli.Exit(fmt.Errorf("cannot get working directory: %w", err), cmd.ExitIO)
+		}
+		config.Dir = cwd
+	}
+
+	cmd.Printf("Unpacking %s into %s", archivePath, config.Dir)
+
+	errs := UnpackFunc(config, archivePath)
+	return cmd.Result(errs)
+}
+
+// UnpackFunc extracts the archive at archivePath into config.Dir, and verifies the extracted media
+// against the Manifest embedded in the archive as config.Manifest.
+// Each media is hashed as it is extracted, so that it is only read once.
+// As the Manifest is usually the final member of the archive, media are verified after extraction.
+// Each media is verified against the algorithms it shares with the Manifest, and fails if it shares
+// none.
+func UnpackFunc(config medhash.Config, archivePath string) error {
+	sums := make(map[string]medhash.Hash)
+	var manFile []byte
+
+	err := archive.Walk(archivePath, func(name string, r io.Reader) error {
+		path := filepath.Join(config.Dir, filepath.FromSlash(name))
+		sum, data, err := extract(config, path, r, name == config.Manifest)
+		if err != nil {
+			cmd.StatusLabel(path, cmd.MsgStatusError, err)
+			return err
+		}
+		cmd.StatusLabel(path, cmd.MsgStatusOK, nil)
+
+		if name == config.Manifest {
+			manFile = data
+		} else {
+			sums[name] = sum
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if manFile == nil {
+		return cmd.ManifestError(fmt.Errorf("no %s found in %s", config.Manifest, archivePath))
+	}
+
+	var manifest medhash.Manifest
+	if err := json.Unmarshal(manFile, &manifest); err != nil {
+		return cmd.ManifestError(fmt.Errorf("%s: %w", config.Manifest, err))
+	}
+
+	cmd.Println("Verifying extracted files")
+
+	var errs error
+	for _, med := range manifest.Media {
+		sum, ok := sums[med.Path]
+		if !ok {
+			err := fmt.Errorf("%s: %w", filepath.Join(config.Dir, med.Path), fs.ErrNotExist)
+			cmd.Status(filepath.Join(config.Dir, med.Path), err)
+			errs = cmd.JoinErrors(errs, err)
+			continue
+		}
+		delete(sums, med.Path)
+
+		err := chkSum(config, med, sum)
+		errs = cmd.JoinErrors(errs, err)
+		cmd.Status(filepath.Join(config.Dir, med.Path), err)
+	}
+
+	for name := range sums {
+		cmd.StatusLabel(filepath.Join(config.Dir, filepath.FromSlash(name)), cmd.MsgStatusExtra, nil)
+	}
+
+	return errs
+}
+
+// chkSum verifies med against sum, with the algorithms both med and config have.
+func chkSum(config medhash.Config, med medhash.Media, sum medhash.Hash) error {
+	var shared medhash.Config
+	shared.Dir = config.Dir
+	for _, alg := range config.Algorithms() {
+		if med.Hash.Get(alg) != "" {
+			shared.Toggle(alg, true)
+		}
+	}
+	if len(shared.Algorithms()) < 1 {
+		return fmt.Errorf("%s: no hash of %s in manifest", filepath.Join(config.Dir, med.Path),
+			strings.Join(config.Algorithms(), ", "))
+	}
+	return med.CheckHash(shared, sum)
+}
+
+// extract writes the data read from r to path, while hashing it as configured.
+// If keep is true, the data is also returned.
+func extract(config medhash.Config, path string, r io.Reader, keep bool) (sum medhash.Hash, data []byte, err error) {
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	if keep {
+		data, err = io.ReadAll(r)
+		if err != nil {
+			return
+		}
+		_, err = f.Write(data)
+		return
+	}
+
+	sum, err = medhash.HashReader(config, io.TeeReader(r, f))
+	return
+}
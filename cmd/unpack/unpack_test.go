@@ -0,0 +1,113 @@
+package unpack_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/cmd/unpack"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/ghifari160/medhash-tools/testcommon"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+func TestUnpack(t *testing.T) {
+	t.Parallel()
+
+	cases := []testcommon.TestCase{
+		testcommon.Case("tar", "tar"),
+		testcommon.Case("tar.gz", "tar.gz"),
+		testcommon.Case("zip", "zip"),
+		testcommon.Case("tar/invalid", "tar", withInvalidate(true)),
+		testcommon.Case("tar/no_manifest", "tar", withNoManifest(true)),
+		testcommon.Case("tar/sha256", "tar", withSHA256(true)),
+		testcommon.Case("tar/sha256/tampered", "tar", withSHA256(true), withTamper(true)),
+		testcommon.Case("tar/sha256/unshared", "tar", withSHA256(true), withFlags("--xxh3")),
+	}
+
+	testcommon.RunCases(t, testUnpack, cases)
+}
+
+func testUnpack(t *testing.T, format string, opts ...testcommon.Options) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+
+	options := testcommon.MergeOptions(opts...)
+	invalidate := options.Bool("invalidate")
+	noManifest := options.Bool("no_manifest")
+	sha256 := options.Bool("sha256")
+	tamper := options.Bool("tamper")
+	var flags []string
+	if options.IsStr("flags") {
+		flags = append(flags, options.Str("flags"))
+	}
+
+	if invalidate {
+		payload.Hash.XXH3 = "__INVALID__"
+	}
+	if !noManifest {
+		conf := medhash.DefaultConfig
+		if sha256 {
+			conf = medhash.Config{SHA256: true}
+		}
+		conf.Dir = dir
+		conf.Manifest = medhash.DefaultManifestName
+		testcommon.CreateManifest(t, conf, payload, medhash.ManifestFormatVer)
+	}
+
+	expected, err := os.ReadFile(filepath.Join(dir, payload.Path))
+	require.NoError(err)
+	if tamper {
+		expected[0] ^= 0xff
+		require.NoError(os.WriteFile(filepath.Join(dir, payload.Path), expected, 0644))
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "delivery."+format)
+	testcommon.CreateArchive(t, archivePath, format, dir)
+
+	out := t.TempDir()
+	command := unpack.CommandUnpack()
+	command.ExitErrHandler = func(ctx context.Context, c *cli.Command, err error) {}
+
+	err = command.Run(t.Context(), append(append([]string{"unpack"}, flags...), archivePath, out))
+	if invalidate || noManifest || tamper || len(flags) > 0 {
+		require.Error(err)
+	} else {
+		require.NoError(err)
+	}
+
+	require.FileExists(filepath.Join(out, payload.Path))
+	actual, err := os.ReadFile(filepath.Join(out, payload.Path))
+	require.NoError(err)
+	require.Equal(expected, actual)
+}
+
+// withInvalidate invalidates the payload hash for testing.
+func withInvalidate(invalidate bool) testcommon.Options {
+	return testcommon.NewOptions("invalidate", invalidate)
+}
+
+// withNoManifest omits the Manifest from the archive for testing.
+func withNoManifest(noManifest bool) testcommon.Options {
+	return testcommon.NewOptions("no_manifest", noManifest)
+}
+
+// withSHA256 hashes the Manifest with SHA256 instead of the default preset for testing.
+func withSHA256(sha256 bool) testcommon.Options {
+	return testcommon.NewOptions("sha256", sha256)
+}
+
+// withTamper modifies the payload after the Manifest is generated for testing.
+func withTamper(tamper bool) testcommon.Options {
+	return testcommon.NewOptions("tamper", tamper)
+}
+
+// withFlags passes the hash algorithm flag to unpack for testing.
+func withFlags(flag string) testcommon.Options {
+	return testcommon.NewOptions("flags", flag)
+}
@@ -2,22 +2,23 @@ package upgrade
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/ghifari160/medhash-tools/checksum"
 	"github.com/ghifari160/medhash-tools/cmd"
 	"github.com/ghifari160/medhash-tools/cmd/gen"
-	"github.com/ghifari160/medhash-tools/color"
 	"github.com/ghifari160/medhash-tools/medhash"
 	"github.com/stretchr/objx"
 	"github.com/urfave/cli/v3"
 )
 
 // CurrentSpec is the most current Specification implemented.
-const CurrentSpec = "0.5.0"
+const CurrentSpec = medhash.ManifestFormatVer
 
 var (
 	v010ChkConfig = medhash.Config{SHA256: true}
@@ -25,6 +26,7 @@ var (
 	v030ChkConfig = medhash.Config{SHA256: true, SHA1: true, MD5: true}
 	v040ChkConfig = medhash.AllConfig
 	v050ChkConfig = medhash.AllConfig
+	v060ChkConfig = medhash.AllConfig
 )
 
 func init() {
@@ -41,6 +43,12 @@ func CommandUpgrade() *cli.Command {
 				Aliases: []string{"i"},
 				Usage:   "ignore patterns",
 			},
+			&cli.StringFlag{
+				Name:    "manifest",
+				Aliases: []string{"m"},
+				Usage:   "manifest file name",
+				Value:   medhash.DefaultManifestName,
+			},
 			&cli.BoolFlag{
 				Name:  "force",
 				Usage: "force upgrade current Manifest",
@@ -84,7 +92,9 @@ func UpgradeAction(ctx context.Context, command *cli.Command) error {
 		config.SHA256 = command.Bool("sha256")
 		config.SHA1 = command.Bool("sha1")
 		config.MD5 = command.Bool("md5")
+		config.CRC32 = command.Bool("crc32")
 	}
+	config.Manifest = command.String("manifest")
 
 	force := command.Bool("force")
 
@@ -92,45 +102,38 @@ func UpgradeAction(ctx context.Context, command *cli.Command) error {
 	if len(dirs) < 1 {
 		cwd, err := os.Getwd()
 		if err != nil {
-			return cli.Exit(fmt.Errorf("cannot get working directory: %w", err), 1)
+			return cli.Exit(fmt.Errorf("cannot get working directory: %w", err), cmd.ExitIO)
 		}
 		dirs = append(dirs, cwd)
 	}
 
-	var manifestIgnored bool
-	ignores := command.StringSlice("ignore")
-
-	for _, ignore := range ignores {
-		if ignore == medhash.DefaultManifestName {
-			manifestIgnored = true
-		}
-	}
-
-	if !manifestIgnored {
-		ignores = append(ignores, medhash.DefaultManifestName)
-	}
+	ignores := cmd.IgnoreManifest(command.StringSlice("ignore"), config.Manifest)
 
 	var errs error
 	for i, dir := range dirs {
 		if len(dirs) > 1 {
-			color.Printf("[%d/%d] Upgrading MedHash for %s\n", i+1, len(dirs), dir)
+			cmd.Printf("[%d/%d] Upgrading MedHash for %s", i+1, len(dirs), dir)
 		} else {
-			color.Printf("Upgrading MedHash for %s\n", dir)
+			cmd.Printf("Upgrading MedHash for %s", dir)
 		}
 
 		conf := config
 		conf.Dir = dir
 
-		_, err := os.Stat(filepath.Join(dir, medhash.DefaultManifestName))
+		_, err := os.Stat(filepath.Join(dir, conf.Manifest))
 		if errors.Is(err, os.ErrNotExist) {
 			_, err := os.Stat(filepath.Join(dir, "sums.txt"))
-			if errors.Is(err, os.ErrNotExist) {
-				errs = cmd.JoinErrors(errs, fmt.Errorf("no manifest.json or sums.txt found in %s", dir))
-			} else if err != nil {
+			sfvPaths, _ := filepath.Glob(filepath.Join(dir, "*.sfv"))
+			if err == nil {
+				cmd.Println("Legacy Manifest detected!")
+				errs = cmd.JoinErrors(errs, upgradeV010(conf, ignores, force))
+			} else if !errors.Is(err, os.ErrNotExist) {
 				errs = cmd.JoinErrors(errs, err)
+			} else if len(sfvPaths) > 0 {
+				cmd.Println("SFV detected!")
+				errs = cmd.JoinErrors(errs, upgradeSFV(conf, sfvPaths, ignores))
 			} else {
-				color.Println("Legacy Manifest detected!")
-				errs = cmd.JoinErrors(errs, upgradeV010(conf, ignores, force))
+				errs = cmd.JoinErrors(errs, fmt.Errorf("no %s, sums.txt, or SFV found in %s", conf.Manifest, dir))
 			}
 		} else if err != nil {
 			errs = cmd.JoinErrors(errs, err)
@@ -140,14 +143,14 @@ func UpgradeAction(ctx context.Context, command *cli.Command) error {
 	}
 
 	if errs != nil {
-		color.Println(cmd.MsgFinalError)
+		cmd.Failln(cmd.MsgFinalError)
 		for _, err := range cmd.UnwrapJoinedErrors(errs) {
-			color.Println(err)
+			cmd.Failln(err)
 		}
-		return cli.Exit("", 1)
+		return cmd.Exit(errs)
 	}
 
-	color.Println(cmd.MsgFinalDone)
+	cmd.Println(cmd.MsgFinalDone)
 	return nil
 }
 
@@ -173,7 +176,7 @@ func upgradeV010(genConfig medhash.Config, ignores []string, force bool) error {
 	}
 	convertedManifest.Config = chkConfig
 
-	color.Printf("Checking legacy Manifest for %s\n", convertedManifest.Config.Dir)
+	cmd.Printf("Checking legacy Manifest for %s", convertedManifest.Config.Dir)
 
 	var chkErrs error
 	for i, med := range legacyMedias {
@@ -181,7 +184,7 @@ func upgradeV010(genConfig medhash.Config, ignores []string, force bool) error {
 		if len(legacyMedia) < 1 {
 			continue
 		} else if len(legacyMedia) < 2 {
-			color.Printf("Unknown media format (line %d): [%s]\n", i, strings.Join(legacyMedia, ","))
+			cmd.Printf("Unknown media format (line %d): [%s]", i, strings.Join(legacyMedia, ","))
 			continue
 		}
 
@@ -195,28 +198,98 @@ func upgradeV010(genConfig medhash.Config, ignores []string, force bool) error {
 	}
 
 	for _, media := range convertedManifest.Media {
-		color.Printf("  %s: ", media.Path)
 		err := media.Check(convertedManifest.Config)
 		if err != nil {
 			chkErrs = cmd.JoinErrors(chkErrs, err)
-			color.Print(cmd.MsgStatusError)
-		} else {
-			color.Println(cmd.MsgStatusOK)
 		}
+		cmd.StatusLabel(media.Path, cmd.MsgStatusOKOrError(err), err)
 	}
 	if chkErrs != nil {
 		errs = cmd.JoinErrors(errs, chkErrs)
 		return errs
 	}
 
-	color.Printf("Generating MedHash for %s\n", genConfig.Dir)
+	cmd.Printf("Generating MedHash for %s", genConfig.Dir)
 	errs = cmd.JoinErrors(errs, gen.GenFunc(genConfig, ignores))
 	return errs
 }
 
+// upgradeSFV upgrades the SFV files at sfvPaths to the current Manifest spec version.
+// The media are verified against their CRC32 checksums, which are retained in the Manifest.
+// The SFV files are recorded as the source of the Manifest in its generator.
+func upgradeSFV(genConfig medhash.Config, sfvPaths []string, ignores []string) error {
+	chkConfig := medhash.Config{CRC32: true}
+	chkConfig.Dir = genConfig.Dir
+
+	convertedManifest := &medhash.Manifest{
+		Media: make([]medhash.Media, 0),
+	}
+	convertedManifest.Config = chkConfig
+
+	sources := make([]string, 0, len(sfvPaths))
+	for _, sfvPath := range sfvPaths {
+		medias, err := readSFV(sfvPath)
+		if err != nil {
+			return err
+		}
+		convertedManifest.Media = append(convertedManifest.Media, medias...)
+
+		ignores = append(ignores, filepath.Base(sfvPath))
+		sources = append(sources, filepath.Base(sfvPath))
+	}
+
+	cmd.Printf("Checking SFV for %s", chkConfig.Dir)
+	if err := chkManifest(convertedManifest); err != nil {
+		return err
+	}
+
+	genConfig.CRC32 = true
+	cmd.Printf("Generating MedHash for %s", genConfig.Dir)
+	if err := gen.GenFunc(genConfig, ignores); err != nil {
+		return err
+	}
+
+	return recordGenerator(genConfig.ManifestPath(), cmd.Generator("upgraded from "+strings.Join(sources, ", ")))
+}
+
+// readSFV reads the media listed by the SFV file at sfvPath.
+func readSFV(sfvPath string) ([]medhash.Media, error) {
+	f, err := os.Open(sfvPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	medias, err := checksum.Read(f, checksum.FormatSFV)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", sfvPath, err)
+	}
+	return medias, nil
+}
+
+// recordGenerator records generator in the Manifest at manPath.
+func recordGenerator(manPath, generator string) error {
+	manFile, err := os.ReadFile(manPath)
+	if err != nil {
+		return err
+	}
+
+	var manifest medhash.Manifest
+	if err := json.Unmarshal(manFile, &manifest); err != nil {
+		return err
+	}
+	manifest.Generator = generator
+
+	manFile, err = json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(manPath, manFile, 0644)
+}
+
 func upgradeJSON(genConfig medhash.Config, ignores []string, force bool) error {
 	var errs error
-	legacyPath := filepath.Join(genConfig.Dir, medhash.DefaultManifestName)
+	legacyPath := filepath.Join(genConfig.Dir, genConfig.Manifest)
 
 	legacyFile, err := os.ReadFile(legacyPath)
 	if err != nil {
@@ -231,20 +304,28 @@ func upgradeJSON(genConfig medhash.Config, ignores []string, force bool) error {
 	version := legacyManifest.Get("version").Str()
 	switch version {
 	case "0.2.0":
-		color.Println("Manifest v0.2.0 detected!")
+		cmd.Println("Manifest v0.2.0 detected!")
 		errs = cmd.JoinErrors(errs, upgradeV020(genConfig, legacyManifest, force))
 
 	case "0.3.0":
-		color.Println("Manifest v0.3.0 detected!")
+		cmd.Println("Manifest v0.3.0 detected!")
 		errs = cmd.JoinErrors(errs, upgradeV030(genConfig, legacyManifest, force))
 
 	case "0.4.0":
-		color.Println("Manifest v0.4.0 detected!")
+		cmd.Println("Manifest v0.4.0 detected!")
 		errs = cmd.JoinErrors(errs, upgradeV040(genConfig, legacyManifest, force))
 
 	case "0.5.0":
-		color.Println("Manifest v0.5.0 detected!")
-		errs = cmd.JoinErrors(errs, upgradeV050(genConfig, legacyManifest, force))
+		cmd.Println("Manifest v0.5.0 detected!")
+		errs = cmd.JoinErrors(errs, upgradeV050(genConfig, legacyManifest))
+
+	case "0.6.0":
+		cmd.Println("Manifest v0.6.0 detected!")
+		errs = cmd.JoinErrors(errs, upgradeV060(genConfig, legacyManifest))
+
+	case "0.7.0":
+		cmd.Println("Manifest v0.7.0 detected!")
+		errs = cmd.JoinErrors(errs, upgradeV070(genConfig, legacyManifest, force))
 
 	default:
 		errs = cmd.JoinErrors(errs, fmt.Errorf("unexpected version: %v", legacyManifest.Get("version").Data()))
@@ -253,7 +334,7 @@ func upgradeJSON(genConfig medhash.Config, ignores []string, force bool) error {
 		return errs
 	}
 
-	color.Println("Generating MedHash")
+	cmd.Println("Generating MedHash")
 
 	return cmd.JoinErrors(errs, gen.GenFunc(genConfig, ignores))
 }
@@ -264,7 +345,7 @@ func upgradeV020(genConfig medhash.Config, legacy objx.Map, force bool) error {
 	chkConfig := v020ChkConfig
 	chkConfig.Dir = genConfig.Dir
 
-	color.Printf("Checking legacy manifest for %s\n", chkConfig.Dir)
+	cmd.Printf("Checking legacy manifest for %s", chkConfig.Dir)
 
 	if err := expectVersion("0.2.0", legacy.Get("version")); err != nil {
 		return err
@@ -287,7 +368,7 @@ func upgradeV030(genConfig medhash.Config, legacy objx.Map, force bool) error {
 	chkConfig := v030ChkConfig
 	chkConfig.Dir = genConfig.Dir
 
-	color.Printf("Checking legacy manifest for %s\n", chkConfig.Dir)
+	cmd.Printf("Checking legacy manifest for %s", chkConfig.Dir)
 
 	if err := expectVersion("0.3.0", legacy.Get("version")); err != nil {
 		return err
@@ -310,7 +391,7 @@ func upgradeV040(genConfig medhash.Config, legacy objx.Map, force bool) error {
 	chkConfig := v040ChkConfig
 	chkConfig.Dir = genConfig.Dir
 
-	color.Printf("Checking legacy manifest for %s\n", chkConfig.Dir)
+	cmd.Printf("Checking legacy manifest for %s", chkConfig.Dir)
 
 	if err := expectVersion("0.4.0", legacy.Get("version")); err != nil {
 		return err
@@ -328,30 +409,76 @@ func upgradeV040(genConfig medhash.Config, legacy objx.Map, force bool) error {
 }
 
 // upgradeV050 upgrades a Manifest spec v0.5.0 to the current Manifest spec version.
-//
-// With the force flag enabled, this function regenerates a current Manifest with the config.
-// Otherwise, this function is a placeholder.
-// It does nothing.
-func upgradeV050(genConfig medhash.Config, legacy objx.Map, force bool) error {
+func upgradeV050(genConfig medhash.Config, legacy objx.Map) error {
 	var errs error
 	chkConfig := v050ChkConfig
 	chkConfig.Dir = genConfig.Dir
 
+	cmd.Printf("Checking legacy manifest for %s", chkConfig.Dir)
+
 	if err := expectVersion("0.5.0", legacy.Get("version")); err != nil {
 		return err
 	}
 
+	convertedManifest, mapErrs := mapToManifest(legacy.Get("media"))
+	errs = cmd.JoinErrors(errs, mapErrs)
+	if errs != nil {
+		return errs
+	}
+	convertedManifest.Config = chkConfig
+	errs = cmd.JoinErrors(errs, chkManifest(convertedManifest))
+
+	return errs
+}
+
+// upgradeV060 upgrades a Manifest spec v0.6.0 to the current Manifest spec version.
+func upgradeV060(genConfig medhash.Config, legacy objx.Map) error {
+	var errs error
+	chkConfig := v060ChkConfig
+	chkConfig.Dir = genConfig.Dir
+
+	cmd.Printf("Checking legacy manifest for %s", chkConfig.Dir)
+
+	if err := expectVersion("0.6.0", legacy.Get("version")); err != nil {
+		return err
+	}
+
+	convertedManifest, mapErrs := mapToManifest(legacy.Get("media"))
+	errs = cmd.JoinErrors(errs, mapErrs)
+	if errs != nil {
+		return errs
+	}
+	convertedManifest.Config = chkConfig
+	errs = cmd.JoinErrors(errs, chkManifest(convertedManifest))
+
+	return errs
+}
+
+// upgradeV070 regenerates a Manifest spec v0.7.0, the current Manifest spec version.
+//
+// With the force flag enabled, this function verifies the media of the Manifest, which is then
+// regenerated with the config.
+// Otherwise, it is an error, as the Manifest is already current.
+func upgradeV070(genConfig medhash.Config, legacy objx.Map, force bool) error {
+	var errs error
+
+	if err := expectVersion("0.7.0", legacy.Get("version")); err != nil {
+		return err
+	}
+
 	if !force {
-		return fmt.Errorf("manifest v0.5.0 is the current spec")
+		return fmt.Errorf("manifest v%s is the current spec", CurrentSpec)
 	}
 
-	color.Printf("Forced to regenerated Manifest v0.5.0 %s!\n", chkConfig.Dir)
+	cmd.Printf("Forced to regenerate Manifest v0.7.0 %s!", genConfig.Dir)
 
 	convertedManifest, mapErrs := mapToManifest(legacy.Get("media"))
 	errs = cmd.JoinErrors(errs, mapErrs)
 	if errs != nil {
 		return errs
 	}
+	chkConfig := cmd.MediaConfig(convertedManifest)
+	chkConfig.Dir = genConfig.Dir
 	convertedManifest.Config = chkConfig
 	errs = cmd.JoinErrors(errs, chkManifest(convertedManifest))
 
@@ -402,6 +529,7 @@ func mapToManifest(legacyMedias *objx.Value) (convertedManifest *medhash.Manifes
 			convertStrField(i, "sha256", media.Get("hash.sha256"), &convertedMedia.Hash.SHA256),
 			convertStrField(i, "sha1", media.Get("hash.sha1"), &convertedMedia.Hash.SHA1),
 			convertStrField(i, "md5", media.Get("hash.md5"), &convertedMedia.Hash.MD5),
+			convertStrField(i, "crc32", media.Get("hash.crc32"), &convertedMedia.Hash.CRC32),
 		)
 		if hashErrs != nil {
 			errs = cmd.JoinErrors(errs, hashErrs)
@@ -416,14 +544,11 @@ func mapToManifest(legacyMedias *objx.Value) (convertedManifest *medhash.Manifes
 // chkManifest verifies the Hashes for all Media in the provided manifest.
 func chkManifest(manifest *medhash.Manifest) (errs error) {
 	for _, media := range manifest.Media {
-		color.Printf("  %s: ", filepath.Join(manifest.Config.Dir, media.Path))
 		err := media.Check(manifest.Config)
 		if err != nil {
 			errs = cmd.JoinErrors(errs, err)
-			color.Println(cmd.MsgStatusError)
-		} else {
-			color.Println(cmd.MsgStatusOK)
 		}
+		cmd.StatusLabel(filepath.Join(manifest.Config.Dir, media.Path), cmd.MsgStatusOKOrError(err), err)
 	}
 	return
 }
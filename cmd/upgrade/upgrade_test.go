@@ -2,7 +2,9 @@ package upgrade_test
 
 import (
 	"context"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/ghifari160/medhash-tools/cmd/upgrade"
@@ -29,6 +31,18 @@ func TestUpgrade(t *testing.T) {
 		})),
 		testcommon.Case("0.5.0/not_forced", "0.5.0", withGenConfig(medhash.Config{XXH3: true}), withForce(false)),
 		testcommon.Case("0.5.0/forced", "0.5.0", withGenConfig(medhash.Config{XXH3: true}), withForce(true)),
+		testcommon.Case("0.6.0", "0.6.0", withGenConfig(medhash.Config{XXH3: true})),
+		testcommon.Case("0.7.0/not_forced", "0.7.0", withGenConfig(medhash.Config{XXH3: true, CRC32: true}),
+			withForce(false)),
+		testcommon.Case("0.7.0/forced", "0.7.0", withGenConfig(medhash.Config{XXH3: true, CRC32: true}),
+			withForce(true)),
+		testcommon.Case("0.4.0/manifest", "0.4.0", withGenConfig(medhash.Config{
+			SHA3:     true,
+			SHA256:   true,
+			SHA1:     true,
+			MD5:      true,
+			Manifest: "delivery.json",
+		})),
 	}
 
 	testcommon.RunCases(t, testUpgrade, cases)
@@ -40,7 +54,6 @@ func testUpgrade(t *testing.T, version string, opts ...testcommon.Options) {
 	require := require.New(t)
 	dir := t.TempDir()
 	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
-	manifestPath := filepath.Join(dir, medhash.DefaultManifestName)
 
 	options := testcommon.MergeOptions(opts...)
 	genConf := genConfig(options)
@@ -50,6 +63,7 @@ func testUpgrade(t *testing.T, version string, opts ...testcommon.Options) {
 	if genConf.Manifest == "" {
 		genConf.Manifest = medhash.DefaultManifestName
 	}
+	manifestPath := filepath.Join(dir, genConf.Manifest)
 	chkConf := medhash.DefaultConfig
 	chkConf.Dir = dir
 	chkConf.Manifest = filepath.Base(manifestPath)
@@ -71,6 +85,10 @@ func testUpgrade(t *testing.T, version string, opts ...testcommon.Options) {
 		arguments = append(arguments, "--force")
 	}
 
+	if genConf.Manifest != medhash.DefaultManifestName {
+		arguments = append(arguments, "--manifest", genConf.Manifest)
+	}
+
 	if version == "0.1.0" {
 		testcommon.CreateLegacyManifest(t, dir, payload)
 	} else {
@@ -111,3 +129,39 @@ func genConfig(options testcommon.Options) medhash.Config {
 		return medhash.Config{}
 	}
 }
+
+func TestUpgradeSFV(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+
+	sfv := "; delivery\n" + payload.Path + " " + strings.ToUpper(payload.Hash.CRC32) + "\n"
+	require.NoError(os.WriteFile(filepath.Join(dir, "delivery.sfv"), []byte(sfv), 0644))
+
+	command := upgrade.CommandUpgrade()
+	err := command.Run(t.Context(), []string{"upgrade", dir})
+	require.NoError(err)
+
+	conf := medhash.DefaultConfig
+	conf.CRC32 = true
+	conf.Dir = dir
+	conf.Manifest = medhash.DefaultManifestName
+	testcommon.VerifyManifest(t, conf, payload.Hash)
+
+	manifest := testcommon.ReadManifest(t, conf.ManifestPath())
+	require.Len(manifest.Media, 1)
+	require.Equal(payload.Hash.CRC32, manifest.Media[0].Hash.CRC32)
+	require.Contains(manifest.Generator, "upgraded from delivery.sfv")
+
+	require.NoError(os.Remove(conf.ManifestPath()))
+	sfv = payload.Path + " 00000000\n"
+	require.NoError(os.WriteFile(filepath.Join(dir, "delivery.sfv"), []byte(sfv), 0644))
+
+	command = upgrade.CommandUpgrade()
+	command.ExitErrHandler = func(ctx context.Context, c *cli.Command, err error) {}
+	err = command.Run(t.Context(), []string{"upgrade", dir})
+	require.Error(err)
+	require.NoFileExists(conf.ManifestPath())
+}
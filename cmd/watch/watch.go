@@ -0,0 +1,491 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ghifari160/medhash-tools/cmd"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/ghifari160/medhash-tools/notify"
+	"github.com/urfave/cli/v3"
+)
+
+// DefaultSettle is the default duration a file must remain unchanged before it is hashed.
+const DefaultSettle = 5 * time.Second
+
+func init() {
+	cmd.RegisterCmd(CommandWatch())
+}
+
+func CommandWatch() *cli.Command {
+	return &cli.Command{
+		Name:      "watch",
+		Usage:     "keep a manifest up to date as files are added, changed, moved, or removed",
+		ArgsUsage: "<dir>",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:    "ignore",
+				Aliases: []string{"i"},
+				Usage:   "ignore patterns",
+			},
+			&cli.StringFlag{
+				Name:    "manifest",
+				Aliases: []string{"m"},
+				Usage:   "manifest file name",
+				Value:   medhash.DefaultManifestName,
+			},
+			&cli.DurationFlag{
+				Name:  "settle",
+				Usage: "hash files once their size is unchanged for this duration",
+				Value: DefaultSettle,
+			},
+		},
+		MutuallyExclusiveFlags: []cli.MutuallyExclusiveFlags{
+			{
+				Flags: [][]cli.Flag{
+					{
+						&cli.BoolFlag{
+							Name:  "all",
+							Usage: "use all algorithms",
+						},
+					},
+					cmd.HashAlgs(),
+				},
+			},
+		},
+		Action: WatchAction,
+	}
+}
+
+func WatchAction(ctx context.Context, command *cli.Command) error {
+	if command.Args().Len() != 1 {
+		return cli.Exit("watch requires exactly one target directory", cmd.ExitUsage)
+	}
+
+	// Without algorithm flags, files are hashed with the algorithms already in the Manifest, or
+	// with the default preset for a new Manifest.
+	var config medhash.Config
+	if cmd.AlgsSet(command) {
+		config = cmd.HashConfig(command)
+	}
+	config.Dir = command.Args().First()
+	config.Manifest = command.String("manifest")
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cmd.Printf("Watching %s (press Ctrl+C to stop)", config.Dir)
+
+	err := WatchFunc(ctx, config, command.StringSlice("ignore"), command.Duration("settle"))
+	return cmd.Result(err)
+}
+
+// WatchFunc keeps the Manifest of config.Dir up to date until ctx is done.
+// The Manifest is created if it does not exist.
+// On start, unlisted files are queued, and listed media that no longer exist are removed.
+// New and changed files are hashed once their size and modification time are unchanged for
+// settle, and removed and moved files are removed and renamed without being read.
+// Each change is written to the Manifest atomically.
+// New files are hashed as configured, or with the algorithms of the media already listed if
+// config has no algorithm.
+// Errors hashing individual files are reported without stopping the watch.
+func WatchFunc(ctx context.Context, config medhash.Config, ignores []string, settle time.Duration) error {
+	w, err := newWatcher(config, ignores, settle)
+	if err != nil {
+		return err
+	}
+
+	nw, err := notify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer nw.Close()
+	w.notify = nw
+
+	if err := w.scan(time.Now()); err != nil {
+		return err
+	}
+
+	interval := min(settle/2, time.Second)
+	if interval <= 0 {
+		interval = time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev := <-nw.Events:
+			w.handle(ev, time.Now())
+		case err := <-nw.Errors:
+			if errors.Is(err, notify.ErrOverflow) {
+				cmd.Println("Events were dropped, rescanning")
+				w.report(w.scan(time.Now()))
+				continue
+			}
+			return err
+		case now := <-ticker.C:
+			w.tick(now)
+		}
+	}
+}
+
+// pendingFile is a file waiting to be hashed.
+type pendingFile struct {
+	size    int64
+	modTime time.Time
+	// changed is the last time the file was seen changing.
+	changed time.Time
+}
+
+// move is the source of a move, waiting to be paired with its destination.
+type move struct {
+	rel string
+	dir bool
+	at  time.Time
+}
+
+// watcher is the state of a watch.
+type watcher struct {
+	config   medhash.Config
+	manPath  string
+	manifest *medhash.Manifest
+	ignores  []string
+	settle   time.Duration
+
+	notify  *notify.Watcher
+	dirs    map[string]bool
+	pending map[string]*pendingFile
+	moves   map[uint32]move
+}
+
+// newWatcher returns a watcher of the Manifest of config.Dir.
+func newWatcher(config medhash.Config, ignores []string, settle time.Duration) (*watcher, error) {
+	root, err := filepath.Abs(config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	config.Dir = root
+	if config.Manifest == "" {
+		config.Manifest = medhash.DefaultManifestName
+	}
+	manPath := config.ManifestPath()
+
+	manifest, err := cmd.ReadManifest(manPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		manifest, err = medhash.NewWithConfig(config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Version != medhash.ManifestFormatVer {
+		return nil, cmd.VersionError(manPath, manifest.Version)
+	}
+	if len(manifest.Manifests) > 0 {
+		return nil, fmt.Errorf("%s: manifests with child manifests cannot be watched", manPath)
+	}
+	if manifest.Root != "" {
+		return nil, fmt.Errorf("%s: manifests stored outside their media root cannot be watched", manPath)
+	}
+
+	if len(config.Algorithms()) < 1 {
+		algs := cmd.MediaConfig(manifest)
+		algs.Dir, algs.Manifest = config.Dir, config.Manifest
+		config = algs
+	}
+	manifest.Config = config
+
+	return &watcher{
+		config:   config,
+		manPath:  manPath,
+		manifest: manifest,
+		ignores:  ignores,
+		settle:   settle,
+		dirs:     make(map[string]bool),
+		pending:  make(map[string]*pendingFile),
+		moves:    make(map[uint32]move),
+	}, nil
+}
+
+// scan watches every directory of the media root, queues the unlisted files, and removes the
+// listed media that no longer exist.
+func (w *watcher) scan(now time.Time) error {
+	errs := w.watchTree("", now)
+
+	changed := false
+	for _, med := range append([]medhash.Media(nil), w.manifest.Media...) {
+		if _, err := os.Stat(w.abs(med.Path)); errors.Is(err, fs.ErrNotExist) {
+			errs = cmd.JoinErrors(errs, w.manifest.Remove(med.Path))
+			cmd.StatusLabel(w.abs(med.Path), cmd.MsgStatusRemoved, nil)
+			changed = true
+		}
+	}
+	if changed {
+		errs = cmd.JoinErrors(errs, w.write())
+	}
+	return errs
+}
+
+// watchTree watches the directory rel and its subdirectories, and queues their unlisted files.
+func (w *watcher) watchTree(rel string, now time.Time) error {
+	var errs error
+	err := filepath.Walk(w.abs(rel), func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			errs = cmd.JoinErrors(errs, fmt.Errorf("cannot access %s: %w", path, err))
+			return nil
+		}
+
+		r, err := filepath.Rel(w.config.Dir, path)
+		if err != nil {
+			errs = cmd.JoinErrors(errs, err)
+			return nil
+		}
+		r = filepath.ToSlash(r)
+		if w.ignored(r) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() {
+			if err := w.notify.Add(path); err != nil {
+				errs = cmd.JoinErrors(errs, err)
+				return filepath.SkipDir
+			}
+			w.dirs[path] = true
+			return nil
+		}
+		if info.Mode().IsRegular() && !w.listed(r) {
+			w.pending[r] = &pendingFile{size: info.Size(), modTime: info.ModTime(), changed: now}
+		}
+		return nil
+	})
+	return cmd.JoinErrors(errs, err)
+}
+
+// unwatchTree stops watching the directory rel and its subdirectories.
+func (w *watcher) unwatchTree(rel string) {
+	dir := w.abs(rel)
+	for d := range w.dirs {
+		if d == dir || strings.HasPrefix(d, dir+string(filepath.Separator)) {
+			// The watches of removed directories are already removed.
+			_ = w.notify.Remove(d)
+			delete(w.dirs, d)
+		}
+	}
+}
+
+// handle updates the state of w for ev.
+func (w *watcher) handle(ev notify.Event, now time.Time) {
+	r, err := filepath.Rel(w.config.Dir, ev.Path)
+	if err != nil || r == "." {
+		return
+	}
+	rel := filepath.ToSlash(r)
+	if w.ignored(rel) {
+		return
+	}
+
+	switch {
+	case ev.Op.Has(notify.Rename):
+		delete(w.pending, rel)
+		w.moves[ev.Cookie] = move{rel: rel, dir: ev.Dir, at: now}
+
+	case ev.Op.Has(notify.Remove):
+		delete(w.pending, rel)
+		if ev.Dir {
+			w.unwatchTree(rel)
+		}
+		w.report(w.remove(rel))
+
+	case ev.Op.Has(notify.Create):
+		if from, ok := w.moves[ev.Cookie]; ok && ev.Cookie != 0 {
+			delete(w.moves, ev.Cookie)
+			if from.dir {
+				w.unwatchTree(from.rel)
+			}
+			w.report(w.rename(from.rel, rel))
+		}
+		if ev.Dir {
+			w.report(w.watchTree(rel, now))
+		} else if !w.listed(rel) {
+			w.touch(rel, now)
+		}
+
+	case ev.Op.Has(notify.Write) || ev.Op.Has(notify.CloseWrite):
+		if !ev.Dir {
+			w.touch(rel, now)
+		}
+	}
+}
+
+// touch queues the file rel, as changed at now.
+func (w *watcher) touch(rel string, now time.Time) {
+	info, err := os.Stat(w.abs(rel))
+	if err != nil || !info.Mode().IsRegular() {
+		return
+	}
+	w.pending[rel] = &pendingFile{size: info.Size(), modTime: info.ModTime(), changed: now}
+}
+
+// tick hashes the pending files that settled, and removes the media moved out of the media root.
+func (w *watcher) tick(now time.Time) {
+	var errs error
+	changed := false
+
+	for cookie, from := range w.moves {
+		if now.Sub(from.at) < w.settle {
+			continue
+		}
+		delete(w.moves, cookie)
+		if from.dir {
+			w.unwatchTree(from.rel)
+		}
+		errs = cmd.JoinErrors(errs, w.remove(from.rel))
+	}
+
+	for rel, file := range w.pending {
+		info, err := os.Stat(w.abs(rel))
+		if err != nil {
+			delete(w.pending, rel)
+			continue
+		}
+		if info.Size() != file.size || !info.ModTime().Equal(file.modTime) {
+			file.size, file.modTime, file.changed = info.Size(), info.ModTime(), now
+			continue
+		}
+		if now.Sub(file.changed) < w.settle {
+			continue
+		}
+		delete(w.pending, rel)
+
+		status := cmd.MsgStatusAdded
+		if w.listed(rel) {
+			status = cmd.MsgStatusChanged
+			errs = cmd.JoinErrors(errs, w.manifest.Remove(rel))
+		}
+		if err := w.manifest.Add(rel); err != nil {
+			cmd.StatusLabel(w.abs(rel), cmd.MsgStatusError, err)
+			errs = cmd.JoinErrors(errs, err)
+			continue
+		}
+		cmd.StatusLabel(w.abs(rel), status, nil)
+		changed = true
+	}
+
+	if changed {
+		errs = cmd.JoinErrors(errs, w.write())
+	}
+	w.report(errs)
+}
+
+// remove removes the media rel, or the media within the directory rel, and writes the Manifest.
+func (w *watcher) remove(rel string) error {
+	var errs error
+	changed := false
+	for _, med := range append([]medhash.Media(nil), w.manifest.Media...) {
+		if med.Path == rel || strings.HasPrefix(med.Path, rel+"/") {
+			errs = cmd.JoinErrors(errs, w.manifest.Remove(med.Path))
+			cmd.StatusLabel(w.abs(med.Path), cmd.MsgStatusRemoved, nil)
+			changed = true
+		}
+	}
+	for p := range w.pending {
+		if strings.HasPrefix(p, rel+"/") {
+			delete(w.pending, p)
+		}
+	}
+
+	if changed {
+		errs = cmd.JoinErrors(errs, w.write())
+	}
+	return errs
+}
+
+// rename renames the media from, or the media within the directory from, to to without reading
+// them, and writes the Manifest.
+// Media replaced by the rename are removed.
+func (w *watcher) rename(from, to string) error {
+	paths := make(map[string]string)
+	for _, med := range w.manifest.Media {
+		if med.Path == from {
+			paths[med.Path] = to
+		} else if rest, ok := strings.CutPrefix(med.Path, from+"/"); ok {
+			paths[med.Path] = to + "/" + rest
+		}
+	}
+	if len(paths) < 1 {
+		return nil
+	}
+
+	var errs error
+	for _, p := range paths {
+		if _, moved := paths[p]; w.listed(p) && !moved {
+			errs = cmd.JoinErrors(errs, w.manifest.Remove(p))
+		}
+	}
+	if err := w.manifest.Rename(paths); err != nil {
+		return cmd.JoinErrors(errs, err)
+	}
+	for old, p := range paths {
+		cmd.Printf("  %s: %s from %s", w.abs(p), cmd.MsgStatusMoved, w.abs(old))
+	}
+
+	return cmd.JoinErrors(errs, w.write())
+}
+
+// write atomically writes the Manifest.
+func (w *watcher) write() error {
+	return cmd.WriteManifest(w.manifest, w.manPath)
+}
+
+// report prints errs without stopping the watch.
+func (w *watcher) report(errs error) {
+	if errs == nil {
+		return
+	}
+	for _, err := range cmd.UnwrapJoinedErrors(errs) {
+		cmd.Failf("  %s: %v", cmd.MsgStatusError, err)
+	}
+}
+
+// abs returns the path of rel on the local file system.
+func (w *watcher) abs(rel string) string {
+	return filepath.Join(w.config.Dir, filepath.FromSlash(rel))
+}
+
+// listed reports whether rel is listed by the Manifest.
+func (w *watcher) listed(rel string) bool {
+	_, found := slices.BinarySearchFunc(w.manifest.Media, rel, func(med medhash.Media, rel string) int {
+		return strings.Compare(med.Path, rel)
+	})
+	return found
+}
+
+// ignored reports whether rel is ignored.
+// The Manifest and its temporary files are always ignored.
+func (w *watcher) ignored(rel string) bool {
+	name := filepath.Base(w.manPath)
+	if rel == filepath.ToSlash(w.config.Manifest) || rel == name || strings.HasPrefix(rel, "."+name+".") ||
+		rel == cmd.AuditLogName {
+		return true
+	}
+	for _, ignore := range w.ignores {
+		if matched, _ := filepath.Match(ignore, filepath.FromSlash(rel)); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package watch_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ghifari160/medhash-tools/cmd/watch"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/ghifari160/medhash-tools/testcommon"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+
+	conf := medhash.DefaultConfig
+	conf.Dir = dir
+	conf.Manifest = medhash.DefaultManifestName
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() {
+		done <- watch.WatchFunc(ctx, conf, nil, 50*time.Millisecond)
+	}()
+
+	// Files present on start are added.
+	waitMedia(t, conf.ManifestPath(), []medhash.Media{payload})
+
+	require.NoError(os.Mkdir(filepath.Join(dir, "sub"), 0755))
+	require.NoError(os.Rename(filepath.Join(dir, payload.Path), filepath.Join(dir, "sub", payload.Path)))
+	moved := medhash.Media{Path: "sub/" + payload.Path, Hash: payload.Hash}
+	waitMedia(t, conf.ManifestPath(), []medhash.Media{moved})
+
+	require.NoError(os.Rename(filepath.Join(dir, "sub"), filepath.Join(dir, "renamed")))
+	moved.Path = "renamed/" + payload.Path
+	waitMedia(t, conf.ManifestPath(), []medhash.Media{moved})
+
+	require.NoError(os.WriteFile(filepath.Join(dir, "renamed", "new"), []byte("new"), 0644))
+	hash, err := medhash.HashReader(medhash.DefaultConfig, strings.NewReader("new"))
+	require.NoError(err)
+	added := medhash.Media{Path: "renamed/new", Hash: hash}
+	waitMedia(t, conf.ManifestPath(), []medhash.Media{added, moved})
+
+	require.NoError(os.Remove(filepath.Join(dir, "renamed", payload.Path)))
+	waitMedia(t, conf.ManifestPath(), []medhash.Media{added})
+
+	require.NoError(os.RemoveAll(filepath.Join(dir, "renamed")))
+	waitMedia(t, conf.ManifestPath(), []medhash.Media{})
+
+	cancel()
+	require.NoError(<-done)
+}
+
+// waitMedia waits for the Manifest at manPath to list exactly medias, with the hashes of the
+// default preset.
+func waitMedia(t *testing.T, manPath string, medias []medhash.Media) {
+	t.Helper()
+
+	var got []medhash.Media
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(manPath); err == nil {
+			manifest := testcommon.ReadManifest(t, manPath)
+			got = make([]medhash.Media, 0, len(manifest.Media))
+			for _, med := range manifest.Media {
+				got = append(got, medhash.Media{Path: med.Path, Hash: medhash.Hash{XXH3: med.Hash.XXH3}})
+			}
+
+			want := make([]medhash.Media, 0, len(medias))
+			for _, med := range medias {
+				want = append(want, medhash.Media{Path: med.Path, Hash: medhash.Hash{XXH3: med.Hash.XXH3}})
+			}
+			if len(got) == len(want) && (len(got) == 0 || equal(got, want)) {
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("manifest lists %v, want %v", got, medias)
+}
+
+func equal(a, b []medhash.Media) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
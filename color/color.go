@@ -2,8 +2,10 @@ package color
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"regexp"
+	"strings"
 
 	"github.com/mattn/go-isatty"
 )
@@ -35,12 +37,75 @@ const (
 	ReverseText  = "\033[7m"
 	PositiveText = "\033[27m"
 
+	// ClearLine clears the current line.
+	ClearLine = "\033[2K"
+	// CursorUp moves the cursor up by one line.
+	CursorUp = "\033[1A"
+
 	EscStr  = "\033"
 	EscChar = '\033'
 )
 
 var pattern = regexp.MustCompile(`(?:\033\[[0-9]{1,2}m)`)
 
+// Mode configures when formatting is printed.
+type Mode string
+
+// Modes.
+const (
+	// ModeAuto prints formatting to terminals, unless the NO_COLOR environment variable is set.
+	// The FORCE_COLOR environment variable forces formatting on or off (with "0" or "false"), and
+	// takes precedence over NO_COLOR.
+	ModeAuto Mode = "auto"
+	// ModeAlways always prints formatting.
+	ModeAlways Mode = "always"
+	// ModeNever never prints formatting.
+	ModeNever Mode = "never"
+)
+
+// mode is the current Mode.
+var mode = ModeAuto
+
+// ParseMode parses s as a Mode.
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(s); m {
+	case ModeAuto, ModeAlways, ModeNever:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown color mode %q", s)
+	}
+}
+
+// SetMode sets the current Mode.
+// SetMode is meant to be called once, before anything is printed.
+func SetMode(m Mode) {
+	mode = m
+}
+
+// Enabled reports whether formatting is printed to w in the current Mode.
+func Enabled(w io.Writer) bool {
+	switch mode {
+	case ModeAlways:
+		return true
+	case ModeNever:
+		return false
+	}
+
+	if force, ok := os.LookupEnv("FORCE_COLOR"); ok {
+		switch strings.ToLower(force) {
+		case "0", "false":
+			return false
+		default:
+			return true
+		}
+	}
+	// See https://no-color.org.
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return IsTerminalWriter(w)
+}
+
 func Print(a ...any) (n int, err error) {
 	s := nonTtyClean(fmt.Sprint(a...))
 
@@ -59,8 +124,46 @@ func Println(a ...any) (n int, err error) {
 	return fmt.Print(s)
 }
 
+// Fprint formats using the default formats for its operands and writes to w.
+// Formatting is stripped unless it is enabled for w.
+func Fprint(w io.Writer, a ...any) (n int, err error) {
+	return io.WriteString(w, cleanFor(w, fmt.Sprint(a...)))
+}
+
+// Fprintf formats according to a format specifier and writes to w.
+// Formatting is stripped unless it is enabled for w.
+func Fprintf(w io.Writer, format string, a ...any) (n int, err error) {
+	return io.WriteString(w, cleanFor(w, fmt.Sprintf(format, a...)))
+}
+
+// Fprintln formats using the default formats for its operands and writes to w, with a newline.
+// Formatting is stripped unless it is enabled for w.
+func Fprintln(w io.Writer, a ...any) (n int, err error) {
+	return io.WriteString(w, cleanFor(w, fmt.Sprintln(a...)))
+}
+
+// IsTerminal reports whether the standard output is a terminal.
+func IsTerminal() bool {
+	return IsTerminalWriter(os.Stdout)
+}
+
+// IsTerminalWriter reports whether w is a terminal.
+// Only files, such as os.Stdout and os.Stderr, can be terminals.
+func IsTerminalWriter(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
 func nonTtyClean(s string) string {
-	if isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd()) {
+	return cleanFor(os.Stdout, s)
+}
+
+// cleanFor strips all formatting from s unless it is enabled for w.
+func cleanFor(w io.Writer, s string) string {
+	if Enabled(w) {
 		return s
 	}
 
@@ -1,6 +1,8 @@
 package color_test
 
 import (
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/ghifari160/medhash-tools/color"
@@ -32,3 +34,61 @@ func (s *ColorSuite) TestCleanString() {
 	clean := color.CleanString(payloadColor)
 	s.NotContains(clean, color.EscStr)
 }
+
+func (s *ColorSuite) TestFprint() {
+	var b strings.Builder
+
+	color.Fprint(&b, payloadColor)
+	s.NotContains(b.String(), color.EscStr)
+
+	b.Reset()
+	color.Fprintf(&b, "%s", payloadColor)
+	s.Equal(color.CleanString(payloadColor), b.String())
+
+	b.Reset()
+	color.Fprintln(&b, color.Red+"test"+color.Reset)
+	s.Equal("test\n", b.String())
+
+	s.False(color.IsTerminalWriter(&b))
+}
+
+func (s *ColorSuite) TestMode() {
+	defer color.SetMode(color.ModeAuto)
+
+	var b strings.Builder
+	s.T().Setenv("NO_COLOR", "")
+	s.T().Setenv("FORCE_COLOR", "")
+	os.Unsetenv("FORCE_COLOR")
+
+	s.False(color.Enabled(&b))
+
+	s.T().Setenv("FORCE_COLOR", "1")
+	s.True(color.Enabled(&b))
+	color.Fprint(&b, payloadColor)
+	s.Equal(payloadColor, b.String())
+
+	// FORCE_COLOR takes precedence over NO_COLOR.
+	s.T().Setenv("NO_COLOR", "1")
+	s.True(color.Enabled(&b))
+
+	s.T().Setenv("FORCE_COLOR", "0")
+	s.False(color.Enabled(&b))
+
+	os.Unsetenv("FORCE_COLOR")
+	s.False(color.Enabled(&b))
+
+	// The mode takes precedence over the environment.
+	color.SetMode(color.ModeAlways)
+	s.True(color.Enabled(&b))
+
+	s.T().Setenv("FORCE_COLOR", "1")
+	color.SetMode(color.ModeNever)
+	s.False(color.Enabled(&b))
+
+	mode, err := color.ParseMode("never")
+	s.NoError(err)
+	s.Equal(color.ModeNever, mode)
+
+	_, err = color.ParseMode("rainbow")
+	s.Error(err)
+}
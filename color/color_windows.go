@@ -7,14 +7,19 @@ import (
 )
 
 func init() {
-	var outMode uint32
+	enableVT(os.Stdout)
+	enableVT(os.Stderr)
+}
+
+// enableVT enables the processing of ANSI escape sequences by the console of f, if any.
+func enableVT(f *os.File) {
+	var mode uint32
 
-	out := windows.Handle(os.Stdout.Fd())
-	if err := windows.GetConsoleMode(out, &outMode); err != nil {
+	handle := windows.Handle(f.Fd())
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
 		return
 	}
 
-	outMode |= windows.ENABLE_PROCESSED_OUTPUT | windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING
-	_ = windows.SetConsoleMode(out, outMode)
-
+	mode |= windows.ENABLE_PROCESSED_OUTPUT | windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING
+	_ = windows.SetConsoleMode(handle, mode)
 }
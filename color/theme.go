@@ -0,0 +1,121 @@
+package color
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Kind is the kind of a label.
+type Kind int
+
+// Kinds of labels.
+const (
+	// KindSuccess labels successes (e.g. OK).
+	KindSuccess Kind = iota
+	// KindWarning labels results that need attention, but are not failures (e.g. SKIPPED).
+	KindWarning
+	// KindFailure labels failures (e.g. MISMATCH).
+	KindFailure
+)
+
+// Theme formats labels according to their Kind.
+type Theme struct {
+	// Name identifies the Theme.
+	Name string
+	// Formats lists the formatting of the labels of each Kind.
+	Formats map[Kind]string
+	// Symbols lists the symbols prefixing the labels of each Kind, so that labels can be told apart
+	// without colors.
+	// Labels are not prefixed if Symbols is nil.
+	Symbols map[Kind]string
+}
+
+// Built-in themes.
+var (
+	// ThemeDefault formats successes in green, warnings in yellow, and failures in red.
+	ThemeDefault = Theme{
+		Name: "default",
+		Formats: map[Kind]string{
+			KindSuccess: Green,
+			KindWarning: Yellow,
+			KindFailure: Red,
+		},
+	}
+	// ThemeHighContrast formats labels in bold, with successes in blue, warnings in yellow, and
+	// failures in reverse video, so that they can be told apart without distinguishing red and
+	// green.
+	ThemeHighContrast = Theme{
+		Name: "high-contrast",
+		Formats: map[Kind]string{
+			KindSuccess: Bold + LightBlue,
+			KindWarning: Bold + LightYellow,
+			KindFailure: Bold + ReverseText,
+		},
+	}
+	// ThemeSymbols formats labels as ThemeDefault, prefixed with ✔, −, or ✘.
+	ThemeSymbols = Theme{
+		Name:    "symbols",
+		Formats: ThemeDefault.Formats,
+		Symbols: map[Kind]string{
+			KindSuccess: "✔",
+			KindWarning: "−",
+			KindFailure: "✘",
+		},
+	}
+)
+
+// Themes lists the built-in themes.
+var Themes = []Theme{ThemeDefault, ThemeHighContrast, ThemeSymbols}
+
+// theme is the current Theme.
+var theme = ThemeDefault
+
+// ParseTheme returns the built-in Theme named name.
+func ParseTheme(name string) (Theme, error) {
+	i := slices.IndexFunc(Themes, func(t Theme) bool {
+		return t.Name == name
+	})
+	if i < 0 {
+		return Theme{}, fmt.Errorf("unknown theme %q", name)
+	}
+	return Themes[i], nil
+}
+
+// SetTheme sets the current Theme.
+// SetTheme is meant to be called once, before any label is formatted.
+func SetTheme(t Theme) {
+	theme = t
+}
+
+// CurrentTheme returns the current Theme.
+func CurrentTheme() Theme {
+	return theme
+}
+
+// Label formats label as a label of kind with the current Theme.
+func Label(kind Kind, label string) string {
+	return theme.Label(kind, label)
+}
+
+// Label formats label as a label of kind.
+func (t Theme) Label(kind Kind, label string) string {
+	if symbol, ok := t.Symbols[kind]; ok {
+		label = symbol + " " + label
+	}
+	if format := t.Formats[kind]; format != "" {
+		label = format + label + Reset
+	}
+	return label
+}
+
+// Plain strips label of its formatting and of the symbols of the current Theme.
+func Plain(label string) string {
+	label = CleanString(label)
+	for _, symbol := range theme.Symbols {
+		if s, ok := strings.CutPrefix(label, symbol+" "); ok {
+			return s
+		}
+	}
+	return label
+}
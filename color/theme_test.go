@@ -0,0 +1,26 @@
+package color_test
+
+import (
+	"github.com/ghifari160/medhash-tools/color"
+)
+
+func (s *ColorSuite) TestTheme() {
+	defer color.SetTheme(color.ThemeDefault)
+
+	for _, theme := range color.Themes {
+		parsed, err := color.ParseTheme(theme.Name)
+		s.NoError(err)
+		s.Equal(theme.Name, parsed.Name)
+	}
+	_, err := color.ParseTheme("rainbow")
+	s.Error(err)
+
+	s.Equal(color.Green+"OK"+color.Reset, color.ThemeDefault.Label(color.KindSuccess, "OK"))
+	s.Equal(color.Red+"✘ ERROR"+color.Reset, color.ThemeSymbols.Label(color.KindFailure, "ERROR"))
+	s.Equal("SKIPPED", color.Theme{}.Label(color.KindWarning, "SKIPPED"))
+
+	color.SetTheme(color.ThemeSymbols)
+	s.Equal("− SKIPPED", color.CleanString(color.Label(color.KindWarning, "SKIPPED")))
+	s.Equal("SKIPPED", color.Plain(color.Label(color.KindWarning, "SKIPPED")))
+	s.Equal("OK", color.Plain(color.ThemeHighContrast.Label(color.KindSuccess, "OK")))
+}
@@ -0,0 +1,9 @@
+// Package hashdeep reads and writes hashdeep files, and audits files against them.
+//
+// hashdeep files are CSV files with a size column, a column for each algorithm, and a filename
+// column, preceded by a "%%%% HASHDEEP-1.0" header.
+// Only the MD5, SHA1, and SHA256 hashes are shared by hashdeep and MedHash.
+// Other hashdeep columns (e.g. tiger and whirlpool) are ignored when reading.
+//
+// Audits classify files as hashdeep does in audit mode: matched, moved, new, or missing.
+package hashdeep
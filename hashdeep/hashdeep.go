@@ -0,0 +1,270 @@
+package hashdeep
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ghifari160/medhash-tools/medhash"
+)
+
+// FormatName is the name of the hashdeep format, as accepted by the import and export commands.
+const FormatName = "hashdeep"
+
+// Header is the first line of a hashdeep file.
+const Header = "%%%% HASHDEEP-1.0"
+
+// Algorithms lists the Hash field names of the algorithms shared by hashdeep and MedHash, in the
+// order hashdeep writes their columns.
+var Algorithms = []string{"md5", "sha1", "sha256"}
+
+// Entry is a media with its size, as listed by hashdeep.
+type Entry struct {
+	medhash.Media
+	// Size is the size of the media in bytes.
+	// A negative Size is unknown, as for media listed by a MedHash Manifest.
+	Size int64
+}
+
+// Stat returns the Entry of each media, with its size read from root.
+func Stat(root string, medias []medhash.Media) ([]Entry, error) {
+	entries := make([]Entry, 0, len(medias))
+	for _, med := range medias {
+		info, err := os.Stat(filepath.Join(root, filepath.FromSlash(med.Path)))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{Media: med, Size: info.Size()})
+	}
+	return entries, nil
+}
+
+// Write writes the algs hashes of entries to w as a hashdeep file.
+// algs are Hash field names, and must be a subset of Algorithms.
+// It is an error for an entry to lack any of the algs hashes.
+func Write(w io.Writer, algs []string, entries []Entry) error {
+	columns := []string{"size"}
+	for _, alg := range Algorithms {
+		if slices.Contains(algs, alg) {
+			columns = append(columns, alg)
+		}
+	}
+	if len(columns) < 2 {
+		return errors.New("no md5, sha1, or sha256 algorithm")
+	}
+	columns = append(columns, "filename")
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, Header)
+	fmt.Fprintln(bw, "%%%% "+strings.Join(columns, ","))
+	fmt.Fprintln(bw, "##")
+
+	for _, entry := range entries {
+		fields := []string{strconv.FormatInt(entry.Size, 10)}
+		for _, alg := range columns[1 : len(columns)-1] {
+			digest := entry.Hash.Get(alg)
+			if digest == "" {
+				return fmt.Errorf("%s: no %s hash", entry.Path, alg)
+			}
+			fields = append(fields, digest)
+		}
+		fields = append(fields, entry.Path)
+		fmt.Fprintln(bw, strings.Join(fields, ","))
+	}
+
+	return bw.Flush()
+}
+
+// Read reads a hashdeep file from r.
+// Lines starting with # are skipped.
+// Entries are returned in the order they appear in r.
+func Read(r io.Reader) ([]Entry, error) {
+	scanner := bufio.NewScanner(r)
+
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != Header {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("not a hashdeep file")
+	}
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("no hashdeep column header")
+	}
+	header, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "%%%% ")
+	if !ok {
+		return nil, fmt.Errorf("malformed column header: %q", scanner.Text())
+	}
+	columns := strings.Split(header, ",")
+	if len(columns) < 2 || columns[0] != "size" || columns[len(columns)-1] != "filename" {
+		return nil, fmt.Errorf("unsupported columns: %s", header)
+	}
+
+	entries := make([]Entry, 0)
+	for n := 3; scanner.Scan(); n++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// The filename is the last column, and may contain commas.
+		fields := strings.SplitN(line, ",", len(columns))
+		if len(fields) != len(columns) {
+			return nil, fmt.Errorf("line %d: malformed line: %q", n, line)
+		}
+
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: malformed size: %q", n, fields[0])
+		}
+
+		entry := Entry{Media: medhash.Media{Path: fields[len(fields)-1]}, Size: size}
+		for i, column := range columns[1 : len(columns)-1] {
+			if slices.Contains(Algorithms, column) {
+				entry.Hash.Set(column, strings.ToLower(fields[i+1]))
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// Status is the audit classification of a file.
+type Status string
+
+const (
+	// StatusMatched is a file matching a known file of the same path.
+	StatusMatched Status = "matched"
+	// StatusMoved is a file matching a known file of another path.
+	StatusMoved Status = "moved"
+	// StatusNew is a file not matching any known file.
+	StatusNew Status = "new"
+	// StatusMissing is a known file not matched by any file.
+	StatusMissing Status = "missing"
+)
+
+// Result is the audit result of a file.
+type Result struct {
+	Path   string
+	Status Status
+	// Known is the path of the matching known file of a moved file.
+	Known string
+}
+
+// Report is the result of an audit.
+// Results lists the results of the audited files in order, followed by the missing known files.
+type Report struct {
+	Results []Result
+	Counts  map[Status]int
+}
+
+// Passed reports whether every file matched a known file, and every known file was matched.
+// As in hashdeep, moved files do not fail an audit.
+func (report Report) Passed() bool {
+	return report.Counts[StatusNew] == 0 && report.Counts[StatusMissing] == 0
+}
+
+// Audit classifies files against known.
+// Two entries match if all of their shared hashes are equal, and their sizes are equal when both
+// are known.
+// Entries without a shared hash never match.
+func Audit(known, files []Entry) Report {
+	report := Report{
+		Results: make([]Result, 0, len(files)),
+		Counts:  make(map[Status]int),
+	}
+
+	byPath := make(map[string]int, len(known))
+	byDigest := make(map[string][]int)
+	for i, entry := range known {
+		byPath[entry.Path] = i
+		for _, alg := range medhash.Algorithms() {
+			if digest := entry.Hash.Get(alg); digest != "" {
+				byDigest[alg+":"+digest] = append(byDigest[alg+":"+digest], i)
+			}
+		}
+	}
+	used := make([]bool, len(known))
+
+	for _, file := range files {
+		result := Result{Path: file.Path, Status: StatusNew}
+
+		if i, ok := byPath[file.Path]; ok && match(known[i], file) {
+			result.Status = StatusMatched
+			used[i] = true
+		} else if i, ok := find(known, byDigest, used, file); ok {
+			result.Status = StatusMoved
+			result.Known = known[i].Path
+			used[i] = true
+		}
+
+		report.Results = append(report.Results, result)
+		report.Counts[result.Status]++
+	}
+
+	for i, entry := range known {
+		if !used[i] {
+			report.Results = append(report.Results, Result{Path: entry.Path, Status: StatusMissing})
+			report.Counts[StatusMissing]++
+		}
+	}
+
+	return report
+}
+
+// find returns the index of a known entry matching file, preferring entries that are not used.
+func find(known []Entry, byDigest map[string][]int, used []bool, file Entry) (int, bool) {
+	found := -1
+	for _, alg := range medhash.Algorithms() {
+		digest := file.Hash.Get(alg)
+		if digest == "" {
+			continue
+		}
+
+		for _, i := range byDigest[alg+":"+digest] {
+			if !match(known[i], file) {
+				continue
+			}
+			if !used[i] {
+				return i, true
+			}
+			if found < 0 {
+				found = i
+			}
+		}
+	}
+	return found, found >= 0
+}
+
+// match reports whether a and b match.
+func match(a, b Entry) bool {
+	if a.Size >= 0 && b.Size >= 0 && a.Size != b.Size {
+		return false
+	}
+
+	shared := false
+	for _, alg := range medhash.Algorithms() {
+		da, db := a.Hash.Get(alg), b.Hash.Get(alg)
+		if da == "" || db == "" {
+			continue
+		}
+		if da != db {
+			return false
+		}
+		shared = true
+	}
+	return shared
+}
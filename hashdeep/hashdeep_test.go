@@ -0,0 +1,96 @@
+package hashdeep_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/hashdeep"
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	md5A    = "0cc175b9c0f1b6a831c399e269772661"
+	sha256A = "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"
+	md5B    = "92eb5ffee6ae2fec3ad71c777531578f"
+	sha256B = "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d"
+)
+
+func TestWriteRead(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	entries := []hashdeep.Entry{
+		{Media: medhash.Media{Path: "dir/a", Hash: medhash.Hash{SHA256: sha256A, MD5: md5A}}, Size: 1},
+		{Media: medhash.Media{Path: "b,c", Hash: medhash.Hash{SHA256: sha256B, MD5: md5B}}, Size: 1},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(hashdeep.Write(&buf, []string{"sha256", "md5"}, entries))
+	require.Equal(hashdeep.Header+"\n%%%% size,md5,sha256,filename\n##\n"+
+		"1,"+md5A+","+sha256A+",dir/a\n"+
+		"1,"+md5B+","+sha256B+",b,c\n", buf.String())
+
+	read, err := hashdeep.Read(&buf)
+	require.NoError(err)
+	require.Equal(entries, read)
+
+	require.Error(hashdeep.Write(&buf, []string{"sha1"}, entries))
+	require.Error(hashdeep.Write(&buf, []string{"xxh3"}, entries))
+}
+
+func TestReadUnsupportedColumns(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	input := hashdeep.Header + "\n%%%% size,md5,tiger,filename\n## Invoked from: /\n" +
+		"1," + strings.ToUpper(md5A) + ",abc,/a\n"
+
+	entries, err := hashdeep.Read(strings.NewReader(input))
+	require.NoError(err)
+	require.Equal([]hashdeep.Entry{
+		{Media: medhash.Media{Path: "/a", Hash: medhash.Hash{MD5: md5A}}, Size: 1},
+	}, entries)
+
+	_, err = hashdeep.Read(strings.NewReader(sha256A + "  a\n"))
+	require.Error(err)
+}
+
+func TestAudit(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	a := medhash.Hash{MD5: md5A}
+	b := medhash.Hash{MD5: md5B}
+
+	known := []hashdeep.Entry{
+		{Media: medhash.Media{Path: "matched", Hash: a}, Size: 1},
+		{Media: medhash.Media{Path: "old", Hash: b}, Size: 1},
+		{Media: medhash.Media{Path: "missing", Hash: medhash.Hash{MD5: "00"}}, Size: 1},
+	}
+	files := []hashdeep.Entry{
+		{Media: medhash.Media{Path: "matched", Hash: a}, Size: 1},
+		{Media: medhash.Media{Path: "moved", Hash: b}, Size: -1},
+		{Media: medhash.Media{Path: "new", Hash: medhash.Hash{MD5: "11"}}, Size: 1},
+		{Media: medhash.Media{Path: "resized", Hash: a}, Size: 2},
+	}
+
+	report := hashdeep.Audit(known, files)
+	require.Equal([]hashdeep.Result{
+		{Path: "matched", Status: hashdeep.StatusMatched},
+		{Path: "moved", Status: hashdeep.StatusMoved, Known: "old"},
+		{Path: "new", Status: hashdeep.StatusNew},
+		{Path: "resized", Status: hashdeep.StatusNew},
+		{Path: "missing", Status: hashdeep.StatusMissing},
+	}, report.Results)
+	require.Equal(map[hashdeep.Status]int{
+		hashdeep.StatusMatched: 1,
+		hashdeep.StatusMoved:   1,
+		hashdeep.StatusNew:     2,
+		hashdeep.StatusMissing: 1,
+	}, report.Counts)
+	require.False(report.Passed())
+
+	require.True(hashdeep.Audit(known[:2], files[:2]).Passed())
+}
@@ -2,33 +2,67 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"os"
 
 	"github.com/ghifari160/medhash-tools/cmd"
+	_ "github.com/ghifari160/medhash-tools/cmd/audit"
+	_ "github.com/ghifari160/medhash-tools/cmd/bag"
 	_ "github.com/ghifari160/medhash-tools/cmd/chk"
+	_ "github.com/ghifari160/medhash-tools/cmd/compare"
+	_ "github.com/ghifari160/medhash-tools/cmd/deep"
+	_ "github.com/ghifari160/medhash-tools/cmd/diff"
+	_ "github.com/ghifari160/medhash-tools/cmd/dupes"
+	_ "github.com/ghifari160/medhash-tools/cmd/edit"
+	_ "github.com/ghifari160/medhash-tools/cmd/exporter"
 	_ "github.com/ghifari160/medhash-tools/cmd/gen"
+	_ "github.com/ghifari160/medhash-tools/cmd/hash"
+	_ "github.com/ghifari160/medhash-tools/cmd/importer"
+	_ "github.com/ghifari160/medhash-tools/cmd/pack"
+	_ "github.com/ghifari160/medhash-tools/cmd/repair"
+	_ "github.com/ghifari160/medhash-tools/cmd/unpack"
 	_ "github.com/ghifari160/medhash-tools/cmd/upgrade"
+	_ "github.com/ghifari160/medhash-tools/cmd/watch"
 	"github.com/urfave/cli/v3"
 )
 
-const Name = "MedHash Tools"
-const Version = "0.7.0"
-
 func main() {
 	root := &cli.Command{
-		Name:     "medhash",
-		Usage:    "Simple tool for verifying media file integrity",
-		Commands: cmd.Commands(),
+		Name:         "medhash",
+		Usage:        "Simple tool for verifying media file integrity",
+		Flags:        cmd.LogFlags(),
+		Before:       before,
+		Commands:     cmd.Commands(),
+		OnUsageError: cmd.OnUsageError,
 	}
 
-	printHeader()
 	err := root.Run(context.Background(), os.Args)
 	if err != nil {
-		fmt.Printf("main: %v\n", err)
+		// Commands usually exit on their own with the code of their failure.
+		// Invalid arguments and flags are not always exited on, but are reported with ExitUsage.
+		var exitErr cli.ExitCoder
+		if errors.As(err, &exitErr) {
+			if err.Error() != "" {
+				cmd.Failln(err)
+			}
+			os.Exit(exitErr.ExitCode())
+		}
+
+		cmd.Failf("main: %v", err)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
 
-func printHeader() {
-	fmt.Printf("%s v%s\n", Name, Version)
+// before sets up the log, and logs the header.
+// The version command prints the version itself, so the header is not logged for it.
+func before(ctx context.Context, command *cli.Command) (context.Context, error) {
+	ctx, err := cmd.SetupLog(ctx, command)
+	if err != nil {
+		return ctx, err
+	}
+
+	if command.Args().First() != "version" {
+		cmd.Printf("%s v%s", cmd.Name, cmd.Version)
+	}
+	return ctx, nil
 }
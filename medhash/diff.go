@@ -0,0 +1,168 @@
+package medhash
+
+import (
+	"slices"
+)
+
+// Diff is the difference between two Manifests.
+// Each slice is sorted by path.
+type Diff struct {
+	// Added lists media only listed by the second Manifest.
+	Added []Media `json:"added"`
+	// Removed lists media only listed by the first Manifest.
+	Removed []Media `json:"removed"`
+	// Changed lists media listed by both Manifests with different hashes.
+	Changed []Change `json:"changed"`
+	// Moved lists media listed by both Manifests with the same hashes under different paths.
+	Moved []Move `json:"moved"`
+	// Uncompared lists media listed by both Manifests without a shared hash algorithm.
+	Uncompared []Media `json:"uncompared"`
+}
+
+// Change is a media whose hashes differ between two Manifests.
+type Change struct {
+	Path string `json:"path"`
+	From Hash   `json:"from"`
+	To   Hash   `json:"to"`
+	// Algorithms lists the shared algorithms whose hashes differ.
+	Algorithms []string `json:"algorithms"`
+}
+
+// Move is a media listed under different paths by two Manifests.
+type Move struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Hash Hash   `json:"hash"`
+}
+
+// Empty reports whether diff has no differences.
+// Uncompared media are not differences.
+func (diff Diff) Empty() bool {
+	return len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0 &&
+		len(diff.Moved) == 0
+}
+
+// Compare compares the media listed by a and b, without reading the media.
+// Media of the same path are changed if any hash of a shared algorithm differs.
+// A media removed from a and a media added to b are moved if all of their shared hashes are
+// equal, with at least one shared hash.
+// Child Manifests are not compared.
+func Compare(a, b *Manifest) Diff {
+	diff := Diff{
+		Added:      make([]Media, 0),
+		Removed:    make([]Media, 0),
+		Changed:    make([]Change, 0),
+		Moved:      make([]Move, 0),
+		Uncompared: make([]Media, 0),
+	}
+
+	byPath := make(map[string]Media, len(b.Media))
+	for _, med := range b.Media {
+		byPath[med.Path] = med
+	}
+	inA := make(map[string]bool, len(a.Media))
+
+	var removed []Media
+	for _, from := range a.Media {
+		inA[from.Path] = true
+
+		to, ok := byPath[from.Path]
+		if !ok {
+			removed = append(removed, from)
+			continue
+		}
+
+		shared, algs := cmpHash(from.Hash, to.Hash)
+		if shared == 0 {
+			diff.Uncompared = append(diff.Uncompared, from)
+		} else if len(algs) > 0 {
+			diff.Changed = append(diff.Changed, Change{
+				Path:       from.Path,
+				From:       from.Hash,
+				To:         to.Hash,
+				Algorithms: algs,
+			})
+		}
+	}
+
+	var added []Media
+	for _, med := range b.Media {
+		if !inA[med.Path] {
+			added = append(added, med)
+		}
+	}
+
+	// Removed media are paired with the first matching added media in path order.
+	// Added media are indexed by digest, so that only media sharing a hash are compared.
+	slices.SortStableFunc(removed, mediaCmp)
+	slices.SortStableFunc(added, mediaCmp)
+	byDigest := make(map[string][]int)
+	for i, to := range added {
+		for _, alg := range Algorithms() {
+			if digest := to.Hash.Get(alg); digest != "" {
+				byDigest[alg+":"+digest] = append(byDigest[alg+":"+digest], i)
+			}
+		}
+	}
+	used := make([]bool, len(added))
+	for _, from := range removed {
+		if i, ok := findMove(added, byDigest, used, from); ok {
+			diff.Moved = append(diff.Moved, Move{From: from.Path, To: added[i].Path, Hash: added[i].Hash})
+			used[i] = true
+		} else {
+			diff.Removed = append(diff.Removed, from)
+		}
+	}
+	for i, to := range added {
+		if !used[i] {
+			diff.Added = append(diff.Added, to)
+		}
+	}
+
+	slices.SortStableFunc(diff.Changed, func(a, b Change) int {
+		return mediaCmp(Media{Path: a.Path}, Media{Path: b.Path})
+	})
+	slices.SortStableFunc(diff.Uncompared, mediaCmp)
+
+	return diff
+}
+
+// findMove returns the index of the first unused media of added that from was moved to.
+// byDigest indexes added by algorithm and digest.
+func findMove(added []Media, byDigest map[string][]int, used []bool, from Media) (int, bool) {
+	found := -1
+	for _, alg := range Algorithms() {
+		digest := from.Hash.Get(alg)
+		if digest == "" {
+			continue
+		}
+
+		for _, i := range byDigest[alg+":"+digest] {
+			if used[i] || (found >= 0 && i >= found) {
+				continue
+			}
+			if shared, algs := cmpHash(from.Hash, added[i].Hash); shared > 0 && len(algs) == 0 {
+				found = i
+			}
+		}
+	}
+	return found, found >= 0
+}
+
+// cmpHash compares the hashes of a and b.
+// cmpHash returns the number of algorithms shared by a and b, and the shared algorithms whose
+// hashes differ.
+func cmpHash(a, b Hash) (shared int, algs []string) {
+	algs = make([]string, 0)
+	for _, alg := range Algorithms() {
+		ha, hb := a.Get(alg), b.Get(alg)
+		if ha == "" || hb == "" {
+			continue
+		}
+		shared++
+		if ha != hb {
+			algs = append(algs, alg)
+		}
+	}
+	return
+}
@@ -0,0 +1,90 @@
+package medhash_test
+
+import (
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	a := &medhash.Manifest{Media: []medhash.Media{
+		{Path: "changed.mov", Hash: medhash.Hash{XXH3: "1", SHA256: "a"}},
+		{Path: "kept.mov", Hash: medhash.Hash{XXH3: "2"}},
+		{Path: "old/moved.mov", Hash: medhash.Hash{XXH3: "3", MD5: "c"}},
+		{Path: "removed.mov", Hash: medhash.Hash{XXH3: "4"}},
+		{Path: "uncompared.mov", Hash: medhash.Hash{XXH3: "5"}},
+	}}
+	b := &medhash.Manifest{Media: []medhash.Media{
+		{Path: "added.mov", Hash: medhash.Hash{XXH3: "6"}},
+		{Path: "changed.mov", Hash: medhash.Hash{XXH3: "1", SHA256: "b"}},
+		{Path: "kept.mov", Hash: medhash.Hash{XXH3: "2", MD5: "d"}},
+		{Path: "new/moved.mov", Hash: medhash.Hash{XXH3: "3"}},
+		{Path: "uncompared.mov", Hash: medhash.Hash{MD5: "e"}},
+	}}
+
+	diff := medhash.Compare(a, b)
+	require.False(diff.Empty())
+	require.Equal([]medhash.Media{b.Media[0]}, diff.Added)
+	require.Equal([]medhash.Media{a.Media[3]}, diff.Removed)
+	require.Equal([]medhash.Change{{
+		Path:       "changed.mov",
+		From:       a.Media[0].Hash,
+		To:         b.Media[1].Hash,
+		Algorithms: []string{"sha256"},
+	}}, diff.Changed)
+	require.Equal([]medhash.Move{{
+		From: "old/moved.mov",
+		To:   "new/moved.mov",
+		Hash: b.Media[3].Hash,
+	}}, diff.Moved)
+	require.Equal([]medhash.Media{a.Media[4]}, diff.Uncompared)
+
+	diff = medhash.Compare(a, a)
+	require.True(diff.Empty())
+	require.Empty(diff.Uncompared)
+}
+
+func TestCompareDuplicates(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	a := &medhash.Manifest{Media: []medhash.Media{
+		{Path: "a.mov", Hash: medhash.Hash{XXH3: "1"}},
+		{Path: "b.mov", Hash: medhash.Hash{XXH3: "1"}},
+	}}
+	b := &medhash.Manifest{Media: []medhash.Media{
+		{Path: "c.mov", Hash: medhash.Hash{XXH3: "1"}},
+	}}
+
+	diff := medhash.Compare(a, b)
+	require.Equal([]medhash.Move{{From: "a.mov", To: "c.mov", Hash: b.Media[0].Hash}}, diff.Moved)
+	require.Equal([]medhash.Media{a.Media[1]}, diff.Removed)
+	require.Empty(diff.Added)
+}
+
+func TestCompareMovedOrder(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	// Moves are paired in path order, whichever algorithm they share.
+	a := &medhash.Manifest{Media: []medhash.Media{
+		{Path: "a.mov", Hash: medhash.Hash{XXH3: "1", MD5: "m"}},
+	}}
+	b := &medhash.Manifest{Media: []medhash.Media{
+		{Path: "c.mov", Hash: medhash.Hash{MD5: "m"}},
+		{Path: "d.mov", Hash: medhash.Hash{XXH3: "1"}},
+		{Path: "e.mov", Hash: medhash.Hash{XXH3: "1", MD5: "n"}},
+	}}
+
+	diff := medhash.Compare(a, b)
+	require.Equal([]medhash.Move{{From: "a.mov", To: "c.mov", Hash: b.Media[0].Hash}}, diff.Moved)
+	require.Equal([]medhash.Media{b.Media[1], b.Media[2]}, diff.Added)
+	require.Empty(diff.Removed)
+}
@@ -7,7 +7,9 @@ import (
 	"crypto/sha3"
 	"crypto/sha512"
 	"encoding/hex"
+	"errors"
 	"hash"
+	"hash/crc32"
 	"io"
 	"os"
 	"path/filepath"
@@ -19,6 +21,37 @@ import (
 
 // genHash generates a hash for the media specified in the config path.
 func genHash(config Config, media string) (med Media, err error) {
+	f, err := config.open(media)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	hash, err := HashReader(config, config.progress(filepath.ToSlash(media), f))
+	if err != nil {
+		return
+	}
+
+	med.Path = filepath.ToSlash(media)
+	med.Hash = hash
+
+	return
+}
+
+// open opens media from config.FS, or from config.Dir if config.FS is nil.
+func (config Config) open(media string) (io.ReadCloser, error) {
+	if config.FS != nil {
+		return config.FS.Open(filepath.ToSlash(media))
+	}
+	return os.Open(filepath.Join(config.Dir, media))
+}
+
+// HashReader generates hashes for the data read from r until EOF.
+// Only the hashes toggled in config are generated.
+// r is read at the rate of config.Limiter.
+func HashReader(config Config, r io.Reader) (sum Hash, err error) {
+	r = config.Limiter.Reader(r)
+
 	writers := make([]io.Writer, 0)
 	hashers := make(map[string]hash.Hash)
 
@@ -52,43 +85,40 @@ func genHash(config Config, media string) (med Media, err error) {
 		writers = append(writers, hashers["md5"])
 	}
 
-	writer := io.MultiWriter(writers...)
-
-	f, err := os.Open(filepath.Join(config.Dir, media))
-	if err != nil {
-		return
+	if config.CRC32 {
+		hashers["crc32"] = crc32.NewIEEE()
+		writers = append(writers, hashers["crc32"])
 	}
-	defer f.Close()
 
-	_, err = io.Copy(writer, f)
+	writer := io.MultiWriter(writers...)
+
+	_, err = io.Copy(writer, r)
 	if err != nil {
 		return
 	}
 
-	hash := Hash{}
-
 	if h := hashers["xxh3"]; h != nil {
-		hash.XXH3 = hex.EncodeToString(h.Sum(nil))
+		sum.XXH3 = hex.EncodeToString(h.Sum(nil))
 	}
 	if h := hashers["sha512"]; h != nil {
-		hash.SHA512 = hex.EncodeToString(h.Sum(nil))
+		sum.SHA512 = hex.EncodeToString(h.Sum(nil))
 	}
 	if h := hashers["sha3"]; h != nil {
-		hash.SHA3 = hex.EncodeToString(h.Sum(nil))
-		hash.SHA3_256 = hex.EncodeToString(h.Sum(nil))
+		sum.SHA3 = hex.EncodeToString(h.Sum(nil))
+		sum.SHA3_256 = hex.EncodeToString(h.Sum(nil))
 	}
 	if h := hashers["sha256"]; h != nil {
-		hash.SHA256 = hex.EncodeToString(h.Sum(nil))
+		sum.SHA256 = hex.EncodeToString(h.Sum(nil))
 	}
 	if h := hashers["sha1"]; h != nil {
-		hash.SHA1 = hex.EncodeToString(h.Sum(nil))
+		sum.SHA1 = hex.EncodeToString(h.Sum(nil))
 	}
 	if h := hashers["md5"]; h != nil {
-		hash.MD5 = hex.EncodeToString(h.Sum(nil))
+		sum.MD5 = hex.EncodeToString(h.Sum(nil))
+	}
+	if h := hashers["crc32"]; h != nil {
+		sum.CRC32 = hex.EncodeToString(h.Sum(nil))
 	}
-
-	med.Path = filepath.ToSlash(media)
-	med.Hash = hash
 
 	return
 }
@@ -98,8 +128,28 @@ func genHash(config Config, media string) (med Media, err error) {
 // It is up to the caller to determine which hash are verified by specifying the appropriate flags
 // in config.
 func chkHash(config Config, med Media) (err error) {
-	mediaPath := filepath.FromSlash(med.Path)
+	f, err := config.open(filepath.FromSlash(med.Path))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	return chkReader(config, med, f)
+}
+
+// chkReader verifies the hash for the media against the data read from r until EOF.
+// See chkHash.
+func chkReader(config Config, med Media, r io.Reader) (err error) {
+	sum, err := HashReader(disableMissing(config, med), config.progress(med.Path, r))
+	if err != nil {
+		return
+	}
+
+	return chkSum(config, med, sum)
+}
 
+// disableMissing returns config with the hashes missing from med disabled.
+func disableMissing(config Config, med Media) Config {
 	if med.Hash.XXH3 == "" {
 		config.XXH3 = false
 	}
@@ -118,12 +168,19 @@ func chkHash(config Config, med Media) (err error) {
 	if med.Hash.MD5 == "" {
 		config.MD5 = false
 	}
-
-	chk, err := genHash(config, mediaPath)
-	if err != nil {
-		return
+	if med.Hash.CRC32 == "" {
+		config.CRC32 = false
 	}
 
+	return config
+}
+
+// chkSum verifies the hash for the media against sum, a previously generated hash of its data.
+// See chkHash.
+func chkSum(config Config, med Media, sum Hash) (err error) {
+	config = disableMissing(config, med)
+	chk := Media{Path: med.Path, Hash: sum}
+
 	if config.XXH3 && !hashEq(med.Hash.XXH3, chk.Hash.XXH3) {
 		err = hashErr{"XXH3", med.Hash.XXH3, chk.Hash.XXH3}
 	}
@@ -153,10 +210,17 @@ func chkHash(config Config, med Media) (err error) {
 	if config.MD5 && !hashEq(med.Hash.MD5, chk.Hash.MD5) {
 		err = hashErr{"MD5", med.Hash.MD5, chk.Hash.MD5}
 	}
+	if config.CRC32 && !hashEq(med.Hash.CRC32, chk.Hash.CRC32) {
+		err = hashErr{"CRC32", med.Hash.CRC32, chk.Hash.CRC32}
+	}
 
 	return
 }
 
+// ErrMismatch is the error returned when a hash of a media does not match the data.
+// Use errors.Is to test for it.
+var ErrMismatch = errors.New("hash mismatch")
+
 func hashEq(a, b string) bool {
 	return a == b
 }
@@ -171,3 +235,7 @@ func (err hashErr) Error() string {
 	return "expected " + strings.ToUpper(err.alg) + " hash: " +
 		strconv.Quote(err.expected) + " actual: " + strconv.Quote(err.actual)
 }
+
+func (err hashErr) Is(target error) bool {
+	return target == ErrMismatch
+}
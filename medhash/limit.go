@@ -0,0 +1,71 @@
+package medhash
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Limiter limits the rate media are read at.
+// A Limiter is safe for concurrent use, and the rate is shared by all of its readers.
+type Limiter struct {
+	rate int64
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewLimiter returns a Limiter allowing rate bytes per second.
+// If rate is not positive, reads are not limited.
+func NewLimiter(rate int64) *Limiter {
+	return &Limiter{rate: rate}
+}
+
+// Rate returns the rate of l in bytes per second.
+func (l *Limiter) Rate() int64 {
+	return l.rate
+}
+
+// Reader returns a reader reading from r at the rate of l.
+// If l is nil, r is returned.
+func (l *Limiter) Reader(r io.Reader) io.Reader {
+	if l == nil || l.rate <= 0 {
+		return r
+	}
+	return &limitedReader{l: l, r: r}
+}
+
+// wait blocks until n more bytes can be read.
+func (l *Limiter) wait(n int) {
+	if n <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / l.rate))
+	until := l.next
+	l.mu.Unlock()
+
+	time.Sleep(time.Until(until))
+}
+
+// limitedReader is a reader limited by a Limiter.
+type limitedReader struct {
+	l *Limiter
+	r io.Reader
+}
+
+func (r *limitedReader) Read(p []byte) (n int, err error) {
+	// Reads are capped, so that a large buffer does not read a burst before waiting.
+	if int64(len(p)) > r.l.rate {
+		p = p[:r.l.rate]
+	}
+
+	n, err = r.r.Read(p)
+	r.l.wait(n)
+	return
+}
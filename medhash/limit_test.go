@@ -0,0 +1,39 @@
+package medhash_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/ghifari160/medhash-tools/testcommon"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	data := bytes.Repeat([]byte("a"), 4096)
+
+	unlimited, err := medhash.HashReader(medhash.AllConfig, bytes.NewReader(data))
+	require.NoError(err)
+
+	config := medhash.AllConfig
+	config.Limiter = medhash.NewLimiter(16 * 1024)
+
+	start := time.Now()
+	limited, err := medhash.HashReader(config, bytes.NewReader(data))
+	require.NoError(err)
+	require.GreaterOrEqual(time.Since(start), 200*time.Millisecond)
+	require.Equal(unlimited, limited)
+
+	var nilLimiter *medhash.Limiter
+	r := bytes.NewReader(data)
+	require.Same(r, nilLimiter.Reader(r))
+
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+	config.Dir = dir
+	require.NoError(payload.Check(config))
+}
@@ -1,6 +1,12 @@
 package medhash
 
-const ManifestFormatVer = "0.6.0"
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+)
+
+const ManifestFormatVer = "0.7.0"
 const DefaultManifestName = "medhash.json"
 
 var (
@@ -25,9 +31,15 @@ var (
 
 // Manifest is a MedHash manifest.
 type Manifest struct {
-	Version   string  `json:"version"`
-	Generator string  `json:"generator,omitempty"`
-	Media     []Media `json:"media"`
+	Version   string `json:"version"`
+	Generator string `json:"generator,omitempty"`
+	// Root is the media root the Manifest was generated against.
+	// It is only recorded when the Manifest is stored outside of Root.
+	Root  string  `json:"root,omitempty"`
+	Media []Media `json:"media"`
+	// Manifests lists child Manifests by their path and hash.
+	// Each child Manifest is located in a subdirectory, and lists the media within it.
+	Manifests []Media `json:"manifests,omitempty"`
 
 	Config Config `json:"-"`
 }
@@ -50,11 +62,50 @@ func NewWithConfig(config Config) (man *Manifest, err error) {
 	return
 }
 
+// GenerateFS generates a Manifest of all regular files in fsys using the provided config.
+// The Manifest file itself, as named by config.Manifest, is skipped if it is at the root of fsys.
+// Errors for individual media do not stop the generation, and are joined in the returned error.
+func GenerateFS(fsys fs.FS, config Config) (man *Manifest, err error) {
+	config.FS = fsys
+	man, err = NewWithConfig(config)
+	if err != nil {
+		return
+	}
+
+	var errs []error
+	err = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		if !d.Type().IsRegular() || path == man.Config.Manifest {
+			return nil
+		}
+		errs = append(errs, man.Add(path))
+		return nil
+	})
+	errs = append(errs, err)
+
+	err = errors.Join(errs...)
+	return
+}
+
 // Config configures the hasher.
 type Config struct {
 	// Dir is the path to the target directory.
 	Dir string
+	// FS is the file system media are read from.
+	// If FS is nil, media are read from Dir on the local file system.
+	// Otherwise, media paths are resolved within FS, and Dir is only used in error messages.
+	FS fs.FS
+	// Limiter limits the rate media are read at.
+	// If Limiter is nil, reads are not limited.
+	Limiter *Limiter
+	// Progress is called as media are read by Manifest and Media methods.
+	// If Progress is nil, progress is not reported.
+	Progress ProgressFunc
 	// Manifest is the manifest file name.
+	// It is relative to Dir, unless it is an absolute path.
 	Manifest string
 
 	// XXH3 toggles the XXH3_64 hash generation.
@@ -69,4 +120,77 @@ type Config struct {
 	SHA1 bool
 	// MD5 toggles the MD5 hash generation.
 	MD5 bool
+	// CRC32 toggles the CRC32 (IEEE) checksum generation.
+	CRC32 bool
+}
+
+// ManifestPath returns the path to the manifest file.
+// If config.Manifest is an absolute path, it is returned as is.
+// Otherwise, config.Manifest is joined with config.Dir.
+func (config Config) ManifestPath() string {
+	if filepath.IsAbs(config.Manifest) {
+		return config.Manifest
+	}
+	return filepath.Join(config.Dir, config.Manifest)
+}
+
+// Algorithms returns the names of all supported hash algorithms.
+// Unlike AllConfig, it includes CRC32.
+func Algorithms() []string {
+	config := AllConfig
+	config.CRC32 = true
+	return config.Algorithms()
+}
+
+// Algorithms returns the names of the hash algorithms toggled in config.
+// The names are the Hash field names as they appear in the Manifest, in order of preference.
+func (config Config) Algorithms() []string {
+	algs := make([]string, 0)
+	if config.XXH3 {
+		algs = append(algs, "xxh3")
+	}
+	if config.SHA512 {
+		algs = append(algs, "sha512")
+	}
+	if config.SHA3 {
+		algs = append(algs, "sha3")
+	}
+	if config.SHA256 {
+		algs = append(algs, "sha256")
+	}
+	if config.SHA1 {
+		algs = append(algs, "sha1")
+	}
+	if config.MD5 {
+		algs = append(algs, "md5")
+	}
+	if config.CRC32 {
+		algs = append(algs, "crc32")
+	}
+	return algs
+}
+
+// Toggle toggles the hash generation of alg.
+// alg is the Hash field name as it appears in the Manifest (see Config.Algorithms).
+// Toggle returns false for unknown algorithms.
+func (config *Config) Toggle(alg string, enabled bool) bool {
+	switch alg {
+	case "xxh3":
+		config.XXH3 = enabled
+	case "sha512":
+		config.SHA512 = enabled
+	case "sha3", "sha3-256":
+		config.SHA3 = enabled
+	case "sha256":
+		config.SHA256 = enabled
+	case "sha1":
+		config.SHA1 = enabled
+	case "md5":
+		config.MD5 = enabled
+	case "crc32":
+		config.CRC32 = enabled
+	default:
+		return false
+	}
+	return true
 }
@@ -1,7 +1,9 @@
 package medhash
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"path/filepath"
 	"slices"
 	"strings"
@@ -19,6 +21,21 @@ func (media Media) Check(config Config) error {
 	return mediaErrOrNil(config, media, chkHash(config, media))
 }
 
+// CheckReader checks hashes for media against the data read from r until EOF.
+// Hashes for the media are verified at the same time.
+// CheckReader allows media to be verified without being stored on a file system, such as when it
+// is streamed from an archive.
+func (media Media) CheckReader(config Config, r io.Reader) error {
+	return mediaErrOrNil(config, media, chkReader(config, media, r))
+}
+
+// CheckHash checks hashes for media against hash, a previously generated hash of its data.
+// Hashes for the media are verified at the same time.
+// Only the hashes toggled in config are verified, and hash must contain all of them.
+func (media Media) CheckHash(config Config, hash Hash) error {
+	return mediaErrOrNil(config, media, chkSum(config, media, hash))
+}
+
 // Add adds media to man and generates the appropriate hashes as configured.
 // Add also sorts the man.Media slice.
 func (man *Manifest) Add(media string) error {
@@ -33,6 +50,91 @@ func (man *Manifest) Add(media string) error {
 	return nil
 }
 
+// AddManifest adds a child Manifest to man and generates the appropriate hashes as configured.
+// AddManifest also sorts the man.Manifests slice.
+func (man *Manifest) AddManifest(manifest string) error {
+	med, err := genHash(man.Config, manifest)
+	if err != nil {
+		return err
+	}
+
+	man.Manifests = append(man.Manifests, med)
+	slices.SortStableFunc(man.Manifests, mediaCmp)
+
+	return nil
+}
+
+// AddReader adds media to man with the hashes generated from the data read from r until EOF, as
+// configured.
+// AddReader also sorts the man.Media slice.
+func (man *Manifest) AddReader(media string, r io.Reader) error {
+	hash, err := HashReader(man.Config, man.Config.progress(filepath.ToSlash(media), r))
+	if err != nil {
+		return err
+	}
+
+	man.AddHash(media, hash)
+
+	return nil
+}
+
+// AddHash adds media with previously generated hashes to man.
+// AddHash allows Manifests to be built from other sources, such as checksum files, without reading
+// the media.
+// AddHash also sorts the man.Media slice.
+func (man *Manifest) AddHash(media string, hash Hash) {
+	man.AddMedia(Media{Path: media, Hash: hash})
+}
+
+// AddMedia adds medias with previously generated hashes to man, as AddHash does.
+// man.Media is sorted once all medias are added, making AddMedia preferable to AddHash for adding
+// many medias.
+func (man *Manifest) AddMedia(medias ...Media) {
+	for _, med := range medias {
+		med.Path = filepath.ToSlash(med.Path)
+		man.Media = append(man.Media, med)
+	}
+	man.sortMedia()
+}
+
+// Rename renames media in man, from each key of paths to its value, without reading the media.
+// Rename also sorts the man.Media slice.
+// It is an error for any key of paths not to be in man.
+func (man *Manifest) Rename(paths map[string]string) error {
+	var errs []error
+	renamed := make(map[int]string, len(paths))
+	for from, to := range paths {
+		index, found := slices.BinarySearchFunc(man.Media, Media{Path: from}, mediaCmp)
+		if !found {
+			errs = append(errs, fmt.Errorf("media %s not in manifest", from))
+			continue
+		}
+		renamed[index] = filepath.ToSlash(to)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	for index, to := range renamed {
+		man.Media[index].Path = to
+	}
+	man.sortMedia()
+
+	return nil
+}
+
+// Remove removes media from man, without reading the media.
+// man.Media remains sorted.
+func (man *Manifest) Remove(media string) error {
+	index, found := slices.BinarySearchFunc(man.Media, Media{Path: media}, mediaCmp)
+	if !found {
+		return fmt.Errorf("media %s not in manifest", media)
+	}
+	man.Media = slices.Delete(man.Media, index, index+1)
+
+	return nil
+}
+
 // Check checks hashes for media.
 // Hashes for the media are verified at the same time.
 func (man *Manifest) Check(media string) error {
@@ -106,4 +208,60 @@ type Hash struct {
 	SHA3_256 string `json:"sha3-256,omitempty"`
 	SHA1     string `json:"sha1,omitempty"`
 	MD5      string `json:"md5,omitempty"`
+	CRC32    string `json:"crc32,omitempty"`
+}
+
+// Get returns the hash for alg.
+// alg is the Hash field name as it appears in the Manifest (see Config.Algorithms).
+// For SHA3, Get falls back to the deprecated SHA3_256 field.
+// Get returns an empty string for unknown algorithms.
+func (hash Hash) Get(alg string) string {
+	switch alg {
+	case "xxh3":
+		return hash.XXH3
+	case "sha512":
+		return hash.SHA512
+	case "sha3", "sha3-256":
+		if hash.SHA3 != "" {
+			return hash.SHA3
+		}
+		return hash.SHA3_256
+	case "sha256":
+		return hash.SHA256
+	case "sha1":
+		return hash.SHA1
+	case "md5":
+		return hash.MD5
+	case "crc32":
+		return hash.CRC32
+	default:
+		return ""
+	}
+}
+
+// Set sets the hash for alg to value.
+// alg is the Hash field name as it appears in the Manifest (see Config.Algorithms).
+// Setting SHA3 also sets the deprecated SHA3_256 field, as generated hashes do.
+// Set returns false for unknown algorithms.
+func (hash *Hash) Set(alg, value string) bool {
+	switch alg {
+	case "xxh3":
+		hash.XXH3 = value
+	case "sha512":
+		hash.SHA512 = value
+	case "sha3", "sha3-256":
+		hash.SHA3 = value
+		hash.SHA3_256 = value
+	case "sha256":
+		hash.SHA256 = value
+	case "sha1":
+		hash.SHA1 = value
+	case "md5":
+		hash.MD5 = value
+	case "crc32":
+		hash.CRC32 = value
+	default:
+		return false
+	}
+	return true
 }
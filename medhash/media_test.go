@@ -1,8 +1,10 @@
 package medhash_test
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
+	"testing/fstest"
 
 	"github.com/ghifari160/medhash-tools/medhash"
 	"github.com/ghifari160/medhash-tools/testcommon"
@@ -276,3 +278,133 @@ func testGenHash(t *testing.T, alg string, assertFn func(t testing.TB, a *assert
 	require.NoError(man.Add(payload.Path))
 	assertFn(t, assert.New(t), man, payload)
 }
+
+func TestHashReader(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+
+	f, err := os.Open(filepath.Join(dir, payload.Path))
+	require.NoError(err)
+	defer f.Close()
+
+	config := medhash.AllConfig
+	config.CRC32 = true
+	hash, err := medhash.HashReader(config, f)
+	require.NoError(err)
+
+	payload.Hash.SHA3_256 = payload.Hash.SHA3
+	require.Equal(payload.Hash, hash)
+}
+
+func TestAlgorithms(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	require.NotContains(medhash.AllConfig.Algorithms(), "crc32")
+	require.Equal(append(medhash.AllConfig.Algorithms(), "crc32"), medhash.Algorithms())
+}
+
+func TestGenerateFS(t *testing.T) {
+	t.Parallel()
+
+	t.Run("dir", func(t *testing.T) {
+		t.Parallel()
+
+		require := require.New(t)
+		dir := t.TempDir()
+		payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+
+		man, err := medhash.GenerateFS(os.DirFS(dir), medhash.AllConfig)
+		require.NoError(err)
+		require.Len(man.Media, 1)
+		require.Equal(payload.Path, man.Media[0].Path)
+		for _, alg := range medhash.AllConfig.Algorithms() {
+			require.Equal(payload.Hash.Get(alg), man.Media[0].Hash.Get(alg))
+		}
+		require.NoError(man.Check(payload.Path))
+	})
+
+	t.Run("map", func(t *testing.T) {
+		t.Parallel()
+
+		require := require.New(t)
+		fsys := fstest.MapFS{
+			"a":                         {Data: []byte("a")},
+			"sub/b":                     {Data: []byte("b")},
+			medhash.DefaultManifestName: {Data: []byte("{}")},
+		}
+
+		man, err := medhash.GenerateFS(fsys, medhash.DefaultConfig)
+		require.NoError(err)
+		require.Len(man.Media, 2)
+		require.Equal("a", man.Media[0].Path)
+		require.Equal("sub/b", man.Media[1].Path)
+		require.NoError(man.Check("sub/b"))
+
+		fsys["sub/b"] = &fstest.MapFile{Data: []byte("c")}
+		require.Error(man.Check("sub/b"))
+
+		delete(fsys, "a")
+		require.Error(man.Check("a"))
+	})
+}
+
+func TestRename(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	man, err := medhash.New()
+	require.NoError(err)
+	man.AddHash("a", medhash.Hash{XXH3: "1"})
+	man.AddHash("b", medhash.Hash{XXH3: "2"})
+	man.AddHash("c", medhash.Hash{XXH3: "3"})
+
+	require.NoError(man.Rename(map[string]string{"a": "z/a", "c": "0"}))
+	require.Equal([]medhash.Media{
+		{Path: "0", Hash: medhash.Hash{XXH3: "3"}},
+		{Path: "b", Hash: medhash.Hash{XXH3: "2"}},
+		{Path: "z/a", Hash: medhash.Hash{XXH3: "1"}},
+	}, man.Media)
+
+	require.Error(man.Rename(map[string]string{"a": "x", "b": "y"}))
+	require.Equal("b", man.Media[1].Path)
+}
+
+func TestAddMedia(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	man, err := medhash.New()
+	require.NoError(err)
+	man.AddHash("b", medhash.Hash{XXH3: "2"})
+	man.AddMedia(
+		medhash.Media{Path: "c", Hash: medhash.Hash{XXH3: "3"}},
+		medhash.Media{Path: "a", Hash: medhash.Hash{XXH3: "1"}},
+	)
+	require.Equal([]medhash.Media{
+		{Path: "a", Hash: medhash.Hash{XXH3: "1"}},
+		{Path: "b", Hash: medhash.Hash{XXH3: "2"}},
+		{Path: "c", Hash: medhash.Hash{XXH3: "3"}},
+	}, man.Media)
+}
+
+func TestRemove(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	man, err := medhash.New()
+	require.NoError(err)
+	man.AddHash("a", medhash.Hash{XXH3: "1"})
+	man.AddHash("b", medhash.Hash{XXH3: "2"})
+
+	require.NoError(man.Remove("a"))
+	require.Equal([]medhash.Media{{Path: "b", Hash: medhash.Hash{XXH3: "2"}}}, man.Media)
+	require.Error(man.Remove("a"))
+}
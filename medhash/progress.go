@@ -0,0 +1,35 @@
+package medhash
+
+import "io"
+
+// ProgressFunc reports the progress of reading media.
+// It is called with the path of the media, and the number of bytes of it read so far, every time
+// the media is read from.
+// A ProgressFunc is called from the goroutine reading the media.
+type ProgressFunc func(media string, read int64)
+
+// progress returns a reader reporting the progress of reading media from r to config.Progress.
+// If config.Progress is nil, r is returned.
+func (config Config) progress(media string, r io.Reader) io.Reader {
+	if config.Progress == nil {
+		return r
+	}
+	return &progressReader{media: media, r: r, f: config.Progress}
+}
+
+// progressReader is a reader reporting its progress to a ProgressFunc.
+type progressReader struct {
+	media string
+	r     io.Reader
+	f     ProgressFunc
+	read  int64
+}
+
+func (r *progressReader) Read(p []byte) (n int, err error) {
+	n, err = r.r.Read(p)
+	if n > 0 {
+		r.read += int64(n)
+		r.f(r.media, r.read)
+	}
+	return
+}
@@ -0,0 +1,39 @@
+package medhash_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/ghifari160/medhash-tools/testcommon"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgress(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+	payload := testcommon.GenPayload(t, dir, testcommon.PayloadSize())
+
+	reads := make(map[string]int64)
+	config := medhash.DefaultConfig
+	config.Dir = dir
+	config.Progress = func(media string, read int64) {
+		require.Greater(read, reads[media])
+		reads[media] = read
+	}
+
+	man, err := medhash.NewWithConfig(config)
+	require.NoError(err)
+	require.NoError(man.Add(payload.Path))
+	require.Equal(map[string]int64{payload.Path: testcommon.PayloadSize()}, reads)
+
+	clear(reads)
+	require.NoError(payload.Check(config))
+	require.Equal(map[string]int64{payload.Path: testcommon.PayloadSize()}, reads)
+
+	clear(reads)
+	require.NoError(man.AddReader("stream", bytes.NewReader([]byte("stream"))))
+	require.Equal(map[string]int64{"stream": 6}, reads)
+}
@@ -0,0 +1,11 @@
+// Package mhl reads and writes ASC Media Hash Lists (MHL).
+//
+// ASC MHL v2 hash lists are written, optionally as part of an ascmhl history folder.
+// Both ASC MHL v2 and legacy MHL v1 hash lists are read.
+// Hash lists are converted to and from medhash.Media, so that they can be imported into and
+// exported from a MedHash Manifest.
+//
+// Only the XXH3 (64-bit), SHA1, and MD5 hashes are shared by MHL and MedHash.
+// Other MHL hashes (e.g. xxh64, xxh128, and c4) are not verified by MedHash, and are ignored when
+// reading: media hashed only with them, as ascmhl does by default, are read without hashes.
+package mhl
@@ -0,0 +1,326 @@
+package mhl
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ghifari160/medhash-tools/medhash"
+)
+
+// FormatName is the name of the MHL format, as accepted by the import and export commands.
+const FormatName = "mhl"
+
+const (
+	// Version is the version of the ASC MHL hash lists written by this package.
+	Version = "2.0"
+	// Namespace is the XML namespace of ASC MHL v2 hash lists.
+	Namespace = "urn:ASC:MHL:v2.0"
+	// ChainNamespace is the XML namespace of ASC MHL v2 chain files.
+	ChainNamespace = "urn:ASC:MHL:DIRECTORY:v2.0"
+	// HistoryDir is the name of the ascmhl history folder in the media root.
+	HistoryDir = "ascmhl"
+	// ChainFile is the name of the chain file in the ascmhl history folder.
+	ChainFile = "ascmhl_chain.xml"
+)
+
+// algs maps the Hash field names to the MHL hash element names.
+// The MHL xxh3 element is the 64-bit XXH3 hash, as in MedHash.
+var algs = map[string]string{
+	"xxh3": "xxh3",
+	"sha1": "sha1",
+	"md5":  "md5",
+}
+
+// unverified lists the MHL hash element names that MedHash does not verify.
+// ascmhl hashes with xxh64 by default.
+var unverified = []string{"xxh32", "xxh64", "xxh128", "c4", "xxhash", "xxhash64", "xxhash64be"}
+
+// Creator identifies the tool that created a hash list.
+type Creator struct {
+	Tool     string
+	Version  string
+	Hostname string
+	// Date is the creation date of the hash list, and the hash date of its hashes.
+	Date time.Time
+}
+
+// Entry is a media with the file information required by MHL.
+type Entry struct {
+	medhash.Media
+	Size    int64
+	ModTime time.Time
+}
+
+// Stat returns the Entry of each media, with its file information read from root.
+func Stat(root string, medias []medhash.Media) ([]Entry, error) {
+	entries := make([]Entry, 0, len(medias))
+	for _, med := range medias {
+		info, err := os.Stat(filepath.Join(root, filepath.FromSlash(med.Path)))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{Media: med, Size: info.Size(), ModTime: info.ModTime()})
+	}
+	return entries, nil
+}
+
+type hashList struct {
+	XMLName xml.Name    `xml:"urn:ASC:MHL:v2.0 hashlist"`
+	Version string      `xml:"version,attr"`
+	Creator creatorInfo `xml:"creatorinfo"`
+	Process processInfo `xml:"processinfo"`
+	Hashes  []hashEntry `xml:"hashes>hash"`
+}
+
+type creatorInfo struct {
+	CreationDate string `xml:"creationdate"`
+	Hostname     string `xml:"hostname"`
+	Tool         tool   `xml:"tool"`
+}
+
+type tool struct {
+	Version string `xml:"version,attr"`
+	Name    string `xml:",chardata"`
+}
+
+type processInfo struct {
+	Process string   `xml:"process"`
+	Ignore  []string `xml:"ignore>pattern,omitempty"`
+}
+
+type hashEntry struct {
+	Path    hashPath `xml:"path"`
+	Digests []digest `xml:",any"`
+}
+
+type hashPath struct {
+	Size    int64  `xml:"size,attr"`
+	ModTime string `xml:"lastmodificationdate,attr,omitempty"`
+	Path    string `xml:",chardata"`
+}
+
+type digest struct {
+	XMLName  xml.Name
+	Action   string `xml:"action,attr,omitempty"`
+	HashDate string `xml:"hashdate,attr,omitempty"`
+	Value    string `xml:",chardata"`
+}
+
+// Write writes entries to w as an ASC MHL v2 hash list.
+// Every shared hash of each entry is written.
+// It is an error for an entry to lack all shared hashes.
+// ignores are recorded as the ignore patterns of the hash list.
+func Write(w io.Writer, creator Creator, ignores []string, entries []Entry) error {
+	date := creator.Date.Format(time.RFC3339)
+
+	list := hashList{
+		Version: Version,
+		Creator: creatorInfo{
+			CreationDate: date,
+			Hostname:     creator.Hostname,
+			Tool:         tool{Version: creator.Version, Name: creator.Tool},
+		},
+		Process: processInfo{Process: "in-place", Ignore: ignores},
+		Hashes:  make([]hashEntry, 0, len(entries)),
+	}
+
+	for _, entry := range entries {
+		hash := hashEntry{
+			Path: hashPath{
+				Size:    entry.Size,
+				ModTime: entry.ModTime.Format(time.RFC3339),
+				Path:    entry.Path,
+			},
+		}
+
+		for _, alg := range medhash.Algorithms() {
+			name, ok := algs[alg]
+			if !ok {
+				continue
+			}
+			if value := entry.Hash.Get(alg); value != "" {
+				hash.Digests = append(hash.Digests, digest{
+					XMLName:  xml.Name{Local: name},
+					Action:   "original",
+					HashDate: date,
+					Value:    value,
+				})
+			}
+		}
+		if len(hash.Digests) < 1 {
+			return fmt.Errorf("%s: no xxh3, sha1, or md5 hash", entry.Path)
+		}
+
+		list.Hashes = append(list.Hashes, hash)
+	}
+
+	return encode(w, list)
+}
+
+// encode writes v to w as an indented XML document.
+func encode(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// readList accepts both ASC MHL v2 and legacy MHL v1 hash lists.
+type readList struct {
+	Hashes []readHash `xml:"hashes>hash"`
+	Legacy []readHash `xml:"hash"`
+}
+
+type readHash struct {
+	Path    string   `xml:"path"`
+	File    string   `xml:"file"`
+	Digests []digest `xml:",any"`
+}
+
+// Read reads an ASC MHL v2 or a legacy MHL v1 hash list from r.
+// Hashes not shared with MedHash are ignored.
+// Medias with only unverified hashes, such as the xxh64 and xxh128 hashes of ascmhl, are returned
+// with an empty Hash.
+// It is an error for a media to lack all hashes.
+// Medias are returned in the order they appear in r.
+func Read(r io.Reader) ([]medhash.Media, error) {
+	var list readList
+	if err := xml.NewDecoder(r).Decode(&list); err != nil {
+		return nil, err
+	}
+
+	medias := make([]medhash.Media, 0, len(list.Hashes)+len(list.Legacy))
+	for _, hash := range append(list.Hashes, list.Legacy...) {
+		med := medhash.Media{Path: strings.TrimSpace(hash.Path)}
+		if med.Path == "" {
+			med.Path = strings.TrimSpace(hash.File)
+		}
+		if med.Path == "" {
+			return nil, errors.New("hash without path")
+		}
+
+		hashed := false
+		for _, d := range hash.Digests {
+			for alg, name := range algs {
+				if strings.EqualFold(d.XMLName.Local, name) {
+					med.Hash.Set(alg, strings.ToLower(strings.TrimSpace(d.Value)))
+					hashed = true
+				}
+			}
+			if slices.ContainsFunc(unverified, func(name string) bool {
+				return strings.EqualFold(d.XMLName.Local, name)
+			}) {
+				hashed = true
+			}
+		}
+		if !hashed {
+			return nil, fmt.Errorf("%s: no hash", med.Path)
+		}
+
+		medias = append(medias, med)
+	}
+
+	return medias, nil
+}
+
+type chain struct {
+	XMLName xml.Name     `xml:"urn:ASC:MHL:DIRECTORY:v2.0 ascmhldirectory"`
+	Lists   []chainEntry `xml:"hashlist"`
+}
+
+type chainEntry struct {
+	SequenceNr int    `xml:"sequencenr,attr"`
+	Path       string `xml:"path"`
+	C4         string `xml:"c4"`
+}
+
+// WriteHistory writes entries as the next generation of the ascmhl history folder in root.
+// The folder and its chain file are created if they do not exist.
+// The path of the written hash list is returned.
+func WriteHistory(root string, creator Creator, ignores []string, entries []Entry) (string, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(abs, HistoryDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	var ch chain
+	chainFile, err := os.ReadFile(filepath.Join(dir, ChainFile))
+	if err == nil {
+		if err := xml.Unmarshal(chainFile, &ch); err != nil {
+			return "", fmt.Errorf("%s: %w", ChainFile, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	seq := len(ch.Lists) + 1
+	name := fmt.Sprintf("%04d_%s_%s.mhl", seq, filepath.Base(abs),
+		creator.Date.UTC().Format("2006-01-02_150405Z"))
+
+	var buf bytes.Buffer
+	if err := Write(&buf, creator, ignores, entries); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return "", err
+	}
+
+	id, err := C4(&buf)
+	if err != nil {
+		return "", err
+	}
+	ch.Lists = append(ch.Lists, chainEntry{SequenceNr: seq, Path: name, C4: id})
+
+	buf.Reset()
+	if err := encode(&buf, ch); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(filepath.Join(dir, ChainFile), buf.Bytes(), 0644)
+}
+
+const c4Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// C4 returns the C4 ID of the data read from r until EOF.
+// MHL chain files identify hash lists by their C4 ID.
+func C4(r io.Reader) (string, error) {
+	h := sha512.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+
+	n := new(big.Int).SetBytes(h.Sum(nil))
+	base := big.NewInt(int64(len(c4Alphabet)))
+	mod := new(big.Int)
+
+	id := []byte(strings.Repeat("1", 88))
+	for i := len(id) - 1; n.Sign() > 0; i-- {
+		n.DivMod(n, base, mod)
+		id[i] = c4Alphabet[mod.Int64()]
+	}
+
+	return "c4" + string(id), nil
+}
@@ -0,0 +1,180 @@
+package mhl_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/ghifari160/medhash-tools/mhl"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	md5A  = "0cc175b9c0f1b6a831c399e269772661"
+	sha1A = "86f7e437faa5a7fce15d1ddcb9eaeaea377667b8"
+	xxh3A = "e6c632b61e964e1f"
+)
+
+var creator = mhl.Creator{
+	Tool:     "MedHash Tools Test",
+	Version:  "0.0.0",
+	Hostname: "localhost",
+	Date:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+}
+
+func TestWriteRead(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	entries := []mhl.Entry{
+		{
+			Media: medhash.Media{
+				Path: "dir/a",
+				Hash: medhash.Hash{XXH3: xxh3A, SHA256: "ignored", SHA1: sha1A, MD5: md5A},
+			},
+			Size:    1,
+			ModTime: creator.Date,
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(mhl.Write(&buf, creator, []string{"ascmhl"}, entries))
+	require.Contains(buf.String(), `xmlns="`+mhl.Namespace+`"`)
+	require.Contains(buf.String(), `<path size="1" lastmodificationdate="2024-01-02T03:04:05Z">dir/a</path>`)
+	require.Contains(buf.String(), `<tool version="0.0.0">MedHash Tools Test</tool>`)
+
+	medias, err := mhl.Read(&buf)
+	require.NoError(err)
+	require.Equal([]medhash.Media{
+		{Path: "dir/a", Hash: medhash.Hash{XXH3: xxh3A, SHA1: sha1A, MD5: md5A}},
+	}, medias)
+}
+
+func TestWriteNoSharedHash(t *testing.T) {
+	t.Parallel()
+
+	entries := []mhl.Entry{{Media: medhash.Media{Path: "a", Hash: medhash.Hash{SHA256: "abc"}}}}
+	require.Error(t, mhl.Write(&bytes.Buffer{}, creator, nil, entries))
+}
+
+func TestReadLegacy(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<hashlist version="1.1">
+  <creatorinfo>
+    <name>DIT</name>
+  </creatorinfo>
+  <hash>
+    <file>Clips/A001.mov</file>
+    <size>1</size>
+    <lastmodificationdate>2024-01-02T03:04:05Z</lastmodificationdate>
+    <MD5>` + strings.ToUpper(md5A) + `</MD5>
+    <xxhash64be>0123456789abcdef</xxhash64be>
+    <hashdate>2024-01-02T03:04:05Z</hashdate>
+  </hash>
+</hashlist>
+`
+
+	medias, err := mhl.Read(strings.NewReader(input))
+	require.NoError(err)
+	require.Equal([]medhash.Media{{Path: "Clips/A001.mov", Hash: medhash.Hash{MD5: md5A}}}, medias)
+}
+
+func TestReadUnverified(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	input := `<hashlist version="2.0" xmlns="urn:ASC:MHL:v2.0"><hashes>
+<hash><path size="1">a</path><xxh64 action="original">0123456789abcdef</xxh64></hash>
+<hash><path size="1">b</path><xxh128 action="original">0123456789abcdef0123456789abcdef</xxh128>
+<md5 action="original">` + md5A + `</md5></hash>
+</hashes></hashlist>`
+
+	medias, err := mhl.Read(strings.NewReader(input))
+	require.NoError(err)
+	require.Equal([]medhash.Media{{Path: "a"}, {Path: "b", Hash: medhash.Hash{MD5: md5A}}}, medias)
+}
+
+func TestReadASCMHL(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	f, err := os.Open(filepath.Join("testdata", mhl.HistoryDir, "0001_Card_2024-01-02_030405Z.mhl"))
+	require.NoError(err)
+	defer f.Close()
+
+	medias, err := mhl.Read(f)
+	require.NoError(err)
+	require.Equal([]medhash.Media{{Path: "Clips/A001.mov"}, {Path: "Clips/A002.mov"}}, medias)
+}
+
+func TestReadUnsupported(t *testing.T) {
+	t.Parallel()
+
+	input := `<hashlist version="2.0" xmlns="urn:ASC:MHL:v2.0"><hashes><hash>
+<path size="1">a</path><sha512 action="original">0123456789abcdef</sha512>
+</hash></hashes></hashlist>`
+
+	_, err := mhl.Read(strings.NewReader(input))
+	require.Error(t, err)
+}
+
+func TestWriteHistory(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	root := filepath.Join(t.TempDir(), "Card")
+	require.NoError(os.Mkdir(root, 0755))
+	require.NoError(os.WriteFile(filepath.Join(root, "a"), []byte("a"), 0644))
+
+	entries, err := mhl.Stat(root, []medhash.Media{{Path: "a", Hash: medhash.Hash{MD5: md5A}}})
+	require.NoError(err)
+	require.Equal(int64(1), entries[0].Size)
+
+	first, err := mhl.WriteHistory(root, creator, nil, entries)
+	require.NoError(err)
+	require.Equal(filepath.Join(root, mhl.HistoryDir, "0001_Card_2024-01-02_030405Z.mhl"), first)
+
+	second, err := mhl.WriteHistory(root, creator, nil, entries)
+	require.NoError(err)
+	require.Equal(filepath.Join(root, mhl.HistoryDir, "0002_Card_2024-01-02_030405Z.mhl"), second)
+
+	chainFile, err := os.ReadFile(filepath.Join(root, mhl.HistoryDir, mhl.ChainFile))
+	require.NoError(err)
+
+	var chain struct {
+		Lists []struct {
+			SequenceNr int    `xml:"sequencenr,attr"`
+			Path       string `xml:"path"`
+			C4         string `xml:"c4"`
+		} `xml:"hashlist"`
+	}
+	require.NoError(xml.Unmarshal(chainFile, &chain))
+	require.Len(chain.Lists, 2)
+	require.Equal(2, chain.Lists[1].SequenceNr)
+	require.Equal(filepath.Base(second), chain.Lists[1].Path)
+
+	list, err := os.Open(second)
+	require.NoError(err)
+	defer list.Close()
+	id, err := mhl.C4(list)
+	require.NoError(err)
+	require.Equal(id, chain.Lists[1].C4)
+}
+
+func TestC4(t *testing.T) {
+	t.Parallel()
+
+	id, err := mhl.C4(strings.NewReader(""))
+	require.NoError(t, err)
+	require.Equal(t, "c459dsjfscH38cYeXXYogktxf4Cd9ibshE3BHUo6a58hBXmRQdZrAkZzsWcbWtDg5oQstpDuni4Hirj75GEmTc1sFT", id)
+}
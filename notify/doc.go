@@ -0,0 +1,8 @@
+// Package notify reports file system events of watched directories.
+//
+// Events are read from inotify on Linux.
+// Other platforms are not supported, and NewWatcher returns ErrUnsupported.
+//
+// Watches are not recursive: subdirectories must be watched individually, typically as their
+// Create event is received.
+package notify
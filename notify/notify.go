@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"errors"
+	"strings"
+)
+
+// Op is a set of file system operations.
+type Op uint32
+
+const (
+	// Create is the creation of a file or directory, including one moved into a watched directory.
+	Create Op = 1 << iota
+	// Write is a write to a file.
+	Write
+	// CloseWrite is the closing of a file opened for writing.
+	CloseWrite
+	// Remove is the removal of a file or directory.
+	Remove
+	// Rename is the move of a file or directory out of its watched directory.
+	Rename
+)
+
+// Has reports whether op contains all operations of other.
+func (op Op) Has(other Op) bool {
+	return op&other == other
+}
+
+func (op Op) String() string {
+	names := make([]string, 0)
+	for _, o := range []struct {
+		op   Op
+		name string
+	}{
+		{Create, "CREATE"},
+		{Write, "WRITE"},
+		{CloseWrite, "CLOSE_WRITE"},
+		{Remove, "REMOVE"},
+		{Rename, "RENAME"},
+	} {
+		if op.Has(o.op) {
+			names = append(names, o.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// Event is a file system event.
+type Event struct {
+	// Path is the path of the file or directory, joined with the path of its watched directory.
+	Path string
+	Op   Op
+	// Dir reports whether Path is a directory.
+	Dir bool
+	// Cookie pairs the Rename and Create events of a move between watched directories.
+	// Cookie is zero for other events.
+	Cookie uint32
+}
+
+var (
+	// ErrUnsupported is returned by NewWatcher on unsupported platforms.
+	ErrUnsupported = errors.New("file system events are not supported on this platform")
+	// ErrOverflow is sent on the Errors channel when events were dropped.
+	// Receivers should rescan the watched directories.
+	ErrOverflow = errors.New("event queue overflowed")
+	// ErrClosed is returned when using a closed Watcher.
+	ErrClosed = errors.New("watcher closed")
+)
@@ -0,0 +1,223 @@
+package notify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// mask is the inotify mask of the watched events.
+const mask = unix.IN_CREATE | unix.IN_MODIFY | unix.IN_CLOSE_WRITE | unix.IN_DELETE |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_DELETE_SELF | unix.IN_ONLYDIR
+
+// Watcher watches directories for file system events.
+type Watcher struct {
+	// Events receives the events of the watched directories.
+	// It is closed when the Watcher is closed.
+	Events chan Event
+	// Errors receives the errors of reading events.
+	// It is closed when the Watcher is closed.
+	Errors chan error
+
+	// fd is kept apart from f, as f.Fd would switch f to blocking mode.
+	fd   int
+	f    *os.File
+	done chan struct{}
+
+	mu    sync.Mutex
+	paths map[int32]string
+	wds   map[string]int32
+}
+
+// NewWatcher returns a Watcher without any watched directory.
+func NewWatcher() (*Watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify: %w", err)
+	}
+
+	w := &Watcher{
+		Events: make(chan Event),
+		Errors: make(chan error),
+		// A non-blocking file descriptor is read through the runtime poller, so that Close
+		// interrupts a pending read.
+		fd:    fd,
+		f:     os.NewFile(uintptr(fd), "inotify"),
+		done:  make(chan struct{}),
+		paths: make(map[int32]string),
+		wds:   make(map[string]int32),
+	}
+	go w.read()
+
+	return w, nil
+}
+
+// Add watches dir.
+func (w *Watcher) Add(dir string) error {
+	dir = filepath.Clean(dir)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.paths == nil {
+		return ErrClosed
+	}
+
+	wd, err := unix.InotifyAddWatch(w.fd, dir, mask)
+	if err != nil {
+		return &os.PathError{Op: "watch", Path: dir, Err: err}
+	}
+	w.paths[int32(wd)] = dir
+	w.wds[dir] = int32(wd)
+
+	return nil
+}
+
+// Remove stops watching dir.
+func (w *Watcher) Remove(dir string) error {
+	dir = filepath.Clean(dir)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.paths == nil {
+		return ErrClosed
+	}
+
+	wd, ok := w.wds[dir]
+	if !ok {
+		return fmt.Errorf("%s: not watched", dir)
+	}
+	delete(w.wds, dir)
+	delete(w.paths, wd)
+
+	if _, err := unix.InotifyRmWatch(w.fd, uint32(wd)); err != nil {
+		return &os.PathError{Op: "unwatch", Path: dir, Err: err}
+	}
+	return nil
+}
+
+// Close stops watching all directories, and closes the Events and Errors channels.
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	if w.paths == nil {
+		w.mu.Unlock()
+		return ErrClosed
+	}
+	w.paths = nil
+	w.wds = nil
+	w.mu.Unlock()
+
+	close(w.done)
+	return w.f.Close()
+}
+
+// read reads events until w is closed.
+func (w *Watcher) read() {
+	defer close(w.Events)
+	defer close(w.Errors)
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := w.f.Read(buf)
+		if errors.Is(err, os.ErrClosed) {
+			return
+		} else if err != nil {
+			if !w.sendError(err) {
+				return
+			}
+			continue
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			raw := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			name := buf[offset+unix.SizeofInotifyEvent : offset+unix.SizeofInotifyEvent+int(raw.Len)]
+			offset += unix.SizeofInotifyEvent + int(raw.Len)
+
+			if raw.Mask&unix.IN_Q_OVERFLOW != 0 {
+				if !w.sendError(ErrOverflow) {
+					return
+				}
+				continue
+			}
+
+			ev, ok := w.event(raw, string(bytes.TrimRight(name, "\x00")))
+			if !ok {
+				continue
+			}
+			select {
+			case w.Events <- ev:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
+
+// event converts raw into an Event.
+// ok is false for events that are not reported, such as those of removed watches.
+func (w *Watcher) event(raw *unix.InotifyEvent, name string) (ev Event, ok bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	dir, ok := w.paths[raw.Wd]
+	if !ok {
+		return Event{}, false
+	}
+
+	// The watch of a removed directory is removed by the kernel.
+	if raw.Mask&unix.IN_IGNORED != 0 {
+		delete(w.paths, raw.Wd)
+		if w.wds[dir] == raw.Wd {
+			delete(w.wds, dir)
+		}
+		return Event{}, false
+	}
+
+	ev.Path = dir
+	if name != "" {
+		ev.Path = filepath.Join(dir, name)
+	}
+	ev.Dir = raw.Mask&unix.IN_ISDIR != 0
+
+	if raw.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+		ev.Op |= Create
+	}
+	if raw.Mask&unix.IN_MODIFY != 0 {
+		ev.Op |= Write
+	}
+	if raw.Mask&unix.IN_CLOSE_WRITE != 0 {
+		ev.Op |= CloseWrite
+	}
+	if raw.Mask&(unix.IN_DELETE|unix.IN_DELETE_SELF) != 0 {
+		ev.Op |= Remove
+	}
+	if raw.Mask&unix.IN_MOVED_FROM != 0 {
+		ev.Op |= Rename
+	}
+	if raw.Mask&(unix.IN_MOVED_FROM|unix.IN_MOVED_TO) != 0 {
+		ev.Cookie = raw.Cookie
+	}
+	if raw.Mask&unix.IN_DELETE_SELF != 0 {
+		ev.Dir = true
+	}
+
+	return ev, ev.Op != 0
+}
+
+// sendError sends err on w.Errors.
+// sendError reports false if w is closed.
+func (w *Watcher) sendError(err error) bool {
+	select {
+	case w.Errors <- err:
+		return true
+	case <-w.done:
+		return false
+	}
+}
@@ -0,0 +1,66 @@
+package notify_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ghifari160/medhash-tools/notify"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcher(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir := t.TempDir()
+
+	w, err := notify.NewWatcher()
+	require.NoError(err)
+	require.NoError(w.Add(dir))
+
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+
+	require.NoError(os.WriteFile(a, []byte("a"), 0644))
+	ev := next(t, w)
+	require.Equal(notify.Event{Path: a, Op: notify.Create}, ev)
+	require.Equal(notify.Event{Path: a, Op: notify.Write}, next(t, w))
+	require.Equal(notify.Event{Path: a, Op: notify.CloseWrite}, next(t, w))
+
+	require.NoError(os.Rename(a, b))
+	from, to := next(t, w), next(t, w)
+	require.Equal(a, from.Path)
+	require.Equal(notify.Rename, from.Op)
+	require.Equal(b, to.Path)
+	require.Equal(notify.Create, to.Op)
+	require.NotZero(from.Cookie)
+	require.Equal(from.Cookie, to.Cookie)
+
+	require.NoError(os.Mkdir(filepath.Join(dir, "sub"), 0755))
+	require.Equal(notify.Event{Path: filepath.Join(dir, "sub"), Op: notify.Create, Dir: true}, next(t, w))
+
+	require.NoError(os.Remove(b))
+	require.Equal(notify.Event{Path: b, Op: notify.Remove}, next(t, w))
+
+	require.NoError(w.Close())
+	_, ok := <-w.Events
+	require.False(ok)
+	require.ErrorIs(w.Add(dir), notify.ErrClosed)
+}
+
+// next returns the next event of w.
+func next(t *testing.T, w *notify.Watcher) notify.Event {
+	t.Helper()
+
+	select {
+	case ev := <-w.Events:
+		return ev
+	case err := <-w.Errors:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+	}
+	return notify.Event{}
+}
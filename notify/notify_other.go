@@ -0,0 +1,31 @@
+//go:build !linux
+
+package notify
+
+// Watcher watches directories for file system events.
+type Watcher struct {
+	// Events receives the events of the watched directories.
+	Events chan Event
+	// Errors receives the errors of reading events.
+	Errors chan error
+}
+
+// NewWatcher returns ErrUnsupported.
+func NewWatcher() (*Watcher, error) {
+	return nil, ErrUnsupported
+}
+
+// Add returns ErrUnsupported.
+func (w *Watcher) Add(dir string) error {
+	return ErrUnsupported
+}
+
+// Remove returns ErrUnsupported.
+func (w *Watcher) Remove(dir string) error {
+	return ErrUnsupported
+}
+
+// Close returns ErrUnsupported.
+func (w *Watcher) Close() error {
+	return ErrUnsupported
+}
@@ -0,0 +1,6 @@
+// Package par2 reads the file index of PAR2 (Parity Volume Set 2.0) files.
+//
+// Only the file description packets are read, which list the name and the MD5 hash of each file
+// in the recovery set.
+// Recovery data is skipped, and is never used to repair files.
+package par2
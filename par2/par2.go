@@ -0,0 +1,96 @@
+package par2
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/ghifari160/medhash-tools/medhash"
+)
+
+// FormatName is the name of the PAR2 format, as accepted by the import command.
+const FormatName = "par2"
+
+// Magic is the magic sequence starting every PAR2 packet.
+var Magic = []byte("PAR2\x00PKT")
+
+var typeFileDesc = []byte("PAR 2.0\x00FileDesc")
+
+const (
+	headerSize = 64
+	// fileDescSize is the size of the fixed fields of a file description packet body.
+	fileDescSize = 56
+	// maxNameSize is the largest file name accepted in a file description packet, so that a
+	// corrupt packet length cannot exhaust the memory.
+	maxNameSize = 64 << 10
+)
+
+// Read reads the files listed by the PAR2 file read from r.
+// Packets with a corrupt hash are skipped, as PAR2 files repeat their file description packets.
+// Medias are returned in the order they first appear in r.
+func Read(r io.Reader) ([]medhash.Media, error) {
+	medias := make([]medhash.Media, 0)
+	seen := make(map[string]bool)
+
+	header := make([]byte, headerSize)
+	for {
+		if _, err := io.ReadFull(r, header); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if !bytes.Equal(header[:8], Magic) {
+			return nil, errors.New("not a PAR2 packet")
+		}
+		length := binary.LittleEndian.Uint64(header[8:16])
+		if length < headerSize || length%4 != 0 || length > math.MaxInt64 {
+			return nil, fmt.Errorf("malformed packet length: %d", length)
+		}
+
+		if !bytes.Equal(header[48:64], typeFileDesc) {
+			if _, err := io.CopyN(io.Discard, r, int64(length-headerSize)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if length-headerSize > fileDescSize+maxNameSize {
+			return nil, fmt.Errorf("malformed file description packet length: %d", length)
+		}
+		body := make([]byte, length-headerSize)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return nil, err
+		}
+		if len(body) < fileDescSize {
+			return nil, fmt.Errorf("malformed file description packet length: %d", length)
+		}
+
+		// The packet hash covers the packet from the recovery set ID to its end.
+		h := md5.New()
+		h.Write(header[32:])
+		h.Write(body)
+		if !bytes.Equal(h.Sum(nil), header[16:32]) {
+			continue
+		}
+
+		fileID := hex.EncodeToString(body[:16])
+		if seen[fileID] {
+			continue
+		}
+		seen[fileID] = true
+
+		medias = append(medias, medhash.Media{
+			Path: strings.TrimRight(string(body[fileDescSize:]), "\x00"),
+			Hash: medhash.Hash{MD5: hex.EncodeToString(body[16:32])},
+		})
+	}
+
+	return medias, nil
+}
@@ -0,0 +1,82 @@
+package par2_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"testing"
+
+	"github.com/ghifari160/medhash-tools/medhash"
+	"github.com/ghifari160/medhash-tools/par2"
+	"github.com/stretchr/testify/require"
+)
+
+const md5A = "0cc175b9c0f1b6a831c399e269772661"
+
+// packet returns a PAR2 packet of typ with body.
+func packet(typ string, body []byte) []byte {
+	var setID [16]byte
+	var typeField [16]byte
+	copy(typeField[:], typ)
+
+	h := md5.New()
+	h.Write(setID[:])
+	h.Write(typeField[:])
+	h.Write(body)
+
+	var buf bytes.Buffer
+	buf.Write(par2.Magic)
+	binary.Write(&buf, binary.LittleEndian, uint64(64+len(body)))
+	buf.Write(h.Sum(nil))
+	buf.Write(setID[:])
+	buf.Write(typeField[:])
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+// fileDesc returns the body of a file description packet.
+func fileDesc(id byte, hash [16]byte, name string) []byte {
+	var buf bytes.Buffer
+	buf.Write(bytes.Repeat([]byte{id}, 16))
+	buf.Write(hash[:])
+	buf.Write(make([]byte, 16))
+	binary.Write(&buf, binary.LittleEndian, uint64(1))
+	buf.WriteString(name)
+	for buf.Len()%4 != 0 {
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	hashA := md5.Sum([]byte("a"))
+
+	corrupt := packet("PAR 2.0\x00FileDesc", fileDesc(3, hashA, "corrupt"))
+	corrupt[len(corrupt)-4] = 'x'
+
+	var input bytes.Buffer
+	input.Write(packet("PAR 2.0\x00Main", make([]byte, 12)))
+	input.Write(packet("PAR 2.0\x00FileDesc", fileDesc(1, hashA, "dir/a")))
+	input.Write(corrupt)
+	input.Write(packet("PAR 2.0\x00FileDesc", fileDesc(2, hashA, "b")))
+	input.Write(packet("PAR 2.0\x00FileDesc", fileDesc(1, hashA, "dir/a")))
+
+	medias, err := par2.Read(&input)
+	require.NoError(err)
+	require.Equal([]medhash.Media{
+		{Path: "dir/a", Hash: medhash.Hash{MD5: md5A}},
+		{Path: "b", Hash: medhash.Hash{MD5: md5A}},
+	}, medias)
+
+	_, err = par2.Read(bytes.NewReader(bytes.Repeat([]byte("x"), 64)))
+	require.Error(err)
+
+	// The body of a file description packet claiming to be huge is not allocated.
+	huge := packet("PAR 2.0\x00FileDesc", fileDesc(1, hashA, "a"))
+	binary.LittleEndian.PutUint64(huge[8:16], 1<<62)
+	_, err = par2.Read(bytes.NewReader(huge))
+	require.ErrorContains(err, "malformed file description packet length")
+}
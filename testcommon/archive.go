@@ -0,0 +1,101 @@
+package testcommon
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// CreateArchive creates an archive at archivePath of all regular files in dir for use in tests.
+// format is one of "tar", "tar.gz", or "zip".
+// Members are named by their path relative to dir.
+func CreateArchive(t testing.TB, archivePath, format, dir string) {
+	t.Helper()
+	require := require.New(t)
+
+	f, err := os.Create(archivePath)
+	require.NoError(err)
+	defer f.Close()
+
+	var add func(name string, info fs.FileInfo, r io.Reader) error
+	var closer io.Closer
+
+	switch format {
+	case "zip":
+		zw := zip.NewWriter(f)
+		add = func(name string, info fs.FileInfo, r io.Reader) error {
+			w, err := zw.Create(name)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(w, r)
+			return err
+		}
+		closer = zw
+
+	default:
+		var w io.Writer = f
+		var gz *gzip.Writer
+		if format == "tar.gz" {
+			gz = gzip.NewWriter(f)
+			w = gz
+		}
+
+		tw := tar.NewWriter(w)
+		add = func(name string, info fs.FileInfo, r io.Reader) error {
+			hdr, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			hdr.Name = name
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			_, err = io.Copy(tw, r)
+			return err
+		}
+		closer = closerFunc(func() error {
+			if err := tw.Close(); err != nil {
+				return err
+			}
+			if gz != nil {
+				return gz.Close()
+			}
+			return nil
+		})
+	}
+
+	err = filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		member, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer member.Close()
+
+		return add(filepath.ToSlash(rel), info, member)
+	})
+	require.NoError(err)
+	require.NoError(closer.Close())
+}
+
+type closerFunc func() error
+
+func (fn closerFunc) Close() error {
+	return fn()
+}
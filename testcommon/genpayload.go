@@ -9,6 +9,7 @@ import (
 	"crypto/sha512"
 	"encoding/hex"
 	"hash"
+	"hash/crc32"
 	"io"
 	"os"
 	"path/filepath"
@@ -48,7 +49,8 @@ func GenPayload(t testing.TB, dir string, size int64) (payload medhash.Media) {
 	sha256 := sha256.New()
 	sha1 := sha1.New()
 	md5 := md5.New()
-	writer := io.MultiWriter(f, xxh3, sha512, sha3, sha256, sha1, md5)
+	crc32 := crc32.NewIEEE()
+	writer := io.MultiWriter(f, xxh3, sha512, sha3, sha256, sha1, md5, crc32)
 
 	for counter < size {
 		n, err := rand.Read(buf)
@@ -73,6 +75,7 @@ func GenPayload(t testing.TB, dir string, size int64) (payload medhash.Media) {
 	payload.Hash.SHA256 = hashToString(t, sha256)
 	payload.Hash.SHA1 = hashToString(t, sha1)
 	payload.Hash.MD5 = hashToString(t, md5)
+	payload.Hash.CRC32 = hashToString(t, crc32)
 
 	t.Log("Done generating payload")
 
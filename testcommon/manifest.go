@@ -30,10 +30,11 @@ func CreateLegacyManifest(t testing.TB, dir string, payload medhash.Media) {
 
 // CreateManifest creates a Manifest version ver for use in tests.
 // It is up to the caller to follow the spec of the specified version.
+// If the Manifest is stored outside config.Dir, config.Dir is recorded as the Manifest root.
 func CreateManifest(t testing.TB, config medhash.Config, payload medhash.Media, ver string) {
 	t.Helper()
 	require := require.New(t)
-	manifestPath := filepath.Join(config.Dir, config.Manifest)
+	manifestPath := config.ManifestPath()
 
 	if !config.XXH3 {
 		payload.Hash.XXH3 = ""
@@ -54,12 +55,18 @@ func CreateManifest(t testing.TB, config medhash.Config, payload medhash.Media,
 	if !config.MD5 {
 		payload.Hash.MD5 = ""
 	}
+	if !config.CRC32 {
+		payload.Hash.CRC32 = ""
+	}
 
 	manifest, err := medhash.NewWithConfig(config)
 	require.NoError(err)
 	manifest.Version = ver
 	manifest.Generator = "MedHash Tools Test"
 	manifest.Media = []medhash.Media{payload}
+	if filepath.Dir(manifestPath) != filepath.Clean(config.Dir) {
+		manifest.Root = filepath.ToSlash(config.Dir)
+	}
 
 	require.NoError(storeManifest(manifest, manifestPath))
 	require.FileExists(manifestPath)
@@ -72,7 +79,7 @@ func VerifyManifest(t testing.TB, config medhash.Config, hash medhash.Hash) {
 	t.Helper()
 	require := require.New(t)
 	assert := assert.New(t)
-	manifestPath := filepath.Join(config.Dir, config.Manifest)
+	manifestPath := config.ManifestPath()
 
 	if hash.XXH3 == "" {
 		config.XXH3 = false
@@ -92,6 +99,9 @@ func VerifyManifest(t testing.TB, config medhash.Config, hash medhash.Hash) {
 	if hash.MD5 == "" {
 		config.MD5 = false
 	}
+	if hash.CRC32 == "" {
+		config.CRC32 = false
+	}
 
 	require.FileExists(manifestPath)
 	manifest, err := loadManifest(manifestPath)
@@ -127,9 +137,26 @@ func VerifyManifest(t testing.TB, config medhash.Config, hash medhash.Hash) {
 		if config.MD5 {
 			assert.Equal(hash.MD5, media.Hash.MD5)
 		}
+		if config.CRC32 {
+			assert.Equal(hash.CRC32, media.Hash.CRC32)
+		}
 	}
 }
 
+// ReadManifest reads the Manifest at path.
+func ReadManifest(t testing.TB, path string) *medhash.Manifest {
+	t.Helper()
+	manifest, err := loadManifest(path)
+	require.NoError(t, err)
+	return manifest
+}
+
+// WriteManifest writes manifest to its Config.ManifestPath.
+func WriteManifest(t testing.TB, manifest *medhash.Manifest) {
+	t.Helper()
+	require.NoError(t, storeManifest(manifest, manifest.Config.ManifestPath()))
+}
+
 func loadManifest(path string) (manifest *medhash.Manifest, err error) {
 	f, err := os.Open(path)
 	if err != nil {